@@ -0,0 +1,127 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"currencyify/components/auth"
+	"currencyify/utils"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+const usage = `usage:
+  currencyify apikey create -name NAME -scopes convert,rates,admin [-tier TIER]
+  currencyify apikey list
+  currencyify apikey revoke ID
+  currencyify apikey signing-secret ID`
+
+// redisConn opens the redis connection holding the API keys.
+var redisConn = utils.Conn
+
+// Run runs the admin command given in the command line args, i.e. `apikey create -name billing -scopes convert`,
+// writing its output to the given writer.
+// It returns error if the command fails.
+func Run(args []string, out io.Writer) error {
+	if len(args) < 2 || args[0] != "apikey" {
+		return errors.New(usage)
+	}
+
+	conn, err := redisConn()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := conn.Close(); err != nil {
+			slog.Error("error closing redis connection", "error", err)
+		}
+	}()
+
+	switch args[1] {
+	case "create":
+		return createAPIKey(conn, args[2:], out)
+	case "list":
+		return listAPIKeys(conn, out)
+	case "revoke":
+		if len(args) != 3 {
+			return errors.New(usage)
+		}
+		if err = auth.RevokeAPIKey(conn, args[2]); err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(out, "revoked API key: %s\n", args[2])
+		return err
+	case "signing-secret":
+		if len(args) != 3 {
+			return errors.New(usage)
+		}
+		secret, err := auth.RotateSigningSecret(conn, args[2])
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(out, "signing secret: %s\nrequests of the key must be signed from now on, store the secret safely, it replaces the previous one\n", secret)
+		return err
+	default:
+		return errors.New(usage)
+	}
+}
+
+// createAPIKey issues a new API key and prints it, as it can't be recovered later.
+func createAPIKey(conn redis.Conn, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("apikey create", flag.ContinueOnError)
+	flags.SetOutput(out)
+	name := flags.String("name", "", "name of the client the key is issued to")
+	scopes := flags.String("scopes", "", "comma separated scopes granted to the key: convert, rates, admin")
+	tier := flags.String("tier", "", "rate limit tier of the key, i.e. premium, the default tier when empty")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *name == "" {
+		return errors.New("`-name` flag is required")
+	}
+
+	var keyScopes []string
+	for _, scope := range strings.Split(*scopes, ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			keyScopes = append(keyScopes, scope)
+		}
+	}
+
+	key, apiKey, err := auth.CreateAPIKey(conn, *name, keyScopes, *tier)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(out, "id: %s\nkey: %s\nstore the key safely, it can't be shown again\n", apiKey.ID, key)
+	return err
+}
+
+// listAPIKeys prints the issued API keys as a table.
+func listAPIKeys(conn redis.Conn, out io.Writer) error {
+	apiKeys, err := auth.ListAPIKeys(conn)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "ID\tNAME\tSCOPES\tTIER\tSIGNED\tCREATED AT")
+	for _, apiKey := range apiKeys {
+		tier := apiKey.Tier
+		if tier == "" {
+			tier = "-"
+		}
+		signed := "no"
+		if apiKey.SigningSecret != "" {
+			signed = "yes"
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", apiKey.ID, apiKey.Name, strings.Join(apiKey.Scopes, ","), tier, signed, apiKey.CreatedAt.Format(time.RFC3339))
+	}
+
+	return w.Flush()
+}
@@ -0,0 +1,58 @@
+package admin
+
+import (
+	"net/http"
+
+	"currencyify/components"
+	"currencyify/utils"
+)
+
+type VendorUsageComponent struct {
+	components.BaseComponent
+}
+
+type VendorUsage interface {
+	GetVendorUsage() (*VendorUsageResponse, error)
+
+	GetVendorUsageAppError() *utils.AppError
+	SetVendorUsageAppError(int, error)
+}
+
+type VendorUsageResponse struct {
+	Providers []utils.VendorUsage `json:"providers"`
+}
+
+// GetVendorUsage is used to get the calls made to each vendor API provider in the current minute, day and month, along
+// with their budgets.
+// It returns the vendor API usage and error.
+func (vuc *VendorUsageComponent) GetVendorUsage() (*VendorUsageResponse, error) {
+	providers, err := utils.GetVendorUsage(vuc.RedisConn)
+	if err != nil {
+		vuc.SetVendorUsageAppError(http.StatusInternalServerError, err)
+		return nil, err
+	}
+
+	return &VendorUsageResponse{Providers: providers}, nil
+}
+
+// GetVendorUsageAppError is used to retrieve app error from the vendor usage component.
+// It returns app error of the component.
+func (vuc *VendorUsageComponent) GetVendorUsageAppError() *utils.AppError {
+	return vuc.AppError
+}
+
+// SetVendorUsageAppError is used to set the app error for the vendor usage component.
+func (vuc *VendorUsageComponent) SetVendorUsageAppError(status int, err error) {
+	vuc.AppError = &utils.AppError{
+		Status: status,
+		Error:  err,
+	}
+}
+
+func init() {
+	components.ComponentMap["VendorUsage"] = func(bc *components.BaseComponent) interface{} {
+		c := &VendorUsageComponent{BaseComponent: *bc}
+
+		return VendorUsage(c)
+	}
+}
@@ -0,0 +1,216 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"currencyify/constants"
+)
+
+// Sinks of the audit log.
+const (
+	SinkFile = "file"
+	SinkSQL  = "sql"
+)
+
+// defaultBufferSize is the number of records waiting to be written, by default. Records logged while the buffer is
+// full are dropped, so a slow sink never delays the conversions.
+const defaultBufferSize = 1024
+
+// maxBatchSize is the max number of records written to the sink at once.
+const maxBatchSize = 100
+
+// Record is the audit record of a conversion served.
+type Record struct {
+	Time      time.Time `json:"time"`
+	RequestID string    `json:"request_id"`
+	// Client is the identity of the client, `<auth method>:<id>` of the authenticated clients, i.e. `api_key:a1b2c3`,
+	// and `ip:<address>` of the others.
+	Client     string `json:"client"`
+	ClientName string `json:"client_name,omitempty"`
+
+	SourceCurrency string  `json:"source_currency"`
+	TargetCurrency string  `json:"target_currency"`
+	Amount         float64 `json:"amount,omitempty"`
+	TargetAmount   float64 `json:"target_amount,omitempty"`
+
+	// Rate is the rate of the target currency per unit of the source currency.
+	Rate          float64   `json:"rate"`
+	RateTimestamp time.Time `json:"rate_timestamp"`
+	// Provider is the host of the vendor API providing the rates.
+	Provider string `json:"provider"`
+
+	SourceAmount    float64 `json:"source_amount"`
+	ConvertedAmount float64 `json:"converted_amount"`
+}
+
+// Request identifies the request, and the client making it, in the audit records.
+type Request struct {
+	ID         string
+	Client     string
+	ClientName string
+}
+
+type requestCtxKey struct{}
+
+// NewContext is used to attach the given request to the given context, so the audit records of the conversions served
+// within the context identify the request.
+// It returns the context holding the request.
+func NewContext(ctx context.Context, req Request) context.Context {
+	return context.WithValue(ctx, requestCtxKey{}, req)
+}
+
+// FromContext is used to get the request attached to the given context.
+// It returns the request, empty when the context doesn't hold one.
+func FromContext(ctx context.Context) Request {
+	if ctx == nil {
+		return Request{}
+	}
+
+	req, _ := ctx.Value(requestCtxKey{}).(Request)
+	return req
+}
+
+// Sink writes the audit records to their storage.
+type Sink interface {
+	Write(records []Record) error
+	Close() error
+}
+
+// Logger writes the audit records to the sink asynchronously, so logging a record doesn't add latency to the request.
+type Logger struct {
+	sink    Sink
+	records chan Record
+	done    chan struct{}
+
+	closeOnce sync.Once
+}
+
+var (
+	defaultLogger     *Logger
+	defaultLoggerErr  error
+	defaultLoggerOnce sync.Once
+)
+
+// NewLogger is used to create a new logger writing to the given sink, buffering up to the given number of records.
+// It returns the logger instance, writing the records in background till it is closed.
+func NewLogger(sink Sink, bufferSize int) *Logger {
+	if bufferSize <= 0 {
+		bufferSize = defaultBufferSize
+	}
+
+	l := &Logger{sink: sink, records: make(chan Record, bufferSize), done: make(chan struct{})}
+	go l.run()
+
+	return l
+}
+
+// Init is used to create the default logger, writing to the sink set by the `AUDIT_LOG_*` env vars.
+// It returns error when the audit log is configured but its sink can't be created.
+func Init() error {
+	DefaultLogger()
+
+	return defaultLoggerErr
+}
+
+// Close is used to write the records queued by the default logger and close its sink.
+// It returns error closing the sink.
+func Close() error {
+	if logger := DefaultLogger(); logger != nil {
+		return logger.Close()
+	}
+
+	return nil
+}
+
+// DefaultLogger is used to get the logger writing to the sink set by the `AUDIT_LOG_*` env vars, created on first use.
+// It returns the logger, or nil when the audit log isn't configured or its sink can't be created.
+func DefaultLogger() *Logger {
+	defaultLoggerOnce.Do(func() {
+		var sink Sink
+		switch constants.AUDIT_LOG_SINK {
+		case "":
+			return
+		case SinkFile:
+			sink, defaultLoggerErr = NewFileSink(constants.AUDIT_LOG_FILE)
+		case SinkSQL:
+			var db *sql.DB
+			if db, defaultLoggerErr = sql.Open(constants.AUDIT_LOG_SQL_DRIVER, constants.AUDIT_LOG_SQL_DSN); defaultLoggerErr == nil {
+				sink, defaultLoggerErr = NewSQLSink(db, constants.AUDIT_LOG_SQL_DRIVER, constants.AUDIT_LOG_SQL_TABLE)
+			}
+		default:
+			defaultLoggerErr = fmt.Errorf("unknown sink (%s), it should be `%s` or `%s`", constants.AUDIT_LOG_SINK, SinkFile, SinkSQL)
+		}
+		if defaultLoggerErr != nil {
+			return
+		}
+
+		bufferSize, _ := strconv.Atoi(constants.AUDIT_LOG_BUFFER_SIZE)
+		defaultLogger = NewLogger(sink, bufferSize)
+	})
+
+	return defaultLogger
+}
+
+// Log is used to queue the given record to be written, identifying it by the request attached to the given context.
+// The record is dropped when the buffer is full or the logger is closed.
+func (l *Logger) Log(ctx context.Context, record Record) {
+	req := FromContext(ctx)
+	record.RequestID, record.Client, record.ClientName = req.ID, req.Client, req.ClientName
+	if record.Time.IsZero() {
+		record.Time = time.Now().UTC()
+	}
+
+	defer func() {
+		if recover() != nil {
+			slog.WarnContext(ctx, "audit log is closed, dropping the record")
+		}
+	}()
+
+	select {
+	case l.records <- record:
+	default:
+		slog.WarnContext(ctx, "audit log buffer is full, dropping the record")
+	}
+}
+
+// Close is used to write the queued records and close the sink.
+// It returns error closing the sink.
+func (l *Logger) Close() error {
+	l.closeOnce.Do(func() {
+		close(l.records)
+	})
+	<-l.done
+
+	return l.sink.Close()
+}
+
+// run writes the queued records to the sink in batches, till the logger is closed.
+func (l *Logger) run() {
+	defer close(l.done)
+
+	for record := range l.records {
+		batch := []Record{record}
+	drain:
+		for len(batch) < maxBatchSize {
+			select {
+			case record, ok := <-l.records:
+				if !ok {
+					break drain
+				}
+				batch = append(batch, record)
+			default:
+				break drain
+			}
+		}
+
+		if err := l.sink.Write(batch); err != nil {
+			slog.Error("Error writing audit records", "records", len(batch), "error", err)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"currencyify/constants"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type mockSink struct {
+	mu      sync.Mutex
+	batches [][]Record
+	err     error
+	release chan struct{}
+}
+
+func (s *mockSink) Write(records []Record) error {
+	if s.release != nil {
+		<-s.release
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.batches = append(s.batches, records)
+
+	return s.err
+}
+
+func (s *mockSink) Close() error {
+	return nil
+}
+
+func (s *mockSink) records() []Record {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records := make([]Record, 0)
+	for _, batch := range s.batches {
+		records = append(records, batch...)
+	}
+
+	return records
+}
+
+func TestFromContext(t *testing.T) {
+	req := Request{ID: "req-1", Client: "ip:10.0.0.1"}
+
+	assert.Equal(t, req, FromContext(NewContext(context.Background(), req)))
+	assert.Equal(t, Request{}, FromContext(context.Background()))
+}
+
+func TestLogger_Log(t *testing.T) {
+	now := time.Date(2026, time.March, 31, 0, 0, 0, 0, time.UTC)
+	ctx := NewContext(context.Background(), Request{ID: "req-1", Client: "api_key:a1b2c3", ClientName: "billing"})
+
+	testCases := []struct {
+		name string
+
+		bufferSize int
+		records    []Record
+		sinkErr    error
+
+		want []Record
+	}{
+		{
+			name:       "should write the records identified by the request",
+			bufferSize: 10,
+			records: []Record{
+				{Time: now, SourceCurrency: "USD", TargetCurrency: "INR", Amount: 1},
+				{Time: now, SourceCurrency: "EUR", TargetCurrency: "INR", Amount: 2},
+			},
+			want: []Record{
+				{Time: now, RequestID: "req-1", Client: "api_key:a1b2c3", ClientName: "billing", SourceCurrency: "USD", TargetCurrency: "INR", Amount: 1},
+				{Time: now, RequestID: "req-1", Client: "api_key:a1b2c3", ClientName: "billing", SourceCurrency: "EUR", TargetCurrency: "INR", Amount: 2},
+			},
+		},
+		{
+			name:       "should drop the records when the buffer is full",
+			bufferSize: 1,
+			records: []Record{
+				{Time: now, Amount: 1},
+				{Time: now, Amount: 2},
+				{Time: now, Amount: 3},
+			},
+			want: []Record{
+				{Time: now, RequestID: "req-1", Client: "api_key:a1b2c3", ClientName: "billing", Amount: 1},
+			},
+		},
+		{
+			name:       "should keep writing when the sink fails",
+			bufferSize: 10,
+			records:    []Record{{Time: now, Amount: 1}},
+			sinkErr:    errors.New("disk full"),
+			want: []Record{
+				{Time: now, RequestID: "req-1", Client: "api_key:a1b2c3", ClientName: "billing", Amount: 1},
+			},
+		},
+	}
+
+	for _, tCase := range testCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			// Setup
+			sink := &mockSink{err: tCase.sinkErr, release: make(chan struct{})}
+			logger := NewLogger(sink, tCase.bufferSize)
+
+			// Run test
+			for _, record := range tCase.records {
+				logger.Log(ctx, record)
+			}
+			close(sink.release)
+			assert.NoError(t, logger.Close())
+			logger.Log(ctx, Record{Amount: 4})
+
+			// Assert
+			got := sink.records()
+			if len(tCase.want) < len(tCase.records) {
+				// The record taken by the sink before it is released frees a slot of the buffer.
+				assert.LessOrEqual(t, len(got), 2, "case: %v", tCase)
+				got = got[:1]
+			}
+			assert.Equal(t, tCase.want, got, "case: %v", tCase)
+		})
+	}
+}
+
+func TestInit(t *testing.T) {
+	type vars struct {
+		sink      string
+		file      string
+		sqlDriver string
+		sqlDSN    string
+	}
+
+	testCases := []struct {
+		name string
+
+		vars vars
+
+		wantLogger bool
+		hasErr     bool
+		err        string
+	}{
+		{
+			name: "should not create the logger when the audit log isn't configured",
+		},
+		{
+			name:       "should create the logger of the file sink",
+			vars:       vars{sink: SinkFile, file: filepath.Join(t.TempDir(), "audit.log")},
+			wantLogger: true,
+		},
+		{
+			name:   "should fail for the file sink without the file",
+			vars:   vars{sink: SinkFile},
+			hasErr: true,
+			err:    "audit log file is required",
+		},
+		{
+			name:   "should fail for the unreachable postgres database",
+			vars:   vars{sink: SinkSQL, sqlDriver: "postgres", sqlDSN: "postgres://audit@127.0.0.1:1/audit?sslmode=disable&connect_timeout=1"},
+			hasErr: true,
+		},
+		{
+			name:   "should fail for the unreachable mysql database",
+			vars:   vars{sink: SinkSQL, sqlDriver: "mysql", sqlDSN: "audit@tcp(127.0.0.1:1)/audit?timeout=1s"},
+			hasErr: true,
+		},
+		{
+			name:   "should fail for the unknown driver",
+			vars:   vars{sink: SinkSQL, sqlDriver: "oracle"},
+			hasErr: true,
+			err:    `sql: unknown driver "oracle" (forgotten import?)`,
+		},
+		{
+			name:   "should fail for the unknown sink",
+			vars:   vars{sink: "kafka"},
+			hasErr: true,
+			err:    "unknown sink (kafka), it should be `file` or `sql`",
+		},
+	}
+
+	for _, tCase := range testCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			// Setup
+			constants.AUDIT_LOG_SINK, constants.AUDIT_LOG_FILE = tCase.vars.sink, tCase.vars.file
+			constants.AUDIT_LOG_SQL_DRIVER, constants.AUDIT_LOG_SQL_DSN = tCase.vars.sqlDriver, tCase.vars.sqlDSN
+			defaultLogger, defaultLoggerErr, defaultLoggerOnce = nil, nil, sync.Once{}
+
+			// Run test
+			err := Init()
+
+			// Assert
+			if tCase.hasErr {
+				assert.Errorf(t, err, "case: %v", tCase)
+				if tCase.err != "" {
+					assert.EqualErrorf(t, err, tCase.err, "case: %v", tCase)
+				} else {
+					assert.NotContainsf(t, err.Error(), "unknown driver", "case: %v", tCase)
+				}
+				assert.Nilf(t, DefaultLogger(), "case: %v", tCase)
+				return
+			}
+			assert.NoErrorf(t, err, "case: %v", tCase)
+			assert.Equalf(t, tCase.wantLogger, DefaultLogger() != nil, "case: %v", tCase)
+			assert.NoErrorf(t, Close(), "case: %v", tCase)
+		})
+	}
+	constants.AUDIT_LOG_SINK, constants.AUDIT_LOG_FILE = "", ""
+	constants.AUDIT_LOG_SQL_DRIVER, constants.AUDIT_LOG_SQL_DSN = "", ""
+	defaultLogger, defaultLoggerErr, defaultLoggerOnce = nil, nil, sync.Once{}
+}
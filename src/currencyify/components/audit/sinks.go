@@ -0,0 +1,146 @@
+package audit
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	// SQL drivers of the `sql` sink, `postgres` and `mysql`
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+)
+
+// defaultSQLTable is the table of the audit records, by default.
+const defaultSQLTable = "conversion_audit_log"
+
+// sqlTablePattern matches the valid table names, optionally qualified by the schema, as the table name can't be passed
+// as a query param.
+var sqlTablePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// sqlColumns are the columns of the audit records table, in the order of the values of `sqlValues`.
+var sqlColumns = []string{
+	"time", "request_id", "client", "client_name", "source_currency", "target_currency", "amount", "target_amount",
+	"rate", "rate_timestamp", "provider", "source_amount", "converted_amount",
+}
+
+// FileSink appends the audit records to a file as JSON lines.
+type FileSink struct {
+	file *os.File
+	mu   sync.Mutex
+}
+
+// NewFileSink is used to create a new sink appending to the file at the given path, creating it when it doesn't exist.
+// It returns the sink instance and error.
+func NewFileSink(path string) (*FileSink, error) {
+	if path == "" {
+		return nil, errors.New("audit log file is required")
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o640)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileSink{file: file}, nil
+}
+
+// Write is used to append the given records to the file, one JSON object per line.
+// It returns error writing the file.
+func (s *FileSink) Write(records []Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w := bufio.NewWriter(s.file)
+	encoder := json.NewEncoder(w)
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			return err
+		}
+	}
+
+	return w.Flush()
+}
+
+// Close is used to close the file.
+func (s *FileSink) Close() error {
+	return s.file.Close()
+}
+
+// SQLSink inserts the audit records into a SQL table, having the `sqlColumns` columns.
+type SQLSink struct {
+	db     *sql.DB
+	insert string
+}
+
+// NewSQLSink is used to create a new sink inserting into the given table of the given database, opened with the given
+// driver, `conversion_audit_log` when the table isn't given.
+// It returns the sink instance and error if the table name is invalid or the database can't be reached.
+func NewSQLSink(db *sql.DB, driver, table string) (*SQLSink, error) {
+	if table == "" {
+		table = defaultSQLTable
+	}
+	if !sqlTablePattern.MatchString(table) {
+		return nil, fmt.Errorf("invalid audit log table (%s)", table)
+	}
+	if err := db.Ping(); err != nil {
+		return nil, err
+	}
+
+	placeholders := make([]string, len(sqlColumns))
+	for i := range placeholders {
+		placeholders[i] = "?"
+		if driver == "postgres" || driver == "pgx" {
+			placeholders[i] = fmt.Sprintf("$%d", i+1)
+		}
+	}
+
+	return &SQLSink{
+		db:     db,
+		insert: fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", table, strings.Join(sqlColumns, ", "), strings.Join(placeholders, ", ")),
+	}, nil
+}
+
+// Write is used to insert the given records in one transaction.
+// It returns error inserting the records, none of them being inserted then.
+func (s *SQLSink) Write(records []Record) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.Prepare(s.insert)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	for _, record := range records {
+		if _, err = stmt.Exec(sqlValues(record)...); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Close is used to close the database.
+func (s *SQLSink) Close() error {
+	return s.db.Close()
+}
+
+// sqlValues are the values of the columns of the given record.
+func sqlValues(record Record) []interface{} {
+	return []interface{}{
+		record.Time, record.RequestID, record.Client, record.ClientName, record.SourceCurrency, record.TargetCurrency,
+		record.Amount, record.TargetAmount, record.Rate, record.RateTimestamp, record.Provider, record.SourceAmount,
+		record.ConvertedAmount,
+	}
+}
@@ -0,0 +1,209 @@
+package audit
+
+import (
+	"bufio"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// mockDriver is the SQL driver recording the statements executed, failing the records having the `fail` request ID.
+type mockDriver struct {
+	mu         sync.Mutex
+	queries    []string
+	args       [][]driver.Value
+	committed  int
+	rolledBack int
+}
+
+type mockConn struct{ d *mockDriver }
+
+type mockStmt struct {
+	d     *mockDriver
+	query string
+}
+
+type mockTx struct{ d *mockDriver }
+
+func (d *mockDriver) Open(string) (driver.Conn, error) { return &mockConn{d: d}, nil }
+
+func (c *mockConn) Prepare(query string) (driver.Stmt, error) {
+	return &mockStmt{d: c.d, query: query}, nil
+}
+func (c *mockConn) Close() error              { return nil }
+func (c *mockConn) Begin() (driver.Tx, error) { return &mockTx{d: c.d}, nil }
+
+func (s *mockStmt) Close() error  { return nil }
+func (s *mockStmt) NumInput() int { return -1 }
+func (s *mockStmt) Query([]driver.Value) (driver.Rows, error) {
+	return nil, errors.New("not supported")
+}
+func (s *mockStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	if args[1] == "fail" {
+		return nil, errors.New("constraint violated")
+	}
+	s.d.queries = append(s.d.queries, s.query)
+	s.d.args = append(s.d.args, args)
+
+	return driver.RowsAffected(1), nil
+}
+
+func (tx *mockTx) Commit() error {
+	tx.d.mu.Lock()
+	defer tx.d.mu.Unlock()
+	tx.d.committed++
+	return nil
+}
+func (tx *mockTx) Rollback() error {
+	tx.d.mu.Lock()
+	defer tx.d.mu.Unlock()
+	tx.d.rolledBack++
+	return nil
+}
+
+var registerMockDriverOnce sync.Once
+
+var mockSQLDriver = &mockDriver{}
+
+func TestFileSink_Write(t *testing.T) {
+	// Setup
+	path := filepath.Join(t.TempDir(), "audit.log")
+	now := time.Date(2026, time.March, 31, 0, 0, 0, 0, time.UTC)
+	records := []Record{
+		{Time: now, RequestID: "req-1", Client: "ip:10.0.0.1", SourceCurrency: "USD", TargetCurrency: "INR", Amount: 1, Rate: 83, SourceAmount: 1, ConvertedAmount: 83},
+		{Time: now, RequestID: "req-2", Client: "api_key:a1b2c3", SourceCurrency: "INR", TargetCurrency: "USD", TargetAmount: 1, Rate: 0.012, SourceAmount: 83, ConvertedAmount: 1},
+	}
+
+	// Run test
+	sink, err := NewFileSink(path)
+	assert.NoError(t, err)
+	assert.NoError(t, sink.Write(records[:1]))
+	assert.NoError(t, sink.Close())
+	sink, err = NewFileSink(path)
+	assert.NoError(t, err)
+	assert.NoError(t, sink.Write(records[1:]))
+	assert.NoError(t, sink.Close())
+
+	// Assert
+	file, err := os.Open(path)
+	assert.NoError(t, err)
+	defer file.Close()
+	got := make([]Record, 0)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record Record
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+		got = append(got, record)
+	}
+	assert.Equal(t, records, got, "records should be appended as JSON lines")
+
+	_, err = NewFileSink("")
+	assert.EqualError(t, err, "audit log file is required")
+}
+
+func TestSQLSink_Write(t *testing.T) {
+	registerMockDriverOnce.Do(func() {
+		sql.Register("audit-mock", mockSQLDriver)
+	})
+	now := time.Date(2026, time.March, 31, 0, 0, 0, 0, time.UTC)
+
+	type vars struct {
+		driver  string
+		table   string
+		records []Record
+	}
+
+	testCases := []struct {
+		name string
+
+		vars vars
+
+		wantQuery      string
+		wantRows       int
+		wantCommitted  int
+		wantRolledBack int
+		hasErr         bool
+		err            string
+	}{
+		{
+			name: "should insert the records into the default table in one transaction",
+			vars: vars{
+				driver:  "mysql",
+				records: []Record{{Time: now, RequestID: "req-1"}, {Time: now, RequestID: "req-2"}},
+			},
+			wantQuery:     "INSERT INTO conversion_audit_log (time, request_id, client, client_name, source_currency, target_currency, amount, target_amount, rate, rate_timestamp, provider, source_amount, converted_amount) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			wantRows:      2,
+			wantCommitted: 1,
+		},
+		{
+			name: "should use the numbered placeholders of postgres",
+			vars: vars{
+				driver:  "postgres",
+				table:   "audit.conversions",
+				records: []Record{{Time: now, RequestID: "req-1"}},
+			},
+			wantQuery:     "INSERT INTO audit.conversions (time, request_id, client, client_name, source_currency, target_currency, amount, target_amount, rate, rate_timestamp, provider, source_amount, converted_amount) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)",
+			wantRows:      1,
+			wantCommitted: 1,
+		},
+		{
+			name: "should roll back the records when one fails",
+			vars: vars{
+				driver:  "mysql",
+				records: []Record{{Time: now, RequestID: "req-1"}, {Time: now, RequestID: "fail"}},
+			},
+			wantRows:       1,
+			wantRolledBack: 1,
+			hasErr:         true,
+			err:            "constraint violated",
+		},
+		{
+			name: "should fail for the invalid table",
+			vars: vars{
+				driver: "mysql",
+				table:  "audit; DROP TABLE users",
+			},
+			hasErr: true,
+			err:    "invalid audit log table (audit; DROP TABLE users)",
+		},
+	}
+
+	for _, tCase := range testCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			// Setup
+			*mockSQLDriver = mockDriver{}
+			db, _ := sql.Open("audit-mock", "")
+			defer db.Close()
+
+			// Run test
+			sink, err := NewSQLSink(db, tCase.vars.driver, tCase.vars.table)
+			if err == nil {
+				err = sink.Write(tCase.vars.records)
+			}
+
+			// Assert
+			if tCase.hasErr {
+				if assert.Errorf(t, err, "case: %v", tCase) {
+					assert.EqualErrorf(t, err, tCase.err, "case: %v", tCase)
+				}
+			} else {
+				assert.NoErrorf(t, err, "case: %v", tCase)
+				assert.Equal(t, tCase.wantQuery, mockSQLDriver.queries[0], "case: %v", tCase)
+				assert.Equal(t, []driver.Value{now, "req-1", "", "", "", "", 0.0, 0.0, 0.0, time.Time{}, "", 0.0, 0.0}, mockSQLDriver.args[0], "case: %v", tCase)
+			}
+			assert.Len(t, mockSQLDriver.args, tCase.wantRows, "case: %v", tCase)
+			assert.Equal(t, tCase.wantCommitted, mockSQLDriver.committed, "case: %v", tCase)
+			assert.Equal(t, tCase.wantRolledBack, mockSQLDriver.rolledBack, "case: %v", tCase)
+		})
+	}
+}
@@ -0,0 +1,258 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"currencyify/utils"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// Scopes granted to the API keys. Admin scope grants all the others.
+const (
+	ScopeConvert = "convert"
+	ScopeRates   = "rates"
+	ScopeAdmin   = "admin"
+)
+
+// apiKeysHashKey is the redis hash holding the API keys, keyed by the SHA-256 of the keys.
+const apiKeysHashKey = "api-keys"
+
+const apiKeyPrefix = "cfy_"
+
+const signingSecretPrefix = "cfys_"
+
+// apiKeyIDLength is the length of the API key IDs, which are the prefix of the hash of the keys.
+const apiKeyIDLength = 12
+
+// ErrInvalidAPIKey is returned when the API key isn't found, i.e. it is revoked or never issued.
+var ErrInvalidAPIKey = errors.New("invalid API key")
+
+var scopes = []string{ScopeConvert, ScopeRates, ScopeAdmin}
+
+type APIKey struct {
+	ID     string   `json:"id"`
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+	// Tier is the rate limit tier of the key, empty for the default tier.
+	Tier string `json:"tier,omitempty"`
+	// SigningSecret is the secret signing the requests of the key, empty when the requests aren't signed.
+	SigningSecret string    `json:"signing_secret,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// Principal is the authenticated client of the request, along with the scopes granted to it.
+type Principal struct {
+	// ID is the API key ID, or the subject of the bearer token.
+	ID     string
+	Name   string
+	Scopes []string
+	// Tier is the rate limit tier of the API key, empty for the default tier.
+	Tier string
+	// SigningSecret is the secret signing the requests of the API key, empty when the requests aren't signed.
+	SigningSecret string
+	// Method is the authentication method, one of `MethodAPIKey` and `MethodJWT`.
+	Method string
+}
+
+// Client identifies the principal across the authentication methods, as `<method>:<id>`.
+func (p *Principal) Client() string {
+	return p.Method + ":" + p.ID
+}
+
+// HasScope checks whether the principal is granted the given scope, either directly or through the admin scope.
+func (p *Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ValidateScopes checks whether all the given scopes are known.
+// It returns error naming the unknown scope.
+func ValidateScopes(keyScopes []string) error {
+	if len(keyScopes) == 0 {
+		return fmt.Errorf("at least one scope is required, one of: %s", strings.Join(scopes, ", "))
+	}
+
+	for _, scope := range keyScopes {
+		known := false
+		for _, s := range scopes {
+			known = known || s == scope
+		}
+		if !known {
+			return fmt.Errorf("unknown scope (%s), it should be one of: %s", scope, strings.Join(scopes, ", "))
+		}
+	}
+
+	return nil
+}
+
+// CreateAPIKey is used to issue a new API key having the given scopes and rate limit tier. Only the hash of the key is
+// stored, so the key can't be recovered later.
+// It returns the key, its details and error.
+func CreateAPIKey(conn redis.Conn, name string, keyScopes []string, tier string) (string, *APIKey, error) {
+	if err := ValidateScopes(keyScopes); err != nil {
+		return "", nil, err
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, err
+	}
+	key := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+	hash := hashAPIKey(key)
+
+	apiKey := &APIKey{
+		ID:        hash[:apiKeyIDLength],
+		Name:      name,
+		Scopes:    keyScopes,
+		Tier:      tier,
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+	}
+	apiKeyBytes, err := json.Marshal(apiKey)
+	if err != nil {
+		return "", nil, err
+	}
+	if _, err = utils.HSetData(conn, apiKeysHashKey, hash, string(apiKeyBytes)); err != nil {
+		return "", nil, err
+	}
+
+	return key, apiKey, nil
+}
+
+// ListAPIKeys is used to list the issued API keys, oldest first.
+// It returns the API keys and error.
+func ListAPIKeys(conn redis.Conn) ([]APIKey, error) {
+	data, err := utils.HGetAllData(conn, apiKeysHashKey)
+	if err != nil {
+		return nil, err
+	}
+
+	apiKeys := make([]APIKey, 0, len(data))
+	for _, apiKeyStr := range data {
+		var apiKey APIKey
+		if err = json.Unmarshal([]byte(apiKeyStr), &apiKey); err != nil {
+			return nil, err
+		}
+		apiKeys = append(apiKeys, apiKey)
+	}
+	sort.Slice(apiKeys, func(i, j int) bool {
+		if !apiKeys[i].CreatedAt.Equal(apiKeys[j].CreatedAt) {
+			return apiKeys[i].CreatedAt.Before(apiKeys[j].CreatedAt)
+		}
+
+		return apiKeys[i].ID < apiKeys[j].ID
+	})
+
+	return apiKeys, nil
+}
+
+// RevokeAPIKey is used to revoke the API key having the given ID.
+// It returns error if the API key isn't found.
+func RevokeAPIKey(conn redis.Conn, id string) error {
+	data, err := utils.HGetAllData(conn, apiKeysHashKey)
+	if err != nil {
+		return err
+	}
+
+	for hash := range data {
+		if len(id) != apiKeyIDLength || !strings.HasPrefix(hash, id) {
+			continue
+		}
+
+		if _, err = utils.HDelData(conn, apiKeysHashKey, hash); err != nil {
+			return err
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("API key (%s) not found", id)
+}
+
+// RotateSigningSecret is used to issue a new signing secret to the API key having the given ID, replacing its current
+// one. Requests of the key must be signed once it has a signing secret.
+// It returns the signing secret and error if the API key isn't found.
+func RotateSigningSecret(conn redis.Conn, id string) (string, error) {
+	data, err := utils.HGetAllData(conn, apiKeysHashKey)
+	if err != nil {
+		return "", err
+	}
+
+	for hash, apiKeyStr := range data {
+		if len(id) != apiKeyIDLength || !strings.HasPrefix(hash, id) {
+			continue
+		}
+
+		var apiKey APIKey
+		if err = json.Unmarshal([]byte(apiKeyStr), &apiKey); err != nil {
+			return "", err
+		}
+
+		secret := make([]byte, 32)
+		if _, err = rand.Read(secret); err != nil {
+			return "", err
+		}
+		apiKey.SigningSecret = signingSecretPrefix + base64.RawURLEncoding.EncodeToString(secret)
+
+		apiKeyBytes, err := json.Marshal(apiKey)
+		if err != nil {
+			return "", err
+		}
+		if _, err = utils.HSetData(conn, apiKeysHashKey, hash, string(apiKeyBytes)); err != nil {
+			return "", err
+		}
+
+		return apiKey.SigningSecret, nil
+	}
+
+	return "", fmt.Errorf("API key (%s) not found", id)
+}
+
+// AuthenticateAPIKey is used to look up the client of the given API key.
+// It returns the principal and error, `ErrInvalidAPIKey` when the key isn't issued.
+func AuthenticateAPIKey(conn redis.Conn, key string) (*Principal, error) {
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	apiKeyStr, err := redis.String(conn.Do("HGET", apiKeysHashKey, hashAPIKey(key)))
+	if errors.Is(err, redis.ErrNil) {
+		return nil, ErrInvalidAPIKey
+	} else if err != nil {
+		return nil, err
+	}
+
+	var apiKey APIKey
+	if err = json.Unmarshal([]byte(apiKeyStr), &apiKey); err != nil {
+		return nil, err
+	}
+
+	return &Principal{
+		ID:            apiKey.ID,
+		Name:          apiKey.Name,
+		Scopes:        apiKey.Scopes,
+		Tier:          apiKey.Tier,
+		SigningSecret: apiKey.SigningSecret,
+		Method:        MethodAPIKey,
+	}, nil
+}
+
+// hashAPIKey is used to get the hex encoded SHA-256 of the API key, under which the key is stored.
+func hashAPIKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,135 @@
+package auth
+
+import (
+	"testing"
+
+	"currencyify/utils"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateScopes(t *testing.T) {
+	testCases := []struct {
+		name string
+
+		scopes []string
+
+		hasErr bool
+		err    string
+	}{
+		{
+			name:   "should success for the known scopes",
+			scopes: []string{ScopeConvert, ScopeRates},
+		},
+		{
+			name:   "should fail when scopes are empty",
+			hasErr: true,
+			err:    "at least one scope is required",
+		},
+		{
+			name:   "should fail for the unknown scope",
+			scopes: []string{ScopeRates, "write"},
+			hasErr: true,
+			err:    "unknown scope (write)",
+		},
+	}
+
+	for _, tCase := range testCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			// Run test
+			err := ValidateScopes(tCase.scopes)
+
+			// Assert
+			if tCase.hasErr {
+				if assert.Errorf(t, err, "case: %v", tCase) {
+					assert.Containsf(t, err.Error(), tCase.err, "case: %v", tCase)
+				}
+			} else {
+				assert.NoErrorf(t, err, "case: %v", tCase)
+			}
+		})
+	}
+}
+
+func TestPrincipal_HasScope(t *testing.T) {
+	testCases := []struct {
+		name string
+
+		scopes []string
+		scope  string
+
+		want bool
+	}{
+		{
+			name:   "should have the granted scope",
+			scopes: []string{ScopeRates},
+			scope:  ScopeRates,
+			want:   true,
+		},
+		{
+			name:   "should not have the scope which isn't granted",
+			scopes: []string{ScopeRates},
+			scope:  ScopeConvert,
+			want:   false,
+		},
+		{
+			name:   "should have all the scopes when admin scope is granted",
+			scopes: []string{ScopeAdmin},
+			scope:  ScopeConvert,
+			want:   true,
+		},
+	}
+
+	for _, tCase := range testCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			// Run test
+			got := (&Principal{Scopes: tCase.scopes}).HasScope(tCase.scope)
+
+			// Assert
+			assert.Equal(t, tCase.want, got, "case: %v", tCase)
+		})
+	}
+}
+
+func TestAPIKey_Lifecycle(t *testing.T) {
+	// Setup
+	conn := utils.NewMockRedisConn()
+
+	// Run test
+	key, apiKey, err := CreateAPIKey(conn, "billing", []string{ScopeConvert}, "premium")
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Regexp(t, `^cfy_[A-Za-z0-9_-]{43}$`, key)
+	assert.Len(t, apiKey.ID, apiKeyIDLength)
+	stored, _ := utils.HGetAllData(conn, apiKeysHashKey)
+	for hash, apiKeyStr := range stored {
+		assert.NotContains(t, hash+apiKeyStr, key, "key should be stored hashed")
+	}
+
+	principal, err := AuthenticateAPIKey(conn, key)
+	assert.NoError(t, err)
+	assert.Equal(t, &Principal{ID: apiKey.ID, Name: "billing", Scopes: []string{ScopeConvert}, Tier: "premium", Method: MethodAPIKey}, principal)
+
+	_, err = AuthenticateAPIKey(conn, key+"x")
+	assert.ErrorIs(t, err, ErrInvalidAPIKey)
+	_, err = AuthenticateAPIKey(conn, "not-a-key")
+	assert.ErrorIs(t, err, ErrInvalidAPIKey)
+
+	apiKeys, err := ListAPIKeys(conn)
+	assert.NoError(t, err)
+	assert.Equal(t, []APIKey{*apiKey}, apiKeys)
+
+	secret, err := RotateSigningSecret(conn, apiKey.ID)
+	assert.NoError(t, err)
+	assert.Regexp(t, `^cfys_[A-Za-z0-9_-]{43}$`, secret)
+	principal, _ = AuthenticateAPIKey(conn, key)
+	assert.Equal(t, secret, principal.SigningSecret)
+	_, err = RotateSigningSecret(conn, "unknown")
+	assert.EqualError(t, err, "API key (unknown) not found")
+
+	assert.EqualError(t, RevokeAPIKey(conn, "unknown"), "API key (unknown) not found")
+	assert.NoError(t, RevokeAPIKey(conn, apiKey.ID))
+	_, err = AuthenticateAPIKey(conn, key)
+	assert.ErrorIs(t, err, ErrInvalidAPIKey, "revoked key should be rejected")
+}
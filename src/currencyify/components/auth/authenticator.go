@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// Authentication methods of the principals.
+const (
+	MethodAPIKey = "api_key"
+	MethodJWT    = "jwt"
+)
+
+// ErrMissingCredentials is returned when the request has neither bearer token nor API key.
+var ErrMissingCredentials = errors.New("API key or bearer token is required")
+
+type principalCtxKey struct{}
+
+// NewContext is used to attach the given principal to the given context.
+// It returns the context holding the principal.
+func NewContext(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalCtxKey{}, principal)
+}
+
+// FromContext is used to get the principal attached to the given context.
+// It returns the principal, and whether the context holds one.
+func FromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalCtxKey{}).(*Principal)
+	return principal, ok
+}
+
+// Credentials are the credentials passed with the request.
+type Credentials struct {
+	// Authorization is the value of the `Authorization` header, i.e. `Bearer <token>`.
+	Authorization string
+	APIKey        string
+}
+
+// Authenticator authenticates the requests using the bearer token when passed, or else the API key.
+type Authenticator struct {
+	// RedisConn opens the redis connection used to look up the API keys.
+	RedisConn func() (redis.Conn, error)
+	// JWTVerifier gets the verifier of the bearer tokens, nil when bearer tokens aren't accepted.
+	JWTVerifier func() *JWTVerifier
+}
+
+// Authenticate is used to authenticate the given credentials.
+// It returns the principal and error, wrapping `ErrMissingCredentials`, `ErrInvalidToken` or `ErrInvalidAPIKey` when
+// the credentials are missing or invalid.
+func (a *Authenticator) Authenticate(creds Credentials) (*Principal, error) {
+	if scheme, token, _ := strings.Cut(creds.Authorization, " "); strings.EqualFold(scheme, "Bearer") {
+		var verifier *JWTVerifier
+		if a.JWTVerifier != nil {
+			verifier = a.JWTVerifier()
+		}
+		if verifier == nil {
+			return nil, fmt.Errorf("%w: bearer tokens aren't accepted", ErrInvalidToken)
+		}
+
+		return verifier.Verify(strings.TrimSpace(token))
+	}
+
+	if creds.APIKey == "" {
+		return nil, ErrMissingCredentials
+	}
+
+	conn, err := a.RedisConn()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := conn.Close(); err != nil {
+			slog.Error("error closing redis connection", "error", err)
+		}
+	}()
+
+	return AuthenticateAPIKey(conn, creds.APIKey)
+}
+
+// IsUnauthenticated checks whether the error is caused by missing or invalid credentials, rather than a failure of the
+// service.
+func IsUnauthenticated(err error) bool {
+	return errors.Is(err, ErrMissingCredentials) || errors.Is(err, ErrInvalidToken) || errors.Is(err, ErrInvalidAPIKey)
+}
@@ -0,0 +1,462 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"currencyify/constants"
+
+	"github.com/go-jose/go-jose/v4"
+	"golang.org/x/sync/singleflight"
+)
+
+// jwtLeeway is the clock skew tolerated while validating the time based claims.
+const jwtLeeway = time.Minute
+
+// jwksMinRefreshInterval limits how often the JWKS is refetched for the tokens signed with an unknown key, and is the
+// first backoff after a failed fetch.
+const jwksMinRefreshInterval = time.Minute
+
+// jwksMaxBackoff is the max backoff between the fetches of the JWKS failing in a row.
+const jwksMaxBackoff = 15 * time.Minute
+
+// jwtMinRSAKeyBits is the min size of the RSA keys of the JWKS.
+const jwtMinRSAKeyBits = 2048
+
+// jwtAlgorithms are the asymmetric algorithms accepted for the signature of the tokens.
+var jwtAlgorithms = []jose.SignatureAlgorithm{
+	jose.RS256, jose.RS384, jose.RS512,
+	jose.PS256, jose.PS384, jose.PS512,
+	jose.ES256, jose.ES384, jose.ES512,
+}
+
+// jwtCurves are the curves of the EC keys required by the ES algorithms.
+var jwtCurves = map[jose.SignatureAlgorithm]elliptic.Curve{
+	jose.ES256: elliptic.P256(),
+	jose.ES384: elliptic.P384(),
+	jose.ES512: elliptic.P521(),
+}
+
+// ErrInvalidToken is wrapped by the errors of the bearer tokens failing validation.
+var ErrInvalidToken = errors.New("invalid bearer token")
+
+var (
+	defaultJWTVerifier     *JWTVerifier
+	defaultJWTVerifierOnce sync.Once
+)
+
+// JWTConfig is the config of the bearer tokens accepted, issued by the identity provider.
+type JWTConfig struct {
+	// JWKSURL is the URL of the JWKS of the identity provider. Either JWKSURL or JWKSFile is required.
+	JWKSURL string
+	// JWKSFile is the path of the local JWKS file, used instead of JWKSURL.
+	JWKSFile        string
+	RefreshInterval time.Duration
+
+	Issuer   string
+	Audience string
+
+	// ScopesClaim is the claim holding the scopes of the token, either space separated or as array.
+	ScopesClaim string
+	// ScopeMapping maps the scopes of the identity provider to the scopes of the service. Scopes of the token named
+	// same as the service scopes are taken as is when the mapping is empty.
+	ScopeMapping map[string]string
+}
+
+// JWTVerifier validates the bearer tokens against the JWKS of the identity provider, refetching the JWKS periodically
+// and when a token is signed with an unknown key.
+type JWTVerifier struct {
+	config JWTConfig
+	client *http.Client
+	now    func() time.Time
+
+	// fetches coalesces the concurrent fetches of the JWKS, made without holding mu
+	fetches singleflight.Group
+
+	mu          sync.Mutex
+	keys        map[string]jose.JSONWebKey
+	fetchedAt   time.Time
+	attemptedAt time.Time
+	failures    int
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+}
+
+// NewJWTVerifier is used to create a new verifier of the bearer tokens.
+// It returns the verifier instance.
+func NewJWTVerifier(config JWTConfig) *JWTVerifier {
+	if config.RefreshInterval <= 0 {
+		config.RefreshInterval = time.Hour
+	}
+	if config.ScopesClaim == "" {
+		config.ScopesClaim = "scope"
+	}
+
+	return &JWTVerifier{
+		config: config,
+		client: &http.Client{Timeout: 10 * time.Second},
+		now:    time.Now,
+	}
+}
+
+// DefaultJWTVerifier is used to get the verifier configured by the `JWT_*` env vars, created on first use.
+// It returns the verifier, or nil when bearer tokens aren't configured.
+func DefaultJWTVerifier() *JWTVerifier {
+	defaultJWTVerifierOnce.Do(func() {
+		if constants.JWT_JWKS_URL == "" && constants.JWT_JWKS_FILE == "" {
+			return
+		}
+		if constants.JWT_ISSUER == "" || constants.JWT_AUDIENCE == "" {
+			slog.Warn("bearer tokens are disabled, `JWT_ISSUER` and `JWT_AUDIENCE` are required along with the JWKS")
+			return
+		}
+
+		config := JWTConfig{
+			JWKSURL:      constants.JWT_JWKS_URL,
+			JWKSFile:     constants.JWT_JWKS_FILE,
+			Issuer:       constants.JWT_ISSUER,
+			Audience:     constants.JWT_AUDIENCE,
+			ScopesClaim:  constants.JWT_SCOPES_CLAIM,
+			ScopeMapping: make(map[string]string),
+		}
+		if constants.JWT_JWKS_REFRESH_INTERVAL != "" {
+			if interval, err := time.ParseDuration(constants.JWT_JWKS_REFRESH_INTERVAL); err == nil {
+				config.RefreshInterval = interval
+			} else {
+				slog.Warn("Error parsing duration JWT_JWKS_REFRESH_INTERVAL", "error", err)
+			}
+		}
+		for _, mapping := range strings.Split(constants.JWT_SCOPE_MAPPING, ",") {
+			if from, to, ok := strings.Cut(strings.TrimSpace(mapping), ":"); ok {
+				config.ScopeMapping[from] = to
+			}
+		}
+
+		defaultJWTVerifier = NewJWTVerifier(config)
+	})
+
+	return defaultJWTVerifier
+}
+
+// Verify is used to validate the signature, issuer, audience and expiry of the bearer token, and to map its scopes.
+// It returns the principal of the token subject and error, wrapping `ErrInvalidToken` when the token isn't valid.
+func (v *JWTVerifier) Verify(token string) (*Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed token", ErrInvalidToken)
+	}
+
+	var header jwtHeader
+	if err := decodeJWTSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("%w: malformed header", ErrInvalidToken)
+	}
+	alg := jose.SignatureAlgorithm(header.Alg)
+	if !slices.Contains(jwtAlgorithms, alg) {
+		return nil, fmt.Errorf("%w: unsupported algorithm (%s)", ErrInvalidToken, header.Alg)
+	}
+	jws, err := jose.ParseSignedCompact(token, jwtAlgorithms)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed token", ErrInvalidToken)
+	}
+
+	key, err := v.key(jws.Signatures[0].Header.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	if (key.Algorithm != "" && key.Algorithm != header.Alg) || !keyFits(key, alg) {
+		return nil, fmt.Errorf("%w: signing key isn't meant for the algorithm (%s)", ErrInvalidToken, header.Alg)
+	}
+	payload, err := jws.Verify(key.Key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: signature doesn't match", ErrInvalidToken)
+	}
+
+	var claims map[string]interface{}
+	if err = json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("%w: malformed claims", ErrInvalidToken)
+	}
+	if err = v.validateClaims(claims); err != nil {
+		return nil, err
+	}
+
+	subject, _ := claims["sub"].(string)
+	name := subject
+	for _, claim := range []string{"client_id", "azp"} {
+		if clientID, ok := claims[claim].(string); ok && clientID != "" {
+			name = clientID
+			break
+		}
+	}
+
+	return &Principal{
+		ID:     subject,
+		Name:   name,
+		Scopes: v.mapScopes(claims[v.config.ScopesClaim]),
+		Method: MethodJWT,
+	}, nil
+}
+
+// validateClaims checks the issuer, audience, expiry and not before claims of the token.
+func (v *JWTVerifier) validateClaims(claims map[string]interface{}) error {
+	now := v.now()
+
+	if iss, _ := claims["iss"].(string); iss != v.config.Issuer {
+		return fmt.Errorf("%w: unexpected issuer", ErrInvalidToken)
+	}
+
+	audienceFound := false
+	switch aud := claims["aud"].(type) {
+	case string:
+		audienceFound = aud == v.config.Audience
+	case []interface{}:
+		for _, a := range aud {
+			audienceFound = audienceFound || a == v.config.Audience
+		}
+	}
+	if !audienceFound {
+		return fmt.Errorf("%w: unexpected audience", ErrInvalidToken)
+	}
+
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return fmt.Errorf("%w: expiry is required", ErrInvalidToken)
+	} else if now.After(time.Unix(int64(exp), 0).Add(jwtLeeway)) {
+		return fmt.Errorf("%w: token is expired", ErrInvalidToken)
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(jwtLeeway).Before(time.Unix(int64(nbf), 0)) {
+		return fmt.Errorf("%w: token isn't valid yet", ErrInvalidToken)
+	}
+
+	if sub, _ := claims["sub"].(string); sub == "" {
+		return fmt.Errorf("%w: subject is required", ErrInvalidToken)
+	}
+
+	return nil
+}
+
+// mapScopes maps the scopes claim of the token, either space separated or as array, to the scopes of the service.
+// Scopes unknown to the service are dropped.
+func (v *JWTVerifier) mapScopes(claim interface{}) []string {
+	var tokenScopes []string
+	switch c := claim.(type) {
+	case string:
+		tokenScopes = strings.Fields(c)
+	case []interface{}:
+		for _, s := range c {
+			if scope, ok := s.(string); ok {
+				tokenScopes = append(tokenScopes, scope)
+			}
+		}
+	}
+
+	principalScopes := make([]string, 0)
+	for _, scope := range tokenScopes {
+		if len(v.config.ScopeMapping) > 0 {
+			scope = v.config.ScopeMapping[scope]
+		}
+		if ValidateScopes([]string{scope}) == nil {
+			principalScopes = append(principalScopes, scope)
+		}
+	}
+
+	return principalScopes
+}
+
+// key is used to get the public key of the given key ID, refetching the JWKS when it is stale or the key is unknown.
+// Known keys are served while a stale JWKS is refetched in background, so the verification never waits for the
+// identity provider unless the key is unknown. Fetches failing in a row are retried with exponential backoff.
+// Tokens without key ID are accepted only when the JWKS has a single key.
+// It returns the key and error, wrapping `ErrInvalidToken` when the key is unknown or the JWKS can't be fetched.
+func (v *JWTVerifier) key(kid string) (jose.JSONWebKey, error) {
+	v.mu.Lock()
+	key, ok := v.lookup(kid)
+	due := v.refreshDue(ok)
+	v.mu.Unlock()
+
+	if !due {
+		if !ok {
+			return jose.JSONWebKey{}, fmt.Errorf("%w: unknown signing key", ErrInvalidToken)
+		}
+		return key, nil
+	}
+
+	if ok {
+		// keep using the known key till the JWKS is refetched, even when the identity provider is unreachable
+		v.fetches.DoChan("jwks", v.refresh)
+		return key, nil
+	}
+
+	if _, err, _ := v.fetches.Do("jwks", v.refresh); err != nil {
+		return jose.JSONWebKey{}, fmt.Errorf("%w: unknown signing key, error fetching JWKS: %v", ErrInvalidToken, err)
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if key, ok = v.lookup(kid); !ok {
+		return jose.JSONWebKey{}, fmt.Errorf("%w: unknown signing key", ErrInvalidToken)
+	}
+
+	return key, nil
+}
+
+// lookup is used to get the known public key of the given key ID, the only key when the key ID is empty. It should be
+// called holding mu.
+// It returns the key and whether it is known.
+func (v *JWTVerifier) lookup(kid string) (jose.JSONWebKey, bool) {
+	if kid == "" && len(v.keys) == 1 {
+		for _, key := range v.keys {
+			return key, true
+		}
+	}
+	key, ok := v.keys[kid]
+
+	return key, ok
+}
+
+// refreshDue checks whether the JWKS should be refetched, being stale, or not having the key of the token for a while,
+// as unknown keys are refetched at most once per jwksMinRefreshInterval against the tokens having made up key IDs.
+// After failed fetches, it waits for the backoff since the last attempt. It should be called holding mu.
+func (v *JWTVerifier) refreshDue(known bool) bool {
+	sinceAttempt := v.now().Sub(v.attemptedAt)
+	if v.failures > 0 {
+		return sinceAttempt >= min(jwksMinRefreshInterval<<min(v.failures-1, 4), jwksMaxBackoff)
+	}
+
+	return v.now().Sub(v.fetchedAt) >= v.config.RefreshInterval || (!known && sinceAttempt >= jwksMinRefreshInterval)
+}
+
+// refresh fetches the JWKS, without holding mu, replacing the known keys on success and recording the attempt, so the
+// failed fetches are backed off.
+// It returns nil and error fetching the JWKS.
+func (v *JWTVerifier) refresh() (interface{}, error) {
+	v.mu.Lock()
+	v.attemptedAt = v.now()
+	v.mu.Unlock()
+
+	keys, err := v.fetch()
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if err != nil {
+		v.failures++
+		slog.Warn("error refreshing JWKS", "failures", v.failures, "error", err)
+		return nil, err
+	}
+
+	v.keys, v.fetchedAt, v.failures = keys, v.now(), 0
+	slog.Info("fetched JWKS keys", "keys", len(keys))
+
+	return nil, nil
+}
+
+// fetch fetches the JWKS from the configured URL or file.
+// It returns the signature keys by key ID and error.
+func (v *JWTVerifier) fetch() (map[string]jose.JSONWebKey, error) {
+	var jwksBytes []byte
+	var err error
+	if v.config.JWKSFile != "" {
+		if jwksBytes, err = os.ReadFile(v.config.JWKSFile); err != nil {
+			return nil, err
+		}
+	} else {
+		resp, err := v.client.Get(v.config.JWKSURL)
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			_ = resp.Body.Close()
+		}()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("error fetching JWKS, received status: %d", resp.StatusCode)
+		}
+		if jwksBytes, err = io.ReadAll(resp.Body); err != nil {
+			return nil, err
+		}
+	}
+
+	var jwks struct {
+		Keys []json.RawMessage `json:"keys"`
+	}
+	if err = json.Unmarshal(jwksBytes, &jwks); err != nil {
+		return nil, fmt.Errorf("error parsing JWKS: %v", err)
+	}
+
+	keys := make(map[string]jose.JSONWebKey)
+	for i, rawKey := range jwks.Keys {
+		var key jose.JSONWebKey
+		if err = key.UnmarshalJSON(rawKey); err != nil {
+			slog.Warn("skipping JWKS key", "index", i, "error", err)
+			continue
+		}
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+		if err = validateKey(key); err != nil {
+			slog.Warn("skipping JWKS key", "kid", key.KeyID, "error", err)
+			continue
+		}
+		keys[key.KeyID] = key
+	}
+
+	return keys, nil
+}
+
+// validateKey checks the key is either a RSA key of at least jwtMinRSAKeyBits or an EC public key, fitting the
+// algorithm it is declared for.
+func validateKey(key jose.JSONWebKey) error {
+	switch pub := key.Key.(type) {
+	case *rsa.PublicKey:
+		if pub.N.BitLen() < jwtMinRSAKeyBits {
+			return fmt.Errorf("RSA key of %d bits is too short, it should be at least %d bits", pub.N.BitLen(), jwtMinRSAKeyBits)
+		}
+	case *ecdsa.PublicKey:
+	default:
+		return fmt.Errorf("unsupported key type: %T", key.Key)
+	}
+	if key.Algorithm != "" && !keyFits(key, jose.SignatureAlgorithm(key.Algorithm)) {
+		return fmt.Errorf("key doesn't fit its algorithm (%s)", key.Algorithm)
+	}
+
+	return nil
+}
+
+// keyFits checks whether the key can verify the signatures of the given algorithm, as the RS and PS algorithms require
+// a RSA key, and the ES algorithms an EC key on their curve.
+func keyFits(key jose.JSONWebKey, alg jose.SignatureAlgorithm) bool {
+	if !slices.Contains(jwtAlgorithms, alg) {
+		return false
+	}
+	switch pub := key.Key.(type) {
+	case *rsa.PublicKey:
+		return jwtCurves[alg] == nil
+	case *ecdsa.PublicKey:
+		return jwtCurves[alg] == pub.Curve
+	default:
+		return false
+	}
+}
+
+// decodeJWTSegment decodes the base64url encoded JSON segment of the token.
+func decodeJWTSegment(segment string, v interface{}) error {
+	segmentBytes, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(segmentBytes, v)
+}
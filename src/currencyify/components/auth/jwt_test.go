@@ -0,0 +1,553 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"currencyify/utils"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testIssuer   = "https://idp.example.com/"
+	testAudience = "currencyify"
+)
+
+type testSigner struct {
+	rsaKey   *rsa.PrivateKey
+	ecKey    *ecdsa.PrivateKey
+	otherKey *rsa.PrivateKey
+	weakKey  *rsa.PrivateKey
+}
+
+func newTestSigner(t *testing.T) *testSigner {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	weakKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	assert.NoError(t, err)
+
+	return &testSigner{rsaKey: rsaKey, ecKey: ecKey, otherKey: otherKey, weakKey: weakKey}
+}
+
+// jwks is used to get the JWKS of the RSA, EC and weak RSA keys of the signer. The EC key is declared for ES256 as
+// `ec-1`, and listed without algorithm as `ec-2`.
+func (s *testSigner) jwks() []byte {
+	jwks, _ := json.Marshal(map[string]interface{}{
+		"keys": []map[string]string{
+			{
+				"kty": "RSA",
+				"kid": "rsa-1",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(s.rsaKey.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(s.rsaKey.E)).Bytes()),
+			},
+			{
+				"kty": "EC",
+				"kid": "ec-1",
+				"alg": "ES256",
+				"crv": "P-256",
+				"x":   base64.RawURLEncoding.EncodeToString(s.ecKey.X.FillBytes(make([]byte, 32))),
+				"y":   base64.RawURLEncoding.EncodeToString(s.ecKey.Y.FillBytes(make([]byte, 32))),
+			},
+			{
+				"kty": "EC",
+				"kid": "ec-2",
+				"crv": "P-256",
+				"x":   base64.RawURLEncoding.EncodeToString(s.ecKey.X.FillBytes(make([]byte, 32))),
+				"y":   base64.RawURLEncoding.EncodeToString(s.ecKey.Y.FillBytes(make([]byte, 32))),
+			},
+			{
+				"kty": "RSA",
+				"kid": "rsa-weak",
+				"n":   base64.RawURLEncoding.EncodeToString(s.weakKey.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(s.weakKey.E)).Bytes()),
+			},
+		},
+	})
+
+	return jwks
+}
+
+// sign is used to sign the claims using the given algorithm, with the RSA key of the signer for RS256 and PS256 and
+// the EC key for ES256. Kid `other` signs with a key which isn't in the JWKS, and kid `rsa-weak` with the weak RSA key.
+func (s *testSigner) sign(alg, kid string, claims map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	rsaKey := s.rsaKey
+	switch kid {
+	case "other":
+		rsaKey = s.otherKey
+	case "rsa-weak":
+		rsaKey = s.weakKey
+	}
+	hasher := crypto.SHA256.New()
+	hasher.Write([]byte(signingInput))
+	digest := hasher.Sum(nil)
+
+	var signature []byte
+	switch alg {
+	case "RS256":
+		signature, _ = rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest)
+	case "PS256":
+		signature, _ = rsa.SignPSS(rand.Reader, rsaKey, crypto.SHA256, digest, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+	case "ES256":
+		r, sig, _ := ecdsa.Sign(rand.Reader, s.ecKey, digest)
+		signature = append(r.FillBytes(make([]byte, 32)), sig.FillBytes(make([]byte, 32))...)
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// tamperJWT replaces the claims of the token, keeping its signature.
+func tamperJWT(token string, claims map[string]interface{}) string {
+	parts := strings.Split(token, ".")
+	payload, _ := json.Marshal(claims)
+
+	return parts[0] + "." + base64.RawURLEncoding.EncodeToString(payload) + "." + parts[2]
+}
+
+func testClaims(overrides map[string]interface{}) map[string]interface{} {
+	claims := map[string]interface{}{
+		"iss":   testIssuer,
+		"aud":   testAudience,
+		"sub":   "user-1",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"scope": "rates convert openid",
+	}
+	for claim, value := range overrides {
+		if value == nil {
+			delete(claims, claim)
+		} else {
+			claims[claim] = value
+		}
+	}
+
+	return claims
+}
+
+func TestJWTVerifier_Verify(t *testing.T) {
+	signer := newTestSigner(t)
+	jwksFile := filepath.Join(t.TempDir(), "jwks.json")
+	assert.NoError(t, os.WriteFile(jwksFile, signer.jwks(), 0o600))
+
+	type vars struct {
+		config JWTConfig
+		token  string
+	}
+
+	config := JWTConfig{JWKSFile: jwksFile, Issuer: testIssuer, Audience: testAudience}
+
+	testCases := []struct {
+		name string
+
+		vars vars
+
+		want   *Principal
+		hasErr bool
+		err    string
+	}{
+		{
+			name: "should success to verify the RS256 token and map its scopes",
+			vars: vars{
+				config: config,
+				token:  signer.sign("RS256", "rsa-1", testClaims(nil)),
+			},
+			want: &Principal{ID: "user-1", Name: "user-1", Scopes: []string{ScopeRates, ScopeConvert}, Method: MethodJWT},
+		},
+		{
+			name: "should success to verify the PS256 token",
+			vars: vars{
+				config: config,
+				token:  signer.sign("PS256", "rsa-1", testClaims(map[string]interface{}{"client_id": "billing"})),
+			},
+			want: &Principal{ID: "user-1", Name: "billing", Scopes: []string{ScopeRates, ScopeConvert}, Method: MethodJWT},
+		},
+		{
+			name: "should success to verify the ES256 token having audience and scopes as arrays",
+			vars: vars{
+				config: config,
+				token:  signer.sign("ES256", "ec-1", testClaims(map[string]interface{}{"aud": []string{"other", testAudience}, "scope": []string{"admin"}})),
+			},
+			want: &Principal{ID: "user-1", Name: "user-1", Scopes: []string{ScopeAdmin}, Method: MethodJWT},
+		},
+		{
+			name: "should success to map the scopes of the identity provider",
+			vars: vars{
+				config: JWTConfig{JWKSFile: jwksFile, Issuer: testIssuer, Audience: testAudience, ScopesClaim: "scp", ScopeMapping: map[string]string{"fx.read": ScopeRates}},
+				token:  signer.sign("RS256", "rsa-1", testClaims(map[string]interface{}{"scp": "fx.read rates"})),
+			},
+			want: &Principal{ID: "user-1", Name: "user-1", Scopes: []string{ScopeRates}, Method: MethodJWT},
+		},
+		{
+			name: "should fail when token is expired",
+			vars: vars{
+				config: config,
+				token:  signer.sign("RS256", "rsa-1", testClaims(map[string]interface{}{"exp": time.Now().Add(-time.Hour).Unix()})),
+			},
+			hasErr: true,
+			err:    "token is expired",
+		},
+		{
+			name: "should fail when token doesn't have expiry",
+			vars: vars{
+				config: config,
+				token:  signer.sign("RS256", "rsa-1", testClaims(map[string]interface{}{"exp": nil})),
+			},
+			hasErr: true,
+			err:    "expiry is required",
+		},
+		{
+			name: "should fail when token isn't valid yet",
+			vars: vars{
+				config: config,
+				token:  signer.sign("RS256", "rsa-1", testClaims(map[string]interface{}{"nbf": time.Now().Add(time.Hour).Unix()})),
+			},
+			hasErr: true,
+			err:    "token isn't valid yet",
+		},
+		{
+			name: "should fail when token is issued by another issuer",
+			vars: vars{
+				config: config,
+				token:  signer.sign("RS256", "rsa-1", testClaims(map[string]interface{}{"iss": "https://evil.example.com/"})),
+			},
+			hasErr: true,
+			err:    "unexpected issuer",
+		},
+		{
+			name: "should fail when token is issued for another audience",
+			vars: vars{
+				config: config,
+				token:  signer.sign("RS256", "rsa-1", testClaims(map[string]interface{}{"aud": "other"})),
+			},
+			hasErr: true,
+			err:    "unexpected audience",
+		},
+		{
+			name: "should fail when signature doesn't match",
+			vars: vars{
+				config: config,
+				token:  tamperJWT(signer.sign("RS256", "rsa-1", testClaims(nil)), testClaims(map[string]interface{}{"sub": "admin"})),
+			},
+			hasErr: true,
+			err:    "signature doesn't match",
+		},
+		{
+			name: "should fail when token is signed with unknown key",
+			vars: vars{
+				config: config,
+				token:  signer.sign("RS256", "other", testClaims(nil)),
+			},
+			hasErr: true,
+			err:    "unknown signing key",
+		},
+		{
+			name: "should fail when token is signed with RSA key shorter than 2048 bits",
+			vars: vars{
+				config: config,
+				token:  signer.sign("RS256", "rsa-weak", testClaims(nil)),
+			},
+			hasErr: true,
+			err:    "unknown signing key",
+		},
+		{
+			name: "should fail when algorithm of token isn't the one declared by the key",
+			vars: vars{
+				config: config,
+				token:  signer.sign("ES384", "ec-1", testClaims(nil)),
+			},
+			hasErr: true,
+			err:    "signing key isn't meant for the algorithm (ES384)",
+		},
+		{
+			name: "should fail when algorithm of token requires another curve",
+			vars: vars{
+				config: config,
+				token:  signer.sign("ES512", "ec-2", testClaims(nil)),
+			},
+			hasErr: true,
+			err:    "signing key isn't meant for the algorithm (ES512)",
+		},
+		{
+			name: "should fail when algorithm of token requires another key type",
+			vars: vars{
+				config: config,
+				token:  signer.sign("ES256", "rsa-1", testClaims(nil)),
+			},
+			hasErr: true,
+			err:    "signing key isn't meant for the algorithm (ES256)",
+		},
+		{
+			name: "should fail when token isn't signed",
+			vars: vars{
+				config: config,
+				token:  signer.sign("none", "rsa-1", testClaims(nil)),
+			},
+			hasErr: true,
+			err:    "unsupported algorithm (none)",
+		},
+		{
+			name: "should fail when token is malformed",
+			vars: vars{
+				config: config,
+				token:  "not-a-token",
+			},
+			hasErr: true,
+			err:    "malformed token",
+		},
+	}
+
+	for _, tCase := range testCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			// Setup
+			v := NewJWTVerifier(tCase.vars.config)
+
+			// Run test
+			got, err := v.Verify(tCase.vars.token)
+
+			// Assert
+			if tCase.hasErr {
+				if assert.Errorf(t, err, "case: %v", tCase) {
+					assert.ErrorIsf(t, err, ErrInvalidToken, "case: %v", tCase)
+					assert.Containsf(t, err.Error(), tCase.err, "case: %v", tCase)
+				}
+			} else {
+				assert.NoErrorf(t, err, "case: %v", tCase)
+				assert.Equal(t, tCase.want, got, "case: %v", tCase)
+			}
+		})
+	}
+}
+
+func TestJWTVerifier_key(t *testing.T) {
+	// Setup
+	signer := newTestSigner(t)
+	fetches := 0
+	jwks := []byte(`{"keys":[]}`)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		_, _ = w.Write(jwks)
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	v := NewJWTVerifier(JWTConfig{JWKSURL: server.URL, Issuer: testIssuer, Audience: testAudience})
+	v.now = func() time.Time {
+		return now
+	}
+	token := signer.sign("RS256", "rsa-1", testClaims(nil))
+
+	// Run test & Assert
+	_, err := v.Verify(token)
+	assert.ErrorContains(t, err, "unknown signing key")
+	assert.Equal(t, 1, fetches)
+
+	jwks = signer.jwks() // key rotated in by the identity provider
+	_, err = v.Verify(token)
+	assert.ErrorContains(t, err, "unknown signing key", "unknown keys should not be refetched right away")
+	assert.Equal(t, 1, fetches)
+
+	now = now.Add(jwksMinRefreshInterval)
+	_, err = v.Verify(token)
+	assert.NoError(t, err, "unknown keys should be refetched after the min refresh interval")
+	assert.Equal(t, 2, fetches)
+
+	_, err = v.Verify(token)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, fetches, "known keys should be served from memory")
+}
+
+func TestJWTVerifier_key_backoff(t *testing.T) {
+	// Setup
+	signer := newTestSigner(t)
+	fetches := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	v := NewJWTVerifier(JWTConfig{JWKSURL: server.URL, Issuer: testIssuer, Audience: testAudience})
+	v.now = func() time.Time {
+		return now
+	}
+	token := signer.sign("RS256", "rsa-1", testClaims(nil))
+
+	// Run test & Assert
+	_, err := v.Verify(token)
+	assert.ErrorIs(t, err, ErrInvalidToken, "failed fetches should fail the token validation")
+	assert.ErrorContains(t, err, "received status: 503")
+	assert.Equal(t, 1, fetches)
+
+	_, err = v.Verify(token)
+	assert.ErrorContains(t, err, "unknown signing key", "failed fetches should not be retried right away")
+	assert.Equal(t, 1, fetches)
+
+	now = now.Add(jwksMinRefreshInterval)
+	_, err = v.Verify(token)
+	assert.ErrorContains(t, err, "received status: 503")
+	assert.Equal(t, 2, fetches, "failed fetches should be retried after the backoff")
+
+	now = now.Add(jwksMinRefreshInterval)
+	_, _ = v.Verify(token)
+	assert.Equal(t, 2, fetches, "backoff should double after each failed fetch")
+
+	now = now.Add(jwksMinRefreshInterval)
+	_, _ = v.Verify(token)
+	assert.Equal(t, 3, fetches)
+}
+
+func TestJWTVerifier_key_refreshInBackground(t *testing.T) {
+	// Setup
+	signer := newTestSigner(t)
+	var fetches atomic.Int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fetches.Add(1) > 1 {
+			<-release // identity provider hanging
+		}
+		_, _ = w.Write(signer.jwks())
+	}))
+	defer server.Close()
+
+	var mu sync.Mutex
+	now := time.Now()
+	v := NewJWTVerifier(JWTConfig{JWKSURL: server.URL, Issuer: testIssuer, Audience: testAudience})
+	v.now = func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+	token := signer.sign("RS256", "rsa-1", testClaims(nil))
+	_, err := v.Verify(token)
+	assert.NoError(t, err)
+
+	// Run test
+	mu.Lock()
+	now = now.Add(v.config.RefreshInterval)
+	mu.Unlock()
+	for i := 0; i < 3; i++ {
+		_, err = v.Verify(token)
+		assert.NoError(t, err, "known keys should be served while the JWKS is refetched")
+	}
+	close(release)
+
+	// Assert
+	assert.Eventually(t, func() bool {
+		v.mu.Lock()
+		defer v.mu.Unlock()
+		return v.now().Equal(v.fetchedAt)
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, int32(2), fetches.Load(), "concurrent refetches should be coalesced")
+}
+
+func TestAuthenticator_Authenticate(t *testing.T) {
+	signer := newTestSigner(t)
+	jwksFile := filepath.Join(t.TempDir(), "jwks.json")
+	assert.NoError(t, os.WriteFile(jwksFile, signer.jwks(), 0o600))
+	verifier := NewJWTVerifier(JWTConfig{JWKSFile: jwksFile, Issuer: testIssuer, Audience: testAudience})
+	conn := utils.NewMockRedisConn()
+	key, apiKey, _ := CreateAPIKey(conn, "billing", []string{ScopeConvert}, "")
+
+	type vars struct {
+		creds       Credentials
+		jwtVerifier *JWTVerifier
+	}
+
+	testCases := []struct {
+		name string
+
+		vars vars
+
+		wantID     string
+		wantMethod string
+		hasErr     bool
+		err        error
+	}{
+		{
+			name: "should authenticate using the bearer token",
+			vars: vars{
+				creds:       Credentials{Authorization: "Bearer " + signer.sign("RS256", "rsa-1", testClaims(nil)), APIKey: key},
+				jwtVerifier: verifier,
+			},
+			wantID:     "user-1",
+			wantMethod: MethodJWT,
+		},
+		{
+			name: "should authenticate using the API key when bearer token isn't passed",
+			vars: vars{
+				creds:       Credentials{APIKey: key},
+				jwtVerifier: verifier,
+			},
+			wantID:     apiKey.ID,
+			wantMethod: MethodAPIKey,
+		},
+		{
+			name: "should fail when bearer tokens aren't accepted",
+			vars: vars{
+				creds: Credentials{Authorization: "Bearer " + signer.sign("RS256", "rsa-1", testClaims(nil))},
+			},
+			hasErr: true,
+			err:    ErrInvalidToken,
+		},
+		{
+			name: "should fail when credentials are missing",
+			vars: vars{
+				creds:       Credentials{Authorization: "Basic dXNlcjpwYXNz"},
+				jwtVerifier: verifier,
+			},
+			hasErr: true,
+			err:    ErrMissingCredentials,
+		},
+	}
+
+	for _, tCase := range testCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			// Setup
+			a := &Authenticator{
+				RedisConn: func() (redis.Conn, error) {
+					return conn, nil
+				},
+				JWTVerifier: func() *JWTVerifier {
+					return tCase.vars.jwtVerifier
+				},
+			}
+
+			// Run test
+			got, err := a.Authenticate(tCase.vars.creds)
+
+			// Assert
+			if tCase.hasErr {
+				assert.ErrorIsf(t, err, tCase.err, "case: %v", tCase)
+				assert.Truef(t, IsUnauthenticated(err), "case: %v", tCase)
+			} else if assert.NoErrorf(t, err, "case: %v", tCase) {
+				assert.Equal(t, tCase.wantID, got.ID, "case: %v", tCase)
+				assert.Equal(t, tCase.wantMethod, got.Method, "case: %v", tCase)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"currencyify/constants"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// Headers of the signed requests.
+const (
+	SignatureHeader          = "X-Signature"
+	SignatureTimestampHeader = "X-Signature-Timestamp"
+	SignatureNonceHeader     = "X-Signature-Nonce"
+)
+
+// defaultSignatureMaxSkew is the max difference between the timestamp of the signed requests and the server time, by
+// default.
+const defaultSignatureMaxSkew = 5 * time.Minute
+
+// signatureNonceKeyPrefix is the prefix of the redis keys of the nonces used by the signed requests.
+const signatureNonceKeyPrefix = "signature-nonce"
+
+// maxNonceLength limits the length of the nonces, so the clients can't fill redis with long nonces.
+const maxNonceLength = 128
+
+// ErrInvalidSignature is wrapped by the errors of the signed requests failing verification.
+var ErrInvalidSignature = errors.New("invalid request signature")
+
+// SignatureVerifier verifies the signatures of the requests, rejecting the replayed and skewed ones.
+type SignatureVerifier struct {
+	// RedisConn opens the redis connection holding the nonces of the recent requests.
+	RedisConn func() (redis.Conn, error)
+	// MaxSkew is the max difference between the timestamp of the request and the server time.
+	MaxSkew time.Duration
+
+	now func() time.Time
+}
+
+// SignedRequest is the request to verify, along with its signature headers.
+type SignedRequest struct {
+	Method string
+	// URI is the request URI, being the path along with the query string.
+	URI       string
+	Body      []byte
+	Timestamp string
+	Nonce     string
+	Signature string
+}
+
+// NewSignatureVerifier is used to create a new signature verifier, tolerating the skew set by `SIGNATURE_MAX_SKEW`.
+// It returns the verifier instance.
+func NewSignatureVerifier(redisConn func() (redis.Conn, error)) *SignatureVerifier {
+	maxSkew := defaultSignatureMaxSkew
+	if constants.SIGNATURE_MAX_SKEW != "" {
+		if skew, err := time.ParseDuration(constants.SIGNATURE_MAX_SKEW); err == nil && skew > 0 {
+			maxSkew = skew
+		}
+	}
+
+	return &SignatureVerifier{RedisConn: redisConn, MaxSkew: maxSkew, now: time.Now}
+}
+
+// Sign is used to compute the signature of the request, being the hex encoded HMAC-SHA256, keyed by the signing
+// secret, of the method, URI, timestamp, nonce and hex encoded SHA-256 of the body, joined by new lines.
+// It returns the signature.
+func Sign(secret string, req SignedRequest) string {
+	bodyHash := sha256.Sum256(req.Body)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strings.Join([]string{
+		strings.ToUpper(req.Method),
+		req.URI,
+		req.Timestamp,
+		req.Nonce,
+		hex.EncodeToString(bodyHash[:]),
+	}, "\n")))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify is used to verify the signature of the request of the principal, and to check that its timestamp is within
+// the max skew and its nonce isn't used by another request of the principal meanwhile.
+// It returns error wrapping `ErrInvalidSignature` when the request fails verification.
+func (v *SignatureVerifier) Verify(principal *Principal, req SignedRequest) error {
+	if req.Signature == "" || req.Timestamp == "" || req.Nonce == "" {
+		return fmt.Errorf("%w: `%s`, `%s` and `%s` headers are required", ErrInvalidSignature, SignatureHeader, SignatureTimestampHeader, SignatureNonceHeader)
+	}
+	if len(req.Nonce) > maxNonceLength {
+		return fmt.Errorf("%w: nonce is longer than %d characters", ErrInvalidSignature, maxNonceLength)
+	}
+
+	timestamp, err := strconv.ParseInt(req.Timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: timestamp should be unix seconds", ErrInvalidSignature)
+	}
+	if skew := v.now().Sub(time.Unix(timestamp, 0)); skew > v.MaxSkew || skew < -v.MaxSkew {
+		return fmt.Errorf("%w: timestamp is skewed by more than %s", ErrInvalidSignature, v.MaxSkew)
+	}
+
+	signature, err := hex.DecodeString(req.Signature)
+	if err != nil {
+		return fmt.Errorf("%w: signature should be hex encoded", ErrInvalidSignature)
+	}
+	want, _ := hex.DecodeString(Sign(principal.SigningSecret, req))
+	if !hmac.Equal(signature, want) {
+		return fmt.Errorf("%w: signature mismatch", ErrInvalidSignature)
+	}
+
+	conn, err := v.RedisConn()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := conn.Close(); err != nil {
+			slog.Error("error closing redis connection", "error", err)
+		}
+	}()
+
+	// The nonce is kept till the timestamp of the request is out of the max skew, so it can't be replayed.
+	nonceKey := fmt.Sprintf("%s:%s:%s", signatureNonceKeyPrefix, principal.ID, req.Nonce)
+	reply, err := conn.Do("SET", nonceKey, req.Timestamp, "EX", int((2 * v.MaxSkew).Seconds()), "NX")
+	if err != nil {
+		return errors.New("failed to set nonce in Redis")
+	}
+	if reply == nil {
+		return fmt.Errorf("%w: nonce is already used", ErrInvalidSignature)
+	}
+
+	return nil
+}
@@ -0,0 +1,153 @@
+package auth
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"currencyify/utils"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSign(t *testing.T) {
+	req := SignedRequest{Method: "post", URI: "/v1/convert/currency-convert?from=USD", Body: []byte(`{"amount":1}`), Timestamp: "1774915200", Nonce: "n1"}
+
+	got := Sign("secret", req)
+
+	assert.Regexp(t, `^[0-9a-f]{64}$`, got)
+	assert.Equal(t, got, Sign("secret", SignedRequest{Method: "POST", URI: req.URI, Body: req.Body, Timestamp: req.Timestamp, Nonce: req.Nonce}), "method should be case insensitive")
+	assert.NotEqual(t, got, Sign("other", req), "signature should depend on the secret")
+}
+
+func TestSignatureVerifier_Verify(t *testing.T) {
+	now := time.Date(2026, time.March, 31, 0, 0, 0, 0, time.UTC)
+	principal := &Principal{ID: "a1b2c3", SigningSecret: "cfys_secret"}
+	signed := func(req SignedRequest) SignedRequest {
+		req.Signature = Sign(principal.SigningSecret, req)
+		return req
+	}
+	valid := signed(SignedRequest{
+		Method:    "POST",
+		URI:       "/v1/convert/currency-convert",
+		Body:      []byte(`{"from":"USD","to":"INR","amount":1}`),
+		Timestamp: strconv.FormatInt(now.Unix(), 10),
+		Nonce:     "n1",
+	})
+
+	testCases := []struct {
+		name string
+
+		requests []SignedRequest
+
+		hasErr bool
+		err    string
+	}{
+		{
+			name:     "should success for the valid signature",
+			requests: []SignedRequest{valid},
+		},
+		{
+			name: "should success for the timestamp within the max skew",
+			requests: []SignedRequest{signed(SignedRequest{
+				Method:    "GET",
+				URI:       "/v1/exchange-rate/currency-exchange-rate?from=USD&to=INR",
+				Timestamp: strconv.FormatInt(now.Add(-4*time.Minute).Unix(), 10),
+				Nonce:     "n1",
+			})},
+		},
+		{
+			name:     "should fail when the signature headers are missing",
+			requests: []SignedRequest{{Method: "GET", URI: "/v1/exchange-rate/currency-exchange-rate"}},
+			hasErr:   true,
+			err:      "invalid request signature: `X-Signature`, `X-Signature-Timestamp` and `X-Signature-Nonce` headers are required",
+		},
+		{
+			name: "should fail for the timestamp not in unix seconds",
+			requests: []SignedRequest{signed(SignedRequest{
+				Method:    "GET",
+				URI:       "/v1/exchange-rate/currency-exchange-rate",
+				Timestamp: now.Format(time.RFC3339),
+				Nonce:     "n1",
+			})},
+			hasErr: true,
+			err:    "invalid request signature: timestamp should be unix seconds",
+		},
+		{
+			name: "should fail for the skewed timestamp",
+			requests: []SignedRequest{signed(SignedRequest{
+				Method:    "GET",
+				URI:       "/v1/exchange-rate/currency-exchange-rate",
+				Timestamp: strconv.FormatInt(now.Add(6*time.Minute).Unix(), 10),
+				Nonce:     "n1",
+			})},
+			hasErr: true,
+			err:    "invalid request signature: timestamp is skewed by more than 5m0s",
+		},
+		{
+			name: "should fail for the tampered body",
+			requests: []SignedRequest{{
+				Method:    valid.Method,
+				URI:       valid.URI,
+				Body:      []byte(`{"from":"USD","to":"INR","amount":1000}`),
+				Timestamp: valid.Timestamp,
+				Nonce:     valid.Nonce,
+				Signature: valid.Signature,
+			}},
+			hasErr: true,
+			err:    "invalid request signature: signature mismatch",
+		},
+		{
+			name: "should fail for the signature of another secret",
+			requests: []SignedRequest{{
+				Method:    valid.Method,
+				URI:       valid.URI,
+				Body:      valid.Body,
+				Timestamp: valid.Timestamp,
+				Nonce:     valid.Nonce,
+				Signature: Sign("cfys_other", valid),
+			}},
+			hasErr: true,
+			err:    "invalid request signature: signature mismatch",
+		},
+		{
+			name:     "should fail for the replayed nonce",
+			requests: []SignedRequest{valid, valid},
+			hasErr:   true,
+			err:      "invalid request signature: nonce is already used",
+		},
+	}
+
+	for _, tCase := range testCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			// Setup
+			conn := utils.NewMockRedisConn()
+			verifier := &SignatureVerifier{
+				RedisConn: func() (redis.Conn, error) {
+					return conn, nil
+				},
+				MaxSkew: defaultSignatureMaxSkew,
+				now: func() time.Time {
+					return now
+				},
+			}
+
+			// Run test
+			var err error
+			for _, req := range tCase.requests {
+				err = verifier.Verify(principal, req)
+			}
+
+			// Assert
+			if tCase.hasErr {
+				if assert.Errorf(t, err, "case: %v", tCase) {
+					assert.ErrorIsf(t, err, ErrInvalidSignature, "case: %v", tCase)
+					assert.EqualErrorf(t, err, tCase.err, "case: %v", tCase)
+				}
+			} else {
+				assert.NoErrorf(t, err, "case: %v", tCase)
+			}
+		})
+	}
+}
@@ -2,7 +2,10 @@ package components
 
 import (
 	"context"
+	"fmt"
+	"strings"
 
+	"currencyify/tracing"
 	"currencyify/utils"
 
 	"github.com/gomodule/redigo/redis"
@@ -14,4 +17,19 @@ type BaseComponent struct {
 	RedisConn redis.Conn
 }
 
+// Form is the input of the component methods, validated before it is processed.
+type Form interface {
+	Valid() error
+}
+
 var ComponentMap = make(map[string]func(*BaseComponent) interface{})
+
+// ValidForm is used to validate the given form within its own span of the request trace.
+// It returns the validation errors of the form.
+func (bc *BaseComponent) ValidForm(form Form) error {
+	_, span := tracing.Start(bc.ReqCtx, fmt.Sprintf("validate %s", strings.TrimPrefix(fmt.Sprintf("%T", form), "*")))
+	err := form.Valid()
+	tracing.End(span, err)
+
+	return err
+}
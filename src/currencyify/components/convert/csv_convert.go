@@ -0,0 +1,303 @@
+package convert
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"currencyify/components/audit"
+	"currencyify/constants"
+	"currencyify/utils"
+
+	"github.com/microcosm-cc/bluemonday"
+)
+
+const csvFlushInterval = 100
+
+// fetchCSVRates fetches the USD based rates of the given comma separated currencies on the given date, or the latest ones
+// when date is empty.
+var fetchCSVRates = func(reqCtx context.Context, currencyCodes, date string) (utils.Data, error) {
+	if date == "" {
+		return fetchCurrencyExchangeRate(reqCtx, currencyCodes)
+	}
+
+	return fetchHistoricalCurrencyExchangeRate(reqCtx, currencyCodes, date)
+}
+
+type CurrencyCSVConverterForm struct {
+	TargetCurrency string `form:"target_currency"`
+	AmountColumn   string `form:"amount_column"`
+	CurrencyColumn string `form:"currency_column"`
+	DateColumn     string `form:"date_column"`
+}
+
+// ConvertCSV is used to convert the amounts of the given CSV ledger to the target currency. Rows are read from the
+// reader and written to the writer one by one, with converted amount, exchange rate, rate timestamp and error columns
+// appended, so the ledger is never fully loaded into memory. Rows having a date are converted using the rates of that date.
+// Each converted row is recorded in the audit log.
+// It returns error when the conversion couldn't be started or the CSV can't be parsed, ending the output with an error
+// row then, while row level errors are reported in the error column.
+func (ccc *CurrencyConvertComponent) ConvertCSV(form *CurrencyCSVConverterForm, r io.Reader, w io.Writer) error {
+	if err := ccc.ValidForm(form); err != nil {
+		ccc.SetCurrencyConverterAppError(http.StatusBadRequest, err)
+		return err
+	}
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		err = fmt.Errorf("error reading CSV header: %v", err)
+		ccc.SetCurrencyConverterAppError(http.StatusBadRequest, err)
+		return err
+	}
+
+	amountIndex, currencyIndex, dateIndex := -1, -1, -1
+	for index, column := range header {
+		if column = strings.TrimSpace(column); column == form.AmountColumn {
+			amountIndex = index
+		} else if column == form.CurrencyColumn {
+			currencyIndex = index
+		} else if form.DateColumn != "" && column == form.DateColumn {
+			dateIndex = index
+		}
+	}
+	if amountIndex == -1 || currencyIndex == -1 || (form.DateColumn != "" && dateIndex == -1) {
+		err = errors.New("mapped columns not found in CSV header. Please check the `amount_column`, `currency_column` and `date_column` input params")
+		ccc.SetCurrencyConverterAppError(http.StatusBadRequest, err)
+		return err
+	}
+
+	writer := csv.NewWriter(w)
+	if err = writer.Write(append(header, "converted_amount", "exchange_rate", "rate_timestamp", "error")); err != nil {
+		return err
+	}
+
+	rates := make(map[string]*Currency)
+	failures := make(map[string]error)
+	for rowCount := 1; ; rowCount++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			// the rows read so far are already sent, so the client is told with an error row ending the output
+			err = fmt.Errorf("error reading CSV row %d: %v", rowCount, err)
+			ccc.SetCurrencyConverterAppError(http.StatusBadRequest, err)
+			if writeErr := writer.Write(append(make([]string, len(header)), "", "", "", err.Error())); writeErr != nil {
+				return writeErr
+			}
+			flushCSV(writer, w)
+			return err
+		}
+
+		date := ""
+		if dateIndex != -1 && dateIndex < len(record) {
+			date = record[dateIndex]
+		}
+
+		var row []string
+		if amountIndex >= len(record) || currencyIndex >= len(record) {
+			row = append(record, "", "", "", "mapped columns not found in row")
+		} else if amount, err := strconv.ParseFloat(strings.TrimSpace(record[amountIndex]), 64); err != nil {
+			row = append(record, "", "", "", "invalid amount")
+		} else if sourceRate, targetRate, err := ccc.getCSVRowRates(record[currencyIndex], form.TargetCurrency, date, rates, failures); err != nil {
+			row = append(record, "", "", "", err.Error())
+		} else {
+			exchangeRate := targetRate.rate / sourceRate.rate
+			row = append(record,
+				strconv.FormatFloat(amount*exchangeRate, 'f', -1, 64),
+				strconv.FormatFloat(exchangeRate, 'f', -1, 64),
+				olderRateTime(sourceRate.LastUpdateTime, targetRate.LastUpdateTime).Format(time.RFC3339),
+				"",
+			)
+
+			record := audit.Record{
+				SourceCurrency:  strings.ToUpper(strings.TrimSpace(record[currencyIndex])),
+				TargetCurrency:  form.TargetCurrency,
+				Amount:          amount,
+				Rate:            exchangeRate,
+				RateTimestamp:   olderRateTime(sourceRate.LastUpdateTime, targetRate.LastUpdateTime),
+				SourceAmount:    amount,
+				ConvertedAmount: amount * exchangeRate,
+			}
+			if strings.TrimSpace(date) != "" {
+				record.Provider = utils.VendorProvider(constants.FX_RATES_HISTORICAL_API_URL)
+			}
+			ccc.auditConversion(record)
+		}
+
+		if err = writer.Write(row); err != nil {
+			return err
+		}
+
+		if rowCount%csvFlushInterval == 0 {
+			flushCSV(writer, w)
+		}
+	}
+
+	flushCSV(writer, w)
+
+	return writer.Error()
+}
+
+type csvRate struct {
+	*Currency
+	rate float64
+}
+
+// getCSVRowRates resolves the USD based rates of the source and target currencies of a CSV row on the given date, or the
+// latest ones when date is empty. Resolved rates are kept in the given rates map, and the rates which couldn't be fetched
+// from the vendor in the failures map, so each of them is looked up only once per file.
+// It returns the source rate, target rate and error.
+func (ccc *CurrencyConvertComponent) getCSVRowRates(sourceCurrency, targetCurrency, date string, rates map[string]*Currency, failures map[string]error) (*csvRate, *csvRate, error) {
+	sourceCurrency = strings.ToUpper(strings.TrimSpace(sourceCurrency))
+	if currencies, err := currencyRegistry(); err != nil {
+		return nil, nil, err
+	} else if !currencies.Has(sourceCurrency) {
+		return nil, nil, fmt.Errorf("currency (%s) not found in our database", sourceCurrency)
+	}
+
+	if date = strings.TrimSpace(date); date != "" {
+		if len(date) > len(time.DateOnly) {
+			date = date[:len(time.DateOnly)]
+		}
+		if _, err := time.Parse(time.DateOnly, date); err != nil {
+			return nil, nil, fmt.Errorf("invalid date (%s), it should be in YYYY-MM-DD format", date)
+		}
+	}
+
+	cacheKey := func(currencyCode string) string {
+		if date == "" {
+			return currencyCode
+		}
+		return fmt.Sprintf("%s@%s", currencyCode, date)
+	}
+
+	pendingCurrencyCodes := make([]string, 0)
+	for _, currencyCode := range []string{sourceCurrency, targetCurrency} {
+		if err, ok := failures[cacheKey(currencyCode)]; ok {
+			return nil, nil, err
+		}
+		if _, ok := rates[cacheKey(currencyCode)]; ok || containsCurrency(pendingCurrencyCodes, currencyCode) {
+			continue
+		}
+
+		data := new(Currency)
+		if isDataInCache(ccc.ReqCtx, ccc.RedisConn, cacheKey(currencyCode), data) {
+			rates[cacheKey(currencyCode)] = data
+		} else {
+			pendingCurrencyCodes = append(pendingCurrencyCodes, currencyCode)
+		}
+	}
+
+	if len(pendingCurrencyCodes) > 0 {
+		resp, err := fetchCSVRates(ccc.ReqCtx, strings.Join(pendingCurrencyCodes, ","), date)
+		var rowErr error
+		for _, currencyCode := range pendingCurrencyCodes {
+			data := new(Currency)
+			rateErr := err
+			if errors.Is(err, utils.ErrVendorBudgetExhausted) && isDataInCache(ccc.ReqCtx, ccc.RedisConn, utils.StaleKey(cacheKey(currencyCode)), data) {
+				rateErr = nil
+			} else if err == nil {
+				if rateErr = processCurrencyExchangeRate(ccc.ReqCtx, currencyCode, resp, data); rateErr == nil {
+					cacheData(ccc.ReqCtx, ccc.RedisConn, cacheKey(currencyCode), data)
+				}
+			}
+			if rateErr != nil {
+				// kept, so the next rows of the currency fail without calling the vendor again
+				failures[cacheKey(currencyCode)], rowErr = rateErr, rateErr
+				continue
+			}
+			rates[cacheKey(currencyCode)] = data
+		}
+		if rowErr != nil {
+			return nil, nil, rowErr
+		}
+	}
+
+	sourceRate := &csvRate{Currency: rates[cacheKey(sourceCurrency)]}
+	targetRate := &csvRate{Currency: rates[cacheKey(targetCurrency)]}
+	var err error
+	if sourceRate.rate, err = strconv.ParseFloat(sourceRate.CurrencyExchangeRate, 64); err != nil {
+		return nil, nil, err
+	} else if targetRate.rate, err = strconv.ParseFloat(targetRate.CurrencyExchangeRate, 64); err != nil {
+		return nil, nil, err
+	}
+
+	return sourceRate, targetRate, nil
+}
+
+func fetchHistoricalCurrencyExchangeRate(reqCtx context.Context, currencyCodes, date string) (utils.Data, error) {
+	url := fmt.Sprintf("%v", constants.FX_RATES_HISTORICAL_API_URL)
+	reqHeaders := map[string]string{"Content-Type": "application/json"}
+	params := map[string]string{
+		"base":       "USD",
+		"currencies": currencyCodes,
+		"date":       date,
+		"amount":     "1",
+		"format":     "json",
+		"places":     "6",
+	}
+	var resp interface{}
+	var err error
+	if resp, err = utils.GetAPIResponse(reqCtx, "GetHistoricalCurrencyRate", url, http.MethodGet, nil, params, reqHeaders); err != nil {
+		return nil, err
+	}
+	caMap, _ := resp.(map[string]interface{})
+
+	slog.InfoContext(reqCtx, "fetched historical currency rate", "currencies", currencyCodes, "date", date)
+
+	return caMap, nil
+}
+
+// flushCSV flushes the buffered CSV rows and pushes them to the client when the writer supports it.
+func flushCSV(writer *csv.Writer, w io.Writer) {
+	writer.Flush()
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// GetCurrencyCSVConverterForm is used to create a new currency CSV converter form instance.
+// It returns currency CSV converter form instance.
+func (ccc *CurrencyConvertComponent) GetCurrencyCSVConverterForm() *CurrencyCSVConverterForm {
+	return new(CurrencyCSVConverterForm)
+}
+
+// Valid validates and sanitizes the currency CSV converter form. Column mappings default to `amount` and `currency`.
+func (f *CurrencyCSVConverterForm) Valid() error {
+	currencies, err := currencyRegistry()
+	if err != nil {
+		return err
+	}
+
+	errs := utils.ValidationErrors{}
+	p := bluemonday.UGCPolicy()
+
+	if f.TargetCurrency == "" {
+		errs.Add(utils.ErrCodeRequired, "target_currency", nil, "`target_currency` parameter is required")
+		return errs
+	} else if !currencies.Has(f.TargetCurrency) {
+		errs.Add(utils.ErrCodeUnknownCurrency, "target_currency", p.Sanitize(f.TargetCurrency), "`target_currency` not found in our database. Please check the `target_currency` input param, it should be a valid international-standard 3-letter ISO currency code")
+		return errs
+	}
+
+	f.TargetCurrency = p.Sanitize(strings.ToUpper(f.TargetCurrency))
+
+	if f.AmountColumn == "" {
+		f.AmountColumn = "amount"
+	}
+	if f.CurrencyColumn == "" {
+		f.CurrencyColumn = "currency"
+	}
+
+	return nil
+}
@@ -0,0 +1,259 @@
+package convert
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"currencyify/components"
+	"currencyify/constants"
+	"currencyify/utils"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCurrencyCSVConverterForm_Valid(t *testing.T) {
+	constants.CURRENCY_CODES_JSON_FILE_NAME = "../../currency_codes.json"
+
+	type vars struct {
+		form *CurrencyCSVConverterForm
+	}
+
+	testCases := []struct {
+		name string
+
+		vars vars
+
+		want   *CurrencyCSVConverterForm
+		hasErr bool
+		err    string
+	}{
+		{
+			name: "should fail when target currency code is empty",
+			vars: vars{
+				form: &CurrencyCSVConverterForm{},
+			},
+			hasErr: true,
+			err:    "`target_currency` parameter is required",
+		},
+		{
+			name: "should fail when target currency code format is not international-standard 3-letter ISO currency code",
+			vars: vars{
+				form: &CurrencyCSVConverterForm{
+					TargetCurrency: "India",
+				},
+			},
+			hasErr: true,
+			err:    "`target_currency` not found in our database. Please check the `target_currency` input param, it should be a valid international-standard 3-letter ISO currency code",
+		},
+		{
+			name: "should success to validate the currency CSV converter input form with default column mapping",
+			vars: vars{
+				form: &CurrencyCSVConverterForm{
+					TargetCurrency: "inr",
+				},
+			},
+			want: &CurrencyCSVConverterForm{
+				TargetCurrency: "INR",
+				AmountColumn:   "amount",
+				CurrencyColumn: "currency",
+			},
+		},
+	}
+
+	for _, tCase := range testCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			// Setup
+			form := tCase.vars.form
+
+			// Run test
+			err := form.Valid()
+
+			// Assert
+			if tCase.hasErr {
+				if assert.Errorf(t, err, "case: %v", tCase) {
+					assert.Containsf(t, err.Error(), tCase.err, "case: %v", tCase)
+				}
+			} else {
+				assert.NoErrorf(t, err, "case: %v", tCase)
+				assert.Equal(t, tCase.want, form, "case: %v", tCase)
+			}
+		})
+	}
+}
+
+func TestCurrencyConvertComponent_ConvertCSV(t *testing.T) {
+	constants.CURRENCY_CODES_JSON_FILE_NAME = "../../currency_codes.json"
+
+	type vars struct {
+		component components.BaseComponent
+
+		form *CurrencyCSVConverterForm
+		csv  string
+
+		headers     map[string]string
+		cachedRates map[string]*Currency
+	}
+
+	testCases := []struct {
+		name string
+
+		vars vars
+
+		want   string
+		hasErr bool
+		err    string
+	}{
+		{
+			name: "should success to convert the amounts of the given CSV to target currency",
+			vars: vars{
+				component: components.BaseComponent{
+					ReqCtx: context.Background(),
+				},
+				form: &CurrencyCSVConverterForm{
+					TargetCurrency: "INR",
+					AmountColumn:   "value",
+					CurrencyColumn: "ccy",
+					DateColumn:     "booked_on",
+				},
+				csv: "id,value,ccy,booked_on\n1,100,USD,\n2,10,usd,2024-01-15\n3,abc,USD,\n4,10,India,\n",
+				headers: map[string]string{
+					"x-mock-api": "default",
+				},
+			},
+			want: "id,value,ccy,booked_on,converted_amount,exchange_rate,rate_timestamp,error\n" +
+				"1,100,USD,,8277.1291,82.771291,2024-02-26T12:04:00Z,\n" +
+				"2,10,usd,2024-01-15,830,83,2024-01-15T23:59:00Z,\n" +
+				"3,abc,USD,,,,,invalid amount\n" +
+				"4,10,India,,,,,currency (INDIA) not found in our database\n",
+		},
+		{
+			name: "should report the timestamp of the older rate of the row",
+			vars: vars{
+				component: components.BaseComponent{
+					ReqCtx:    context.Background(),
+					RedisConn: utils.NewMockRedisConn(),
+				},
+				form: &CurrencyCSVConverterForm{
+					TargetCurrency: "INR",
+				},
+				csv: "id,amount,currency\n1,100,USD\n",
+				headers: map[string]string{
+					"x-mock-api": "default",
+				},
+				cachedRates: map[string]*Currency{
+					"USD": {CurrencyExchangeRate: "1", LastUpdateTime: time.Date(2024, time.February, 26, 11, 0, 0, 0, time.UTC)},
+				},
+			},
+			want: "id,amount,currency,converted_amount,exchange_rate,rate_timestamp,error\n" +
+				"1,100,USD,8277.1291,82.771291,2024-02-26T11:00:00Z,\n",
+		},
+		{
+			name: "should fail when mapped columns are not found in CSV header",
+			vars: vars{
+				component: components.BaseComponent{
+					ReqCtx: context.Background(),
+				},
+				form: &CurrencyCSVConverterForm{
+					TargetCurrency: "INR",
+				},
+				csv: "id,value,ccy\n1,100,USD\n",
+			},
+			hasErr: true,
+			err:    "mapped columns not found in CSV header",
+		},
+		{
+			name: "should end the output with an error row when the CSV can't be parsed",
+			vars: vars{
+				component: components.BaseComponent{
+					ReqCtx: context.Background(),
+				},
+				form: &CurrencyCSVConverterForm{
+					TargetCurrency: "INR",
+				},
+				csv: "id,amount,currency\n1,100,USD\n2,\"10,USD\n3,5,USD\n",
+				headers: map[string]string{
+					"x-mock-api": "default",
+				},
+			},
+			want: "id,amount,currency,converted_amount,exchange_rate,rate_timestamp,error\n" +
+				"1,100,USD,8277.1291,82.771291,2024-02-26T12:04:00Z,\n" +
+				",,,,,,\"error reading CSV row 2: record on line 3; parse error on line 4, column 9: extraneous or missing \"\" in quoted-field\"\n",
+			hasErr: true,
+			err:    "error reading CSV row 2",
+		},
+	}
+
+	for _, tCase := range testCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			// Setup
+			form := tCase.vars.form
+			ccc := &CurrencyConvertComponent{
+				BaseComponent: tCase.vars.component,
+			}
+			ctx := ccc.ReqCtx
+			ctx = context.WithValue(ctx, "x-mock-headers", tCase.vars.headers)
+			ccc.ReqCtx = ctx
+			for cacheKey, rate := range tCase.vars.cachedRates {
+				dataBytes, _ := json.Marshal(rate)
+				_, _ = utils.SetData(ccc.RedisConn, cacheKey, base64.StdEncoding.EncodeToString(dataBytes), 3600)
+			}
+			got := new(bytes.Buffer)
+
+			// Run test
+			err := ccc.ConvertCSV(form, strings.NewReader(tCase.vars.csv), got)
+
+			// Assert
+			if tCase.hasErr {
+				if assert.Errorf(t, err, "case: %v", tCase) {
+					assert.Containsf(t, err.Error(), tCase.err, "case: %v", tCase)
+				}
+				if tCase.want != "" {
+					assert.Equal(t, tCase.want, got.String(), "case: %v", tCase)
+				}
+			} else {
+				assert.NoErrorf(t, err, "case: %v", tCase)
+				assert.Equal(t, tCase.want, got.String(), "case: %v", tCase)
+			}
+		})
+	}
+}
+
+func TestCurrencyConvertComponent_ConvertCSV_vendorFailure(t *testing.T) {
+	constants.CURRENCY_CODES_JSON_FILE_NAME = "../../currency_codes.json"
+
+	// Setup
+	fetches := make([]string, 0)
+	oldFetchCSVRates := fetchCSVRates
+	fetchCSVRates = func(reqCtx context.Context, currencyCodes, date string) (utils.Data, error) {
+		fetches = append(fetches, currencyCodes+"@"+date)
+		return oldFetchCSVRates(reqCtx, currencyCodes, date)
+	}
+	defer func() {
+		fetchCSVRates = oldFetchCSVRates
+	}()
+	ccc := &CurrencyConvertComponent{
+		BaseComponent: components.BaseComponent{
+			ReqCtx: context.WithValue(context.Background(), "x-mock-headers", map[string]string{"x-mock-api": "vendor_error"}),
+		},
+	}
+	got := new(bytes.Buffer)
+
+	// Run test
+	err := ccc.ConvertCSV(&CurrencyCSVConverterForm{TargetCurrency: "INR", DateColumn: "date"},
+		strings.NewReader("amount,currency,date\n100,USD,\n10,USD,\n5,EUR,\n1,USD,2024-01-15\n2,USD,2024-01-15\n"), got)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"USD,INR@", "USD,INR@2024-01-15"}, fetches, "rates failing to be fetched should not be refetched for the next rows")
+	rows := strings.Split(strings.TrimSpace(got.String()), "\n")
+	if assert.Len(t, rows, 6) {
+		for _, row := range rows[1:] {
+			assert.NotEqual(t, ",", row[len(row)-1:], "row %q should report the vendor error", row)
+		}
+	}
+}
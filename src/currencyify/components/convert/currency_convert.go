@@ -6,19 +6,28 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
+	"math"
 	"net/http"
-	"os"
 	"strconv"
 	"strings"
 	"time"
 
 	"currencyify/components"
+	"currencyify/components/audit"
+	"currencyify/components/registry"
 	"currencyify/constants"
+	"currencyify/metrics"
+	"currencyify/rpc/pb"
+	"currencyify/tracing"
 	"currencyify/utils"
 
 	"github.com/gomodule/redigo/redis"
 	"github.com/microcosm-cc/bluemonday"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/protobuf/proto"
 )
 
 type CurrencyConvertComponent struct {
@@ -27,8 +36,12 @@ type CurrencyConvertComponent struct {
 
 type CurrencyConverter interface {
 	ConvertCurrency(*CurrencyConverterForm) (*CurrencyConverterResponse, error)
+	ConvertCurrencies(*CurrencyBatchConverterForm) (*CurrencyBatchConverterResponse, error)
+	ConvertCSV(*CurrencyCSVConverterForm, io.Reader, io.Writer) error
 
 	GetCurrencyConverterForm() *CurrencyConverterForm
+	GetCurrencyBatchConverterForm() *CurrencyBatchConverterForm
+	GetCurrencyCSVConverterForm() *CurrencyCSVConverterForm
 	GetCurrencyConverterAppError() *utils.AppError
 	SetCurrencyConverterAppError(int, error)
 }
@@ -37,26 +50,63 @@ type CurrencyConverterForm struct {
 	SourceCurrency string  `json:"source_currency"`
 	TargetCurrency string  `json:"target_currency"`
 	Amount         float64 `json:"amount"`
+	TargetAmount   float64 `json:"target_amount,omitempty"`
 }
 
 type CurrencyConverterResponse struct {
 	CurrencyConverterForm
 	ConvertedAmount float64 `json:"converted_amount"`
+	// RateTimestamp is the update time of the older of the source and target currency rates used.
+	RateTimestamp time.Time `json:"-"`
+	// CacheTTL is the number of seconds till the earliest of the rates used expires from cache.
+	CacheTTL int `json:"-"`
 }
 
-var currencyCodesMap map[string]int
+type CurrencyBatchConverterForm struct {
+	Items []*CurrencyConverterForm `json:"items"`
+}
+
+type CurrencyBatchConverterResponse struct {
+	Items []CurrencyBatchConverterItem `json:"items"`
+}
+
+type CurrencyBatchConverterItem struct {
+	Result *CurrencyConverterResponse `json:"result,omitempty"`
+	Error  string                     `json:"error,omitempty"`
+	// Errors lists the violations of the item, when it is invalid.
+	Errors []utils.ValidationError `json:"errors,omitempty"`
+}
+
+// currencyRegistry gets the registry of the supported currencies, loaded once and only read by the forms.
+var currencyRegistry = registry.DefaultRegistry
+
+// auditLogger gets the logger of the audit records of the conversions, nil when the audit log isn't configured.
+var auditLogger = audit.DefaultLogger
 
 type Currency struct {
 	CurrencyExchangeRate string
 	LastUpdateTime       time.Time
 }
 
+// ToProto is used to convert the converted data to its protobuf message.
+// It returns the `ConvertResponse` message.
+func (r *CurrencyConverterResponse) ToProto() proto.Message {
+	return &pb.ConvertResponse{
+		SourceCurrency:  r.SourceCurrency,
+		TargetCurrency:  r.TargetCurrency,
+		Amount:          r.Amount,
+		TargetAmount:    r.TargetAmount,
+		ConvertedAmount: r.ConvertedAmount,
+	}
+}
+
 // ConvertCurrency is used to convert the given amount from source currency to target currency. If data not found in cache then it will hit external APIs to fetch the conversion rates.
+// When target amount is given instead of amount, it computes the source amount needed to receive the target amount.
 // It returns the converted data and error.
 func (ccc *CurrencyConvertComponent) ConvertCurrency(form *CurrencyConverterForm) (*CurrencyConverterResponse, error) {
-	resp := new(CurrencyConverterResponse)
+	var resp *CurrencyConverterResponse
 	var err error
-	if err = form.Valid(); err != nil {
+	if err = ccc.ValidForm(form); err != nil {
 		ccc.AppError = &utils.AppError{
 			Status: http.StatusBadRequest,
 			Error:  err,
@@ -65,14 +115,147 @@ func (ccc *CurrencyConvertComponent) ConvertCurrency(form *CurrencyConverterForm
 		return nil, err
 	}
 
-	if sourceCurrencyRate, err := ccc.getCurrencyRate(form.SourceCurrency); err != nil {
+	rates, rateTimes, err := ccc.getCurrencyRates([]string{form.SourceCurrency, form.TargetCurrency})
+	if err != nil {
 		ccc.SetCurrencyConverterAppError(http.StatusInternalServerError, err)
 		return nil, err
-	} else if targetCurrencyRate, err := ccc.getCurrencyRate(form.TargetCurrency); err != nil {
+	} else if resp, err = convertAmount(form, rates); err != nil {
 		ccc.SetCurrencyConverterAppError(http.StatusInternalServerError, err)
 		return nil, err
+	}
+
+	resp.RateTimestamp = olderRateTime(rateTimes[form.SourceCurrency], rateTimes[form.TargetCurrency])
+	resp.CacheTTL = ccc.getCacheTTL([]string{form.SourceCurrency, form.TargetCurrency})
+	ccc.auditConversion(conversionRecord(form, resp, rates, rateTimes))
+
+	return resp, nil
+}
+
+// getCacheTTL gets the number of seconds till the earliest of the cached rates of the given currencies expires. It is
+// zero when any of the rates isn't in cache, as served from its stale copy.
+func (ccc *CurrencyConvertComponent) getCacheTTL(currencyCodes []string) int {
+	if ccc.RedisConn == nil {
+		return 0
+	}
+
+	cacheTTL, _ := strconv.Atoi(constants.REDIS_DEFAULT_EXPIRY)
+	for _, currencyCode := range currencyCodes {
+		if ttl, err := utils.GetTTL(ccc.RedisConn, currencyCode); err != nil || ttl < cacheTTL {
+			cacheTTL = ttl
+		}
+	}
+
+	return cacheTTL
+}
+
+// auditConversion queues the audit record of the conversion served, the provider of the rates being the vendor API of
+// the latest rates unless set. It doesn't wait for the record to be written.
+func (ccc *CurrencyConvertComponent) auditConversion(record audit.Record) {
+	logger := auditLogger()
+	if logger == nil {
+		return
+	}
+
+	if record.Provider == "" {
+		record.Provider = utils.VendorProvider(constants.FX_RATES_API_URL)
+	}
+	logger.Log(ccc.ReqCtx, record)
+}
+
+// conversionRecord is used to get the audit record of the conversion of the given form, along with the rate used and
+// its timestamp.
+func conversionRecord(form *CurrencyConverterForm, resp *CurrencyConverterResponse, rates map[string]float64, rateTimes map[string]time.Time) audit.Record {
+	return audit.Record{
+		SourceCurrency:  form.SourceCurrency,
+		TargetCurrency:  form.TargetCurrency,
+		Amount:          form.Amount,
+		TargetAmount:    form.TargetAmount,
+		Rate:            rates[form.TargetCurrency] / rates[form.SourceCurrency],
+		RateTimestamp:   olderRateTime(rateTimes[form.SourceCurrency], rateTimes[form.TargetCurrency]),
+		SourceAmount:    resp.Amount,
+		ConvertedAmount: resp.ConvertedAmount,
+	}
+}
+
+// olderRateTime gets the older of the given rate update times, as a conversion rate is as old as the older of its source
+// and target currency rates. Zero times are ignored.
+func olderRateTime(sourceTime, targetTime time.Time) time.Time {
+	if sourceTime.IsZero() || (!targetTime.IsZero() && targetTime.Before(sourceTime)) {
+		return targetTime
+	}
+
+	return sourceTime
+}
+
+// ConvertCurrencies is used to convert multiple amounts, each having its own source and target currency, in one go.
+// Rates of all the currencies are resolved together so that at most one vendor API call is made for the whole batch.
+// An item failing validation or conversion doesn't fail the whole batch, its error is reported along with the item instead.
+// Each converted item is recorded in the audit log.
+// It returns the converted items and error.
+func (ccc *CurrencyConvertComponent) ConvertCurrencies(form *CurrencyBatchConverterForm) (*CurrencyBatchConverterResponse, error) {
+	if err := ccc.ValidForm(form); err != nil {
+		ccc.SetCurrencyConverterAppError(http.StatusBadRequest, err)
+		return nil, err
+	}
+
+	resp := &CurrencyBatchConverterResponse{
+		Items: make([]CurrencyBatchConverterItem, len(form.Items)),
+	}
+
+	currencyCodes := make([]string, 0)
+	for index, item := range form.Items {
+		if err := item.Valid(); err != nil {
+			resp.Items[index].Error = err.Error()
+
+			var validationErrors utils.ValidationErrors
+			if errors.As(err, &validationErrors) {
+				resp.Items[index].Errors = validationErrors
+			}
+		} else {
+			currencyCodes = append(currencyCodes, item.SourceCurrency, item.TargetCurrency)
+		}
+	}
+
+	rates, rateTimes, ratesErr := ccc.getCurrencyRates(currencyCodes)
+	for index, item := range form.Items {
+		if resp.Items[index].Error != "" {
+			continue
+		}
+
+		if result, err := convertAmount(item, rates); err != nil {
+			if ratesErr != nil {
+				err = ratesErr
+			}
+			resp.Items[index].Error = err.Error()
+		} else {
+			resp.Items[index].Result = result
+			ccc.auditConversion(conversionRecord(item, result, rates, rateTimes))
+		}
+	}
+
+	return resp, nil
+}
+
+// convertAmount converts the amount of the given form using the given rates, which are relative to USD.
+// It returns the converted data and error.
+func convertAmount(form *CurrencyConverterForm, rates map[string]float64) (*CurrencyConverterResponse, error) {
+	sourceCurrencyRate, ok := rates[form.SourceCurrency]
+	if !ok {
+		return nil, &utils.RateNotFoundError{Currency: form.SourceCurrency}
+	}
+	targetCurrencyRate, ok := rates[form.TargetCurrency]
+	if !ok {
+		return nil, &utils.RateNotFoundError{Currency: form.TargetCurrency}
+	}
+
+	resp := new(CurrencyConverterResponse)
+	resp.CurrencyConverterForm = *form
+	if form.TargetAmount != 0.0 {
+		amountInUSD := form.TargetAmount / targetCurrencyRate
+		// the source amount is rounded up, so it is enough to receive the target amount
+		resp.Amount = roundUp(amountInUSD*sourceCurrencyRate, registry.MinorUnits(form.SourceCurrency))
+		resp.ConvertedAmount = form.TargetAmount
 	} else {
-		resp.CurrencyConverterForm = *form
 		amountInUSD := form.Amount / sourceCurrencyRate
 		resp.ConvertedAmount = amountInUSD * targetCurrencyRate
 	}
@@ -80,33 +263,104 @@ func (ccc *CurrencyConvertComponent) ConvertCurrency(form *CurrencyConverterForm
 	return resp, nil
 }
 
-func (ccc *CurrencyConvertComponent) getCurrencyRate(currencyCode string) (float64, error) {
-	data := new(Currency)
+// roundUp rounds the given amount up to the given number of decimal places. The float error of the amount is rounded
+// off first, so an exact amount isn't rounded up to the next minor unit.
+func roundUp(amount float64, decimals int) float64 {
+	scale := math.Pow10(decimals)
 
-	if !isDataInCache(ccc.RedisConn, currencyCode, data) {
-		if resp, err := fetchCurrencyExchangeRate(ccc.ReqCtx, currencyCode); err != nil {
-			return 0.0, err
-		} else if err = processCurrencyExchangeRate(currencyCode, resp, data); err != nil {
-			return 0.0, err
+	return math.Ceil(math.Round(amount*scale*1e6)/1e6) / scale
+}
+
+// getCurrencyRates resolves the USD based rates of the given currencies, from cache when available.
+// Currencies not found in cache are fetched together in a single vendor API call. A currency failing to resolve doesn't
+// stop the others from being resolved.
+// It returns the resolved rates, their last update times and the last error occurred.
+func (ccc *CurrencyConvertComponent) getCurrencyRates(currencyCodes []string) (map[string]float64, map[string]time.Time, error) {
+	result := make(map[string]float64)
+	rateTimes := make(map[string]time.Time)
+	pendingCurrencyCodes := make([]string, 0)
+	for _, currencyCode := range currencyCodes {
+		if _, ok := result[currencyCode]; ok || containsCurrency(pendingCurrencyCodes, currencyCode) {
+			continue
+		}
+
+		data := new(Currency)
+		if !isDataInCache(ccc.ReqCtx, ccc.RedisConn, currencyCode, data) {
+			pendingCurrencyCodes = append(pendingCurrencyCodes, currencyCode)
+		} else if rate, err := strconv.ParseFloat(data.CurrencyExchangeRate, 64); err != nil {
+			return result, rateTimes, err
 		} else {
-			cacheData(ccc.RedisConn, currencyCode, data)
+			result[currencyCode] = rate
+			rateTimes[currencyCode] = data.LastUpdateTime
 		}
 	}
 
-	currencyExchangeRate, err := strconv.ParseFloat(data.CurrencyExchangeRate, 64)
-	if err != nil {
-		return 0.0, err
+	if len(pendingCurrencyCodes) == 0 {
+		return result, rateTimes, nil
+	}
+
+	resp, err := fetchCurrencyExchangeRate(ccc.ReqCtx, strings.Join(pendingCurrencyCodes, ","))
+	if errors.Is(err, utils.ErrVendorBudgetExhausted) {
+		return ccc.getStaleCurrencyRates(pendingCurrencyCodes, result, rateTimes, err)
+	} else if err != nil {
+		return result, rateTimes, err
 	}
 
-	return currencyExchangeRate, nil
+	var processErr error
+	for _, currencyCode := range pendingCurrencyCodes {
+		data := new(Currency)
+		if err = processCurrencyExchangeRate(ccc.ReqCtx, currencyCode, resp, data); err != nil {
+			processErr = err
+		} else if rate, err := strconv.ParseFloat(data.CurrencyExchangeRate, 64); err != nil {
+			processErr = err
+		} else {
+			cacheData(ccc.ReqCtx, ccc.RedisConn, currencyCode, data)
+			result[currencyCode] = rate
+			rateTimes[currencyCode] = data.LastUpdateTime
+		}
+	}
+
+	return result, rateTimes, processErr
 }
 
-func fetchCurrencyExchangeRate(reqCtx context.Context, currencyCode string) (utils.Data, error) {
+// getStaleCurrencyRates resolves the rates of the given currencies from their stale copies, kept in cache after the
+// rates expire, when the vendor API call budget is exhausted. Currencies not having stale copies fail with the given
+// budget error.
+// It returns the resolved rates, their last update times and the last error occurred.
+func (ccc *CurrencyConvertComponent) getStaleCurrencyRates(currencyCodes []string, result map[string]float64, rateTimes map[string]time.Time, budgetErr error) (map[string]float64, map[string]time.Time, error) {
+	var staleErr error
+	for _, currencyCode := range currencyCodes {
+		data := new(Currency)
+		if !isDataInCache(ccc.ReqCtx, ccc.RedisConn, utils.StaleKey(currencyCode), data) {
+			staleErr = budgetErr
+		} else if rate, err := strconv.ParseFloat(data.CurrencyExchangeRate, 64); err != nil {
+			staleErr = err
+		} else {
+			slog.WarnContext(ccc.ReqCtx, "vendor API call budget exhausted, serving stale rate", "currency", currencyCode)
+			result[currencyCode] = rate
+			rateTimes[currencyCode] = data.LastUpdateTime
+		}
+	}
+
+	return result, rateTimes, staleErr
+}
+
+func containsCurrency(currencyCodes []string, currencyCode string) bool {
+	for _, code := range currencyCodes {
+		if code == currencyCode {
+			return true
+		}
+	}
+
+	return false
+}
+
+func fetchCurrencyExchangeRate(reqCtx context.Context, currencyCodes string) (utils.Data, error) {
 	url := fmt.Sprintf("%v", constants.FX_RATES_API_URL)
 	reqHeaders := map[string]string{"Content-Type": "application/json"}
 	params := map[string]string{
 		"base":       "USD",
-		"currencies": currencyCode,
+		"currencies": currencyCodes,
 		"resolution": "1m",
 		"amount":     "1",
 		"format":     "json",
@@ -119,12 +373,12 @@ func fetchCurrencyExchangeRate(reqCtx context.Context, currencyCode string) (uti
 	}
 	caMap, _ := resp.(map[string]interface{})
 
-	log.Printf("fetched latest currency rate for: %s", currencyCode)
+	slog.InfoContext(reqCtx, "fetched latest currency rate", "currencies", currencyCodes)
 
 	return caMap, nil
 }
 
-func processCurrencyExchangeRate(currencyCode string, resp utils.Data, data *Currency) error {
+func processCurrencyExchangeRate(ctx context.Context, currencyCode string, resp utils.Data, data *Currency) error {
 	if rates, ok := resp["rates"].(map[string]interface{}); ok {
 		if rate, ok := rates[currencyCode]; ok {
 			switch rate.(type) {
@@ -136,50 +390,65 @@ func processCurrencyExchangeRate(currencyCode string, resp utils.Data, data *Cur
 
 			data.LastUpdateTime, _ = time.Parse(time.RFC3339, resp["date"].(string))
 		} else {
-			return errors.New("received empty rates data from vendor API. Please check input params")
+			return &utils.RateNotFoundError{Currency: currencyCode}
 		}
 	} else {
 		return errors.New("error while processing exchange rates of vendor API data")
 	}
 
-	log.Printf("processed exchange rates data")
+	slog.DebugContext(ctx, "processed exchange rates data")
 
 	return nil
 }
 
-func isDataInCache(redisConn redis.Conn, currencyCode string, cacheData *Currency) bool {
+// isDataInCache looks up the rate cached under the given key within its own span of the request trace.
+// It returns whether the rate is found, unmarshaled into the given data.
+func isDataInCache(ctx context.Context, redisConn redis.Conn, currencyCode string, cacheData *Currency) (found bool) {
+	_, span := tracing.Start(ctx, "cache lookup", trace.WithAttributes(attribute.String("cache.key", currencyCode)))
+	defer func() {
+		span.SetAttributes(attribute.Bool("cache.hit", found))
+		span.End()
+	}()
+
 	if redisConn == nil {
-		log.Printf("redis conn not found to fetch data from cache")
+		slog.WarnContext(ctx, "redis conn not found to fetch data from cache")
 		return false
 	}
 	dataStr, err := utils.GetData(redisConn, currencyCode)
 	if err != nil {
-		log.Printf("data not found in cache for: %v", currencyCode)
+		slog.DebugContext(ctx, "data not found in cache", "key", currencyCode)
 	} else if dataBytes, err := base64.StdEncoding.DecodeString(dataStr); err != nil {
-		log.Printf("error while decoding base64 cache data")
+		slog.ErrorContext(ctx, "error while decoding base64 cache data", "key", currencyCode, "error", err)
 	} else if err := json.Unmarshal(dataBytes, cacheData); err != nil {
-		log.Printf("error unmarshaling cache data")
+		slog.ErrorContext(ctx, "error unmarshaling cache data", "key", currencyCode, "error", err)
 	} else {
-		log.Printf("data found in cache for: %v", currencyCode)
+		slog.DebugContext(ctx, "data found in cache", "key", currencyCode)
+		metrics.ObserveCacheLookup(currencyCode, true)
+		metrics.ObserveCachedRate(currencyCode, cacheData.LastUpdateTime)
 		return true
 	}
 
+	metrics.ObserveCacheLookup(currencyCode, false)
 	return false
 }
 
-func cacheData(redisConn redis.Conn, currencyCode string, cacheData *Currency) {
+func cacheData(ctx context.Context, redisConn redis.Conn, currencyCode string, cacheData *Currency) {
 	if redisConn == nil {
-		log.Printf("redis conn not found to store in cache")
+		slog.WarnContext(ctx, "redis conn not found to store in cache")
 		return
 	}
 	if respBytes, err := json.Marshal(cacheData); err != nil {
-		log.Printf("error marshaling data to store in cache")
+		slog.ErrorContext(ctx, "error marshaling data to store in cache", "key", currencyCode, "error", err)
 	} else if respStr := base64.StdEncoding.EncodeToString(respBytes); respStr != "" {
 		ttl, _ := strconv.Atoi(constants.REDIS_DEFAULT_EXPIRY)
-		if status, err := utils.SetData(redisConn, currencyCode, respStr, ttl); err != nil || !status {
-			log.Printf("error setting data in cache")
+		if status, err := utils.SetRateData(redisConn, currencyCode, respStr, ttl); err != nil || !status {
+			slog.ErrorContext(ctx, "error setting data in cache", "key", currencyCode, "error", err)
 		} else {
-			log.Printf("data succesfully stored in cache")
+			slog.DebugContext(ctx, "data succesfully stored in cache", "key", currencyCode)
+			metrics.ObserveCachedRate(currencyCode, cacheData.LastUpdateTime)
+		}
+		if status, err := utils.SetStaleData(redisConn, currencyCode, respStr); err != nil || !status {
+			slog.ErrorContext(ctx, "error setting stale data in cache", "key", currencyCode, "error", err)
 		}
 	}
 }
@@ -190,6 +459,12 @@ func (ccc *CurrencyConvertComponent) GetCurrencyConverterForm() *CurrencyConvert
 	return new(CurrencyConverterForm)
 }
 
+// GetCurrencyBatchConverterForm is used to create a new currency batch converter form instance.
+// It returns currency batch converter form instance.
+func (ccc *CurrencyConvertComponent) GetCurrencyBatchConverterForm() *CurrencyBatchConverterForm {
+	return new(CurrencyBatchConverterForm)
+}
+
 // GetCurrencyConverterAppError is used to retrieve app error from the currency converter component.
 // It returns app error of the component.
 func (ccc *CurrencyConvertComponent) GetCurrencyConverterAppError() *utils.AppError {
@@ -206,54 +481,61 @@ func (ccc *CurrencyConvertComponent) SetCurrencyConverterAppError(status int, er
 
 // Valid validates and sanitizes the currency converter form.
 func (f *CurrencyConverterForm) Valid() error {
-	currencyCodesStr, err := os.ReadFile(constants.CURRENCY_CODES_JSON_FILE_NAME)
+	currencies, err := currencyRegistry()
 	if err != nil {
 		return err
 	}
 
-	if err = json.Unmarshal(currencyCodesStr, &currencyCodesMap); err != nil {
-		return err
-	}
-
-	errMsg := ""
-	addErrMsg := func(msg string) {
-		if errMsg != "" {
-			errMsg += "\n"
-		}
-		errMsg += msg
-	}
+	errs := utils.ValidationErrors{}
+	p := bluemonday.UGCPolicy()
 
-	checkCurrency := func(currency string) bool {
-		_, ok := currencyCodesMap[strings.ToLower(currency)]
-		return ok
-	}
+	checkCurrency := currencies.Has
 
 	if f.SourceCurrency == "" {
-		addErrMsg("`source_currency` parameter is required")
+		errs.Add(utils.ErrCodeRequired, "source_currency", nil, "`source_currency` parameter is required")
 	} else if !checkCurrency(f.SourceCurrency) {
-		addErrMsg("`source_currency` not found in our database. Please check the `source_currency` input param, it should be a valid international-standard 3-letter ISO currency code")
+		errs.Add(utils.ErrCodeUnknownCurrency, "source_currency", p.Sanitize(f.SourceCurrency), "`source_currency` not found in our database. Please check the `source_currency` input param, it should be a valid international-standard 3-letter ISO currency code")
 	} else {
 		f.SourceCurrency = strings.ToUpper(f.SourceCurrency)
 	}
 
 	if f.TargetCurrency == "" {
-		addErrMsg("`target_currency` parameter is required")
+		errs.Add(utils.ErrCodeRequired, "target_currency", nil, "`target_currency` parameter is required")
 	} else if !checkCurrency(f.TargetCurrency) {
-		addErrMsg("`target_currency` not found in our database. Please check the `target_currency` input param, it should be a valid international-standard 3-letter ISO currency code")
+		errs.Add(utils.ErrCodeUnknownCurrency, "target_currency", p.Sanitize(f.TargetCurrency), "`target_currency` not found in our database. Please check the `target_currency` input param, it should be a valid international-standard 3-letter ISO currency code")
 	} else {
 		f.TargetCurrency = strings.ToUpper(f.TargetCurrency)
 	}
 
-	if f.Amount == 0.0 {
-		addErrMsg("`amount` parameter is required")
+	if f.Amount == 0.0 && f.TargetAmount == 0.0 {
+		errs.Add(utils.ErrCodeRequired, "amount", nil, "`amount` parameter is required, or `target_amount` parameter for reverse conversion")
+	} else if f.Amount != 0.0 && f.TargetAmount != 0.0 {
+		errs.Add(utils.ErrCodeConflict, "target_amount", f.TargetAmount, "only one of `amount` and `target_amount` parameters should be given")
 	}
 
-	p := bluemonday.UGCPolicy()
 	f.SourceCurrency = p.Sanitize(f.SourceCurrency)
 	f.TargetCurrency = p.Sanitize(f.TargetCurrency)
 
-	if errMsg != "" {
-		return errors.New(errMsg)
+	return errs.Err()
+}
+
+// Valid validates the currency batch converter form. Items are validated individually while converting.
+func (f *CurrencyBatchConverterForm) Valid() error {
+	errs := utils.ValidationErrors{}
+	if len(f.Items) == 0 {
+		errs.Add(utils.ErrCodeRequired, "items", nil, "`items` parameter is required")
+		return errs
+	}
+
+	if max, err := strconv.Atoi(constants.BATCH_CONVERT_MAX_ITEMS); err == nil && len(f.Items) > max {
+		errs.Add(utils.ErrCodeTooManyItems, "items", len(f.Items), fmt.Sprintf("`items` parameter can have at most %d items", max))
+		return errs
+	}
+
+	for index, item := range f.Items {
+		if item == nil {
+			f.Items[index] = new(CurrencyConverterForm)
+		}
 	}
 
 	return nil
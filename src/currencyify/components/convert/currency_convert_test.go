@@ -4,14 +4,22 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"io"
 	"net/http"
+	"strings"
 	"testing"
+	"time"
 
 	"currencyify/components"
+	"currencyify/components/audit"
 	"currencyify/constants"
 	"currencyify/utils"
 
 	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 func TestCurrencyConverterForm_Valid(t *testing.T) {
@@ -62,6 +70,19 @@ func TestCurrencyConverterForm_Valid(t *testing.T) {
 			hasErr: true,
 			err:    "`amount` parameter is required",
 		},
+		{
+			name: "should fail when both amount and target amount are given",
+			vars: vars{
+				form: &CurrencyConverterForm{
+					SourceCurrency: "USD",
+					TargetCurrency: "INR",
+					Amount:         10,
+					TargetAmount:   10,
+				},
+			},
+			hasErr: true,
+			err:    "only one of `amount` and `target_amount` parameters should be given",
+		},
 		{
 			name: "should fail when source currency code format is not international-standard 3-letter ISO currency code",
 			vars: vars{
@@ -265,9 +286,10 @@ func TestCurrencyConvertComponent_ConvertCurrency(t *testing.T) {
 
 		vars vars
 
-		want   string
-		hasErr bool
-		err    string
+		want              string
+		wantRateTimestamp time.Time
+		hasErr            bool
+		err               string
 	}{
 		{
 			name: "should success to convert the given amount from source currency to target currency",
@@ -284,7 +306,62 @@ func TestCurrencyConvertComponent_ConvertCurrency(t *testing.T) {
 					"x-mock-api": "default",
 				},
 			},
-			want: `{ "source_currency": "USD", "target_currency": "INR", "amount": 100, "converted_amount": 8277.1291 }`,
+			want:              `{ "source_currency": "USD", "target_currency": "INR", "amount": 100, "converted_amount": 8277.1291 }`,
+			wantRateTimestamp: time.Date(2024, time.February, 26, 12, 4, 0, 0, time.UTC),
+		},
+		{
+			name: "should success to compute the source amount needed to receive the given target amount",
+			vars: vars{
+				component: components.BaseComponent{
+					ReqCtx: context.Background(),
+				},
+				form: &CurrencyConverterForm{
+					SourceCurrency: "USD",
+					TargetCurrency: "INR",
+					TargetAmount:   8277.1291,
+				},
+				headers: map[string]string{
+					"x-mock-api": "default",
+				},
+			},
+			want:              `{ "source_currency": "USD", "target_currency": "INR", "amount": 100, "target_amount": 8277.1291, "converted_amount": 8277.1291 }`,
+			wantRateTimestamp: time.Date(2024, time.February, 26, 12, 4, 0, 0, time.UTC),
+		},
+		{
+			name: "should round the source amount needed to receive the given target amount up to the minor units of source currency",
+			vars: vars{
+				component: components.BaseComponent{
+					ReqCtx: context.Background(),
+				},
+				form: &CurrencyConverterForm{
+					SourceCurrency: "USD",
+					TargetCurrency: "INR",
+					TargetAmount:   1000,
+				},
+				headers: map[string]string{
+					"x-mock-api": "default",
+				},
+			},
+			want:              `{ "source_currency": "USD", "target_currency": "INR", "amount": 12.09, "target_amount": 1000, "converted_amount": 1000 }`,
+			wantRateTimestamp: time.Date(2024, time.February, 26, 12, 4, 0, 0, time.UTC),
+		},
+		{
+			name: "should round the source amount needed to receive the given target amount up to the whole units of source currency without minor units",
+			vars: vars{
+				component: components.BaseComponent{
+					ReqCtx: context.Background(),
+				},
+				form: &CurrencyConverterForm{
+					SourceCurrency: "JPY",
+					TargetCurrency: "INR",
+					TargetAmount:   100,
+				},
+				headers: map[string]string{
+					"x-mock-api": "default",
+				},
+			},
+			want:              `{ "source_currency": "JPY", "target_currency": "INR", "amount": 182, "target_amount": 100, "converted_amount": 100 }`,
+			wantRateTimestamp: time.Date(2024, time.February, 26, 12, 4, 0, 0, time.UTC),
 		},
 		{
 			name: "should fail to convert the given amount from source currency to target currency",
@@ -329,9 +406,374 @@ func TestCurrencyConvertComponent_ConvertCurrency(t *testing.T) {
 				assert.NoErrorf(t, err, "case: %v", tCase)
 				tempWant := new(CurrencyConverterResponse)
 				_ = json.Unmarshal([]byte(tCase.want), tempWant)
+				tempWant.RateTimestamp = tCase.wantRateTimestamp
 				assert.Equal(t, tempWant, got, "case: %v", tCase)
 			}
 		})
 	}
 
 }
+
+type mockAuditSink struct {
+	records []audit.Record
+}
+
+func (s *mockAuditSink) Write(records []audit.Record) error {
+	s.records = append(s.records, records...)
+	return nil
+}
+
+func (s *mockAuditSink) Close() error {
+	return nil
+}
+
+func TestCurrencyConvertComponent_ConvertCurrency_audit(t *testing.T) {
+	constants.CURRENCY_CODES_JSON_FILE_NAME = "../../currency_codes.json"
+	constants.FX_RATES_API_URL = "https://api.fxratesapi.com/latest"
+	rateTimestamp := time.Date(2024, time.February, 26, 12, 4, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name string
+
+		form    *CurrencyConverterForm
+		headers map[string]string
+
+		want []audit.Record
+	}{
+		{
+			name:    "should record the conversion served",
+			form:    &CurrencyConverterForm{SourceCurrency: "USD", TargetCurrency: "INR", Amount: 100},
+			headers: map[string]string{"x-mock-api": "default"},
+			want: []audit.Record{{
+				RequestID:       "req-1",
+				Client:          "api_key:a1b2c3",
+				ClientName:      "billing",
+				SourceCurrency:  "USD",
+				TargetCurrency:  "INR",
+				Amount:          100,
+				Rate:            82.771291,
+				RateTimestamp:   rateTimestamp,
+				Provider:        "api.fxratesapi.com",
+				SourceAmount:    100,
+				ConvertedAmount: 8277.1291,
+			}},
+		},
+		{
+			name:    "should record the reverse conversion served",
+			form:    &CurrencyConverterForm{SourceCurrency: "EUR", TargetCurrency: "USD", TargetAmount: 92},
+			headers: map[string]string{"x-mock-api": "default"},
+			want: []audit.Record{{
+				RequestID:       "req-1",
+				Client:          "api_key:a1b2c3",
+				ClientName:      "billing",
+				SourceCurrency:  "EUR",
+				TargetCurrency:  "USD",
+				TargetAmount:    92,
+				Rate:            1 / 0.92,
+				RateTimestamp:   rateTimestamp,
+				Provider:        "api.fxratesapi.com",
+				SourceAmount:    84.64,
+				ConvertedAmount: 92,
+			}},
+		},
+		{
+			name:    "should not record the conversion failed",
+			form:    &CurrencyConverterForm{SourceCurrency: "USD", TargetCurrency: "INR", Amount: 100},
+			headers: map[string]string{"x-mock-api": "error_response"},
+		},
+	}
+
+	for _, tCase := range testCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			// Setup
+			sink := &mockAuditSink{}
+			logger := audit.NewLogger(sink, 10)
+			auditLogger = func() *audit.Logger {
+				return logger
+			}
+			ctx := audit.NewContext(context.Background(), audit.Request{ID: "req-1", Client: "api_key:a1b2c3", ClientName: "billing"})
+			ccc := &CurrencyConvertComponent{
+				BaseComponent: components.BaseComponent{
+					ReqCtx: context.WithValue(ctx, "x-mock-headers", tCase.headers),
+				},
+			}
+
+			// Run test
+			_, _ = ccc.ConvertCurrency(tCase.form)
+			assert.NoError(t, logger.Close())
+
+			// Assert
+			if assert.Len(t, sink.records, len(tCase.want), "case: %v", tCase) {
+				for i, want := range tCase.want {
+					got := sink.records[i]
+					assert.False(t, got.Time.IsZero(), "case: %v", tCase)
+					got.Time = time.Time{}
+					assert.InDelta(t, want.Rate, got.Rate, 1e-9, "case: %v", tCase)
+					assert.InDelta(t, want.SourceAmount, got.SourceAmount, 1e-9, "case: %v", tCase)
+					got.Rate, got.SourceAmount, want.Rate, want.SourceAmount = 0, 0, 0, 0
+					assert.Equal(t, want, got, "case: %v", tCase)
+				}
+			}
+		})
+	}
+	auditLogger = audit.DefaultLogger
+}
+
+func TestCurrencyConvertComponent_audit(t *testing.T) {
+	constants.CURRENCY_CODES_JSON_FILE_NAME = "../../currency_codes.json"
+	constants.FX_RATES_API_URL = "https://api.fxratesapi.com/latest"
+	constants.FX_RATES_HISTORICAL_API_URL = "https://api.fxratesapi.com/historical"
+
+	type conversion struct {
+		sourceCurrency  string
+		targetCurrency  string
+		convertedAmount float64
+		provider        string
+	}
+
+	testCases := []struct {
+		name string
+
+		convert func(ccc *CurrencyConvertComponent)
+
+		want []conversion
+	}{
+		{
+			name: "should record each converted item of the batch",
+			convert: func(ccc *CurrencyConvertComponent) {
+				_, _ = ccc.ConvertCurrencies(&CurrencyBatchConverterForm{Items: []*CurrencyConverterForm{
+					{SourceCurrency: "USD", TargetCurrency: "INR", Amount: 100},
+					{SourceCurrency: "USD", TargetCurrency: "India", Amount: 100},
+					{SourceCurrency: "USD", TargetCurrency: "JPY", Amount: 1},
+				}})
+			},
+			want: []conversion{
+				{sourceCurrency: "USD", targetCurrency: "INR", convertedAmount: 8277.1291, provider: "api.fxratesapi.com"},
+				{sourceCurrency: "USD", targetCurrency: "JPY", convertedAmount: 150.608807, provider: "api.fxratesapi.com"},
+			},
+		},
+		{
+			name: "should record each holding of the portfolio valued",
+			convert: func(ccc *CurrencyConvertComponent) {
+				_, _ = ccc.ValuePortfolio(&PortfolioValuationForm{ReportingCurrency: "INR", Holdings: []*PortfolioHolding{{Currency: "USD", Amount: 100}}})
+			},
+			want: []conversion{
+				{sourceCurrency: "USD", targetCurrency: "INR", convertedAmount: 8277.1291, provider: "api.fxratesapi.com"},
+			},
+		},
+		{
+			name: "should record each converted row of the CSV",
+			convert: func(ccc *CurrencyConvertComponent) {
+				_ = ccc.ConvertCSV(&CurrencyCSVConverterForm{TargetCurrency: "INR", DateColumn: "date"}, strings.NewReader("amount,currency,date\n100,usd,\n10,USD,2024-01-15\nabc,USD,\n"), io.Discard)
+			},
+			want: []conversion{
+				{sourceCurrency: "USD", targetCurrency: "INR", convertedAmount: 8277.1291, provider: "api.fxratesapi.com"},
+				{sourceCurrency: "USD", targetCurrency: "INR", convertedAmount: 830, provider: "api.fxratesapi.com"},
+			},
+		},
+	}
+
+	for _, tCase := range testCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			// Setup
+			sink := &mockAuditSink{}
+			logger := audit.NewLogger(sink, 10)
+			auditLogger = func() *audit.Logger {
+				return logger
+			}
+			ctx := audit.NewContext(context.Background(), audit.Request{ID: "req-1", Client: "api_key:a1b2c3"})
+			ccc := &CurrencyConvertComponent{
+				BaseComponent: components.BaseComponent{
+					ReqCtx: context.WithValue(ctx, "x-mock-headers", map[string]string{"x-mock-api": "default"}),
+				},
+			}
+
+			// Run test
+			tCase.convert(ccc)
+			assert.NoError(t, logger.Close())
+
+			// Assert
+			if assert.Len(t, sink.records, len(tCase.want), "case: %v", tCase) {
+				for i, want := range tCase.want {
+					got := sink.records[i]
+					assert.Equal(t, "req-1", got.RequestID, "case: %v", tCase)
+					assert.Equal(t, want.sourceCurrency, got.SourceCurrency, "case: %v", tCase)
+					assert.Equal(t, want.targetCurrency, got.TargetCurrency, "case: %v", tCase)
+					assert.InDelta(t, want.convertedAmount, got.ConvertedAmount, 1e-6, "case: %v", tCase)
+					assert.Equal(t, want.provider, got.Provider, "case: %v", tCase)
+					assert.False(t, got.RateTimestamp.IsZero(), "case: %v", tCase)
+				}
+			}
+		})
+	}
+	auditLogger = audit.DefaultLogger
+}
+
+func TestCurrencyConvertComponent_ConvertCurrency_tracing(t *testing.T) {
+	constants.CURRENCY_CODES_JSON_FILE_NAME = "../../currency_codes.json"
+	constants.FX_RATES_API_URL = "https://api.fxratesapi.com/latest"
+	defaultProvider := otel.GetTracerProvider()
+
+	testCases := []struct {
+		name string
+
+		form    *CurrencyConverterForm
+		headers map[string]string
+
+		wantSpans  []string
+		wantFailed string
+	}{
+		{
+			name:      "should trace the validation, cache lookups and vendor call of the conversion",
+			form:      &CurrencyConverterForm{SourceCurrency: "USD", TargetCurrency: "INR", Amount: 100},
+			headers:   map[string]string{"x-mock-api": "default"},
+			wantSpans: []string{"validate convert.CurrencyConverterForm", "cache lookup", "cache lookup", "vendor GetLatestCurrencyRate"},
+		},
+		{
+			name:       "should trace the failed validation",
+			form:       &CurrencyConverterForm{SourceCurrency: "USD", Amount: 100},
+			wantSpans:  []string{"validate convert.CurrencyConverterForm"},
+			wantFailed: "validate convert.CurrencyConverterForm",
+		},
+		{
+			name:       "should trace the failed vendor call",
+			form:       &CurrencyConverterForm{SourceCurrency: "USD", TargetCurrency: "INR", Amount: 100},
+			headers:    map[string]string{"x-mock-api": "vendor_error"},
+			wantSpans:  []string{"validate convert.CurrencyConverterForm", "cache lookup", "cache lookup", "vendor GetLatestCurrencyRate"},
+			wantFailed: "vendor GetLatestCurrencyRate",
+		},
+	}
+
+	for _, tCase := range testCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			// Setup
+			recorder := tracetest.NewSpanRecorder()
+			otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+			ctx, parent := otel.Tracer("test").Start(context.Background(), "CurrencyConvertController.ConvertCurrency")
+			ccc := &CurrencyConvertComponent{
+				BaseComponent: components.BaseComponent{
+					ReqCtx:    context.WithValue(ctx, "x-mock-headers", tCase.headers),
+					RedisConn: utils.NewMockRedisConn(),
+				},
+			}
+
+			// Run test
+			_, _ = ccc.ConvertCurrency(tCase.form)
+			parent.End()
+
+			// Assert
+			spans := recorder.Ended()
+			if assert.Len(t, spans, len(tCase.wantSpans)+1, "case: %v", tCase) {
+				for i, want := range tCase.wantSpans {
+					assert.Equal(t, want, spans[i].Name(), "case: %v", tCase)
+					assert.Equal(t, parent.SpanContext().SpanID(), spans[i].Parent().SpanID(), "case: %v", tCase)
+					if want == tCase.wantFailed {
+						assert.Equal(t, codes.Error, spans[i].Status().Code, "case: %v, span: %v", tCase, want)
+					} else {
+						assert.Equal(t, codes.Unset, spans[i].Status().Code, "case: %v, span: %v", tCase, want)
+					}
+				}
+			}
+		})
+	}
+	otel.SetTracerProvider(defaultProvider)
+}
+
+func TestCurrencyConvertComponent_ConvertCurrencies(t *testing.T) {
+	constants.CURRENCY_CODES_JSON_FILE_NAME = "../../currency_codes.json"
+
+	type vars struct {
+		component components.BaseComponent
+
+		form *CurrencyBatchConverterForm
+
+		headers map[string]string
+	}
+
+	testCases := []struct {
+		name string
+
+		vars vars
+
+		want   string
+		hasErr bool
+		err    string
+	}{
+		{
+			name: "should success to convert all the given items and report per-item errors",
+			vars: vars{
+				component: components.BaseComponent{
+					ReqCtx: context.Background(),
+				},
+				form: &CurrencyBatchConverterForm{
+					Items: []*CurrencyConverterForm{
+						{SourceCurrency: "USD", TargetCurrency: "INR", Amount: 100},
+						{SourceCurrency: "usd", TargetCurrency: "eur", Amount: 50},
+						{SourceCurrency: "USD", TargetCurrency: "India", Amount: 10},
+						{SourceCurrency: "USD", TargetCurrency: "GBP", Amount: 10},
+					},
+				},
+				headers: map[string]string{
+					"x-mock-api": "default",
+				},
+			},
+			want: `{ "items": [ { "result": { "source_currency": "USD", "target_currency": "INR", "amount": 100, "converted_amount": 8277.1291 } }, { "result": { "source_currency": "USD", "target_currency": "EUR", "amount": 50, "converted_amount": 46 } }, { "error": "` + "`target_currency` not found in our database. Please check the `target_currency` input param, it should be a valid international-standard 3-letter ISO currency code" + `", "errors": [ { "code": "unknown_currency", "field": "target_currency", "value": "India", "message": "` + "`target_currency` not found in our database. Please check the `target_currency` input param, it should be a valid international-standard 3-letter ISO currency code" + `" } ] }, { "error": "exchange rate not found for: GBP" } ] }`,
+		},
+		{
+			name: "should success to report per-item errors when vendor API fails",
+			vars: vars{
+				component: components.BaseComponent{
+					ReqCtx: context.Background(),
+				},
+				form: &CurrencyBatchConverterForm{
+					Items: []*CurrencyConverterForm{
+						{SourceCurrency: "USD", TargetCurrency: "INR", Amount: 100},
+					},
+				},
+				headers: map[string]string{
+					"x-mock-api": "error_response",
+				},
+			},
+			want: `{ "items": [ { "error": "some error" } ] }`,
+		},
+		{
+			name: "should fail when items are empty",
+			vars: vars{
+				component: components.BaseComponent{
+					ReqCtx: context.Background(),
+				},
+				form: &CurrencyBatchConverterForm{},
+			},
+			hasErr: true,
+			err:    "`items` parameter is required",
+		},
+	}
+
+	for _, tCase := range testCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			// Setup
+			form := tCase.vars.form
+			ccc := &CurrencyConvertComponent{
+				BaseComponent: tCase.vars.component,
+			}
+			ctx := ccc.ReqCtx
+			ctx = context.WithValue(ctx, "x-mock-headers", tCase.vars.headers)
+			ccc.ReqCtx = ctx
+
+			// Run test
+			got, err := ccc.ConvertCurrencies(form)
+
+			// Assert
+			if tCase.hasErr {
+				if assert.Errorf(t, err, "case: %v", tCase) {
+					assert.Containsf(t, err.Error(), tCase.err, "case: %v", tCase)
+				}
+			} else {
+				assert.NoErrorf(t, err, "case: %v", tCase)
+				tempWant := new(CurrencyBatchConverterResponse)
+				_ = json.Unmarshal([]byte(tCase.want), tempWant)
+				assert.Equal(t, tempWant, got, "case: %v", tCase)
+			}
+		})
+	}
+}
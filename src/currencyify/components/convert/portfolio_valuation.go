@@ -0,0 +1,232 @@
+package convert
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"currencyify/components"
+	"currencyify/components/audit"
+	"currencyify/utils"
+
+	"github.com/microcosm-cc/bluemonday"
+)
+
+type PortfolioValuator interface {
+	ValuePortfolio(*PortfolioValuationForm) (*PortfolioValuationResponse, error)
+
+	GetPortfolioValuationForm() *PortfolioValuationForm
+	GetCurrencyConverterAppError() *utils.AppError
+	SetCurrencyConverterAppError(int, error)
+}
+
+type PortfolioValuationForm struct {
+	ReportingCurrency string              `json:"reporting_currency"`
+	Holdings          []*PortfolioHolding `json:"holdings"`
+}
+
+type PortfolioHolding struct {
+	Currency string  `json:"currency"`
+	Amount   float64 `json:"amount"`
+}
+
+type PortfolioValuationResponse struct {
+	ReportingCurrency string                `json:"reporting_currency"`
+	Holdings          []PortfolioValuedLine `json:"holdings"`
+	Total             float64               `json:"total"`
+	RateTime          time.Time             `json:"rate_time"`
+}
+
+type PortfolioValuedLine struct {
+	PortfolioHolding
+	ExchangeRate    float64 `json:"exchange_rate"`
+	ConvertedAmount float64 `json:"converted_amount"`
+}
+
+// ValuePortfolio is used to value the given holdings in the reporting currency. All the holdings are converted using
+// one rate snapshot, so the rates used for every line are of the same moment. Each line is recorded in the audit log.
+// It returns the converted holdings along with their total and error.
+func (ccc *CurrencyConvertComponent) ValuePortfolio(form *PortfolioValuationForm) (*PortfolioValuationResponse, error) {
+	if err := ccc.ValidForm(form); err != nil {
+		ccc.SetCurrencyConverterAppError(http.StatusBadRequest, err)
+		return nil, err
+	}
+
+	currencyCodes := []string{form.ReportingCurrency}
+	for _, holding := range form.Holdings {
+		currencyCodes = append(currencyCodes, holding.Currency)
+	}
+
+	rates, rateTime, err := ccc.getCurrencyRatesSnapshot(currencyCodes)
+	if err != nil {
+		ccc.SetCurrencyConverterAppError(http.StatusInternalServerError, err)
+		return nil, err
+	}
+
+	resp := &PortfolioValuationResponse{
+		ReportingCurrency: form.ReportingCurrency,
+		Holdings:          make([]PortfolioValuedLine, len(form.Holdings)),
+		RateTime:          rateTime,
+	}
+	for index, holding := range form.Holdings {
+		line := &resp.Holdings[index]
+		line.PortfolioHolding = *holding
+		line.ExchangeRate = rates[form.ReportingCurrency] / rates[holding.Currency]
+		line.ConvertedAmount = holding.Amount / rates[holding.Currency] * rates[form.ReportingCurrency]
+		resp.Total += line.ConvertedAmount
+
+		ccc.auditConversion(audit.Record{
+			SourceCurrency:  holding.Currency,
+			TargetCurrency:  form.ReportingCurrency,
+			Amount:          holding.Amount,
+			Rate:            line.ExchangeRate,
+			RateTimestamp:   rateTime,
+			SourceAmount:    holding.Amount,
+			ConvertedAmount: line.ConvertedAmount,
+		})
+	}
+
+	return resp, nil
+}
+
+// getCurrencyRatesSnapshot resolves the USD based rates of the given currencies as of the same moment. Cached rates are
+// used only when all of them are cached and share the same update time, otherwise all the rates are fetched together
+// in a single vendor API call. When the vendor API call budget is exhausted, the stale copies of the rates, kept in
+// cache after the rates expire, are used instead when all of them are there and share the same update time.
+// It returns the rates, the time of the snapshot and error.
+func (ccc *CurrencyConvertComponent) getCurrencyRatesSnapshot(currencyCodes []string) (map[string]float64, time.Time, error) {
+	uniqueCurrencyCodes := make([]string, 0)
+	for _, currencyCode := range currencyCodes {
+		if !containsCurrency(uniqueCurrencyCodes, currencyCode) {
+			uniqueCurrencyCodes = append(uniqueCurrencyCodes, currencyCode)
+		}
+	}
+
+	if rates, rateTime, ok := ccc.getCachedCurrencyRatesSnapshot(uniqueCurrencyCodes, false); ok {
+		return rates, rateTime, nil
+	}
+
+	resp, err := fetchCurrencyExchangeRate(ccc.ReqCtx, strings.Join(uniqueCurrencyCodes, ","))
+	if errors.Is(err, utils.ErrVendorBudgetExhausted) {
+		rates, rateTime, ok := ccc.getCachedCurrencyRatesSnapshot(uniqueCurrencyCodes, true)
+		if !ok {
+			return nil, time.Time{}, err
+		}
+		slog.WarnContext(ccc.ReqCtx, "vendor API call budget exhausted, serving stale rates snapshot", "currencies", strings.Join(uniqueCurrencyCodes, ","))
+		return rates, rateTime, nil
+	} else if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	rates := make(map[string]float64)
+	var rateTime time.Time
+	for _, currencyCode := range uniqueCurrencyCodes {
+		data := new(Currency)
+		if err = processCurrencyExchangeRate(ccc.ReqCtx, currencyCode, resp, data); err != nil {
+			return nil, time.Time{}, err
+		} else if rates[currencyCode], err = strconv.ParseFloat(data.CurrencyExchangeRate, 64); err != nil {
+			return nil, time.Time{}, err
+		}
+
+		cacheData(ccc.ReqCtx, ccc.RedisConn, currencyCode, data)
+		rateTime = data.LastUpdateTime
+	}
+
+	return rates, rateTime, nil
+}
+
+// getCachedCurrencyRatesSnapshot looks up the cached rates of the given currencies, or their stale copies, as a snapshot.
+// It returns the rates, the time of the snapshot and whether all of them are cached and share the same update time.
+func (ccc *CurrencyConvertComponent) getCachedCurrencyRatesSnapshot(currencyCodes []string, stale bool) (map[string]float64, time.Time, bool) {
+	rates := make(map[string]float64)
+	var rateTime time.Time
+	for index, currencyCode := range currencyCodes {
+		key := currencyCode
+		if stale {
+			key = utils.StaleKey(currencyCode)
+		}
+
+		data := new(Currency)
+		if !isDataInCache(ccc.ReqCtx, ccc.RedisConn, key, data) {
+			return nil, time.Time{}, false
+		} else if index > 0 && !data.LastUpdateTime.Equal(rateTime) {
+			return nil, time.Time{}, false
+		}
+
+		rate, err := strconv.ParseFloat(data.CurrencyExchangeRate, 64)
+		if err != nil {
+			return nil, time.Time{}, false
+		}
+		rates[currencyCode] = rate
+		rateTime = data.LastUpdateTime
+	}
+
+	return rates, rateTime, true
+}
+
+// GetPortfolioValuationForm is used to create a new portfolio valuation form instance.
+// It returns portfolio valuation form instance.
+func (ccc *CurrencyConvertComponent) GetPortfolioValuationForm() *PortfolioValuationForm {
+	return new(PortfolioValuationForm)
+}
+
+// Valid validates and sanitizes the portfolio valuation form.
+func (f *PortfolioValuationForm) Valid() error {
+	currencies, err := currencyRegistry()
+	if err != nil {
+		return err
+	}
+
+	errs := utils.ValidationErrors{}
+	p := bluemonday.UGCPolicy()
+
+	checkCurrency := currencies.Has
+
+	if f.ReportingCurrency == "" {
+		errs.Add(utils.ErrCodeRequired, "reporting_currency", nil, "`reporting_currency` parameter is required")
+	} else if !checkCurrency(f.ReportingCurrency) {
+		errs.Add(utils.ErrCodeUnknownCurrency, "reporting_currency", p.Sanitize(f.ReportingCurrency), "`reporting_currency` not found in our database. Please check the `reporting_currency` input param, it should be a valid international-standard 3-letter ISO currency code")
+	} else {
+		f.ReportingCurrency = strings.ToUpper(f.ReportingCurrency)
+	}
+
+	f.ReportingCurrency = p.Sanitize(f.ReportingCurrency)
+
+	if len(f.Holdings) == 0 {
+		errs.Add(utils.ErrCodeRequired, "holdings", nil, "`holdings` parameter is required")
+	}
+
+	for index, holding := range f.Holdings {
+		if holding == nil {
+			errs.Add(utils.ErrCodeRequired, fmt.Sprintf("holdings[%d]", index), nil, fmt.Sprintf("`holdings` (%d) should not be empty", index))
+			continue
+		}
+
+		if holding.Currency == "" {
+			errs.Add(utils.ErrCodeRequired, fmt.Sprintf("holdings[%d].currency", index), nil, fmt.Sprintf("`currency` parameter of `holdings` (%d) is required", index))
+		} else if !checkCurrency(holding.Currency) {
+			errs.Add(utils.ErrCodeUnknownCurrency, fmt.Sprintf("holdings[%d].currency", index), p.Sanitize(holding.Currency), fmt.Sprintf("`currency` (%s) of `holdings` (%d) not found in our database. Please check the `holdings` input param, it should be a valid international-standard 3-letter ISO currency code", p.Sanitize(holding.Currency), index))
+		} else {
+			holding.Currency = strings.ToUpper(holding.Currency)
+		}
+		holding.Currency = p.Sanitize(holding.Currency)
+
+		if holding.Amount == 0.0 {
+			errs.Add(utils.ErrCodeRequired, fmt.Sprintf("holdings[%d].amount", index), nil, fmt.Sprintf("`amount` parameter of `holdings` (%d) is required", index))
+		}
+	}
+
+	return errs.Err()
+}
+
+func init() {
+	components.ComponentMap["PortfolioValuation"] = func(bc *components.BaseComponent) interface{} {
+		c := &CurrencyConvertComponent{BaseComponent: *bc}
+
+		return PortfolioValuator(c)
+	}
+}
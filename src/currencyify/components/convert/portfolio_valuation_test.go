@@ -0,0 +1,230 @@
+package convert
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"currencyify/components"
+	"currencyify/constants"
+	"currencyify/utils"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPortfolioValuationForm_Valid(t *testing.T) {
+	constants.CURRENCY_CODES_JSON_FILE_NAME = "../../currency_codes.json"
+
+	type vars struct {
+		form *PortfolioValuationForm
+	}
+
+	testCases := []struct {
+		name string
+
+		vars vars
+
+		hasErr bool
+		err    string
+	}{
+		{
+			name: "should fail when reporting currency code is empty",
+			vars: vars{
+				form: &PortfolioValuationForm{
+					Holdings: []*PortfolioHolding{{Currency: "INR", Amount: 10}},
+				},
+			},
+			hasErr: true,
+			err:    "`reporting_currency` parameter is required",
+		},
+		{
+			name: "should fail when holdings are empty",
+			vars: vars{
+				form: &PortfolioValuationForm{
+					ReportingCurrency: "USD",
+				},
+			},
+			hasErr: true,
+			err:    "`holdings` parameter is required",
+		},
+		{
+			name: "should fail when any of the holdings currency code format is not international-standard 3-letter ISO currency code",
+			vars: vars{
+				form: &PortfolioValuationForm{
+					ReportingCurrency: "USD",
+					Holdings:          []*PortfolioHolding{{Currency: "INR", Amount: 10}, {Currency: "India", Amount: 10}},
+				},
+			},
+			hasErr: true,
+			err:    "`currency` (India) of `holdings` (1) not found in our database. Please check the `holdings` input param, it should be a valid international-standard 3-letter ISO currency code",
+		},
+		{
+			name: "should fail when any of the holdings amount is empty",
+			vars: vars{
+				form: &PortfolioValuationForm{
+					ReportingCurrency: "USD",
+					Holdings:          []*PortfolioHolding{{Currency: "INR"}},
+				},
+			},
+			hasErr: true,
+			err:    "`amount` parameter of `holdings` (0) is required",
+		},
+		{
+			name: "should success to validate the portfolio valuation input form",
+			vars: vars{
+				form: &PortfolioValuationForm{
+					ReportingCurrency: "usd",
+					Holdings:          []*PortfolioHolding{{Currency: "inr", Amount: 10}},
+				},
+			},
+		},
+	}
+
+	for _, tCase := range testCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			// Setup
+			form := tCase.vars.form
+
+			// Run test
+			err := form.Valid()
+
+			// Assert
+			if tCase.hasErr {
+				if assert.Errorf(t, err, "case: %v", tCase) {
+					assert.Containsf(t, err.Error(), tCase.err, "case: %v", tCase)
+				}
+			} else {
+				assert.NoErrorf(t, err, "case: %v", tCase)
+			}
+		})
+	}
+}
+
+func TestCurrencyConvertComponent_ValuePortfolio(t *testing.T) {
+	constants.CURRENCY_CODES_JSON_FILE_NAME = "../../currency_codes.json"
+	staleConn := utils.NewMockRedisConn()
+	for currencyCode, rate := range map[string]string{"USD": "1", "INR": "83.1"} {
+		dataBytes, _ := json.Marshal(&Currency{CurrencyExchangeRate: rate, LastUpdateTime: time.Date(2024, time.February, 25, 12, 0, 0, 0, time.UTC)})
+		_, _ = utils.SetStaleData(staleConn, currencyCode, base64.StdEncoding.EncodeToString(dataBytes))
+	}
+
+	type vars struct {
+		component components.BaseComponent
+
+		form *PortfolioValuationForm
+
+		headers map[string]string
+	}
+
+	testCases := []struct {
+		name string
+
+		vars vars
+
+		want   string
+		hasErr bool
+		err    string
+	}{
+		{
+			name: "should success to value the given holdings in the reporting currency",
+			vars: vars{
+				component: components.BaseComponent{
+					ReqCtx: context.Background(),
+				},
+				form: &PortfolioValuationForm{
+					ReportingCurrency: "INR",
+					Holdings: []*PortfolioHolding{
+						{Currency: "USD", Amount: 100},
+						{Currency: "INR", Amount: 1000},
+					},
+				},
+				headers: map[string]string{
+					"x-mock-api": "default",
+				},
+			},
+			want: `{ "reporting_currency": "INR", "holdings": [ { "currency": "USD", "amount": 100, "exchange_rate": 82.771291, "converted_amount": 8277.1291 }, { "currency": "INR", "amount": 1000, "exchange_rate": 1, "converted_amount": 1000 } ], "total": 9277.1291, "rate_time": "2024-02-26T12:04:00Z" }`,
+		},
+		{
+			name: "should fail to value the given holdings in the reporting currency",
+			vars: vars{
+				component: components.BaseComponent{
+					ReqCtx: context.Background(),
+				},
+				form: &PortfolioValuationForm{
+					ReportingCurrency: "INR",
+					Holdings:          []*PortfolioHolding{{Currency: "USD", Amount: 100}},
+				},
+				headers: map[string]string{
+					"x-mock-api": "error_response",
+				},
+			},
+			hasErr: true,
+			err:    "error",
+		},
+		{
+			name: "should success to value the given holdings using the stale rates snapshot when the vendor API call budget is exhausted",
+			vars: vars{
+				component: components.BaseComponent{
+					ReqCtx:    context.Background(),
+					RedisConn: staleConn,
+				},
+				form: &PortfolioValuationForm{
+					ReportingCurrency: "INR",
+					Holdings:          []*PortfolioHolding{{Currency: "USD", Amount: 100}},
+				},
+				headers: map[string]string{
+					"x-mock-api": "budget_exhausted",
+				},
+			},
+			want: `{ "reporting_currency": "INR", "holdings": [ { "currency": "USD", "amount": 100, "exchange_rate": 83.1, "converted_amount": 8310 } ], "total": 8310, "rate_time": "2024-02-25T12:00:00Z" }`,
+		},
+		{
+			name: "should fail to value the given holdings when the vendor API call budget is exhausted and the stale rates aren't cached",
+			vars: vars{
+				component: components.BaseComponent{
+					ReqCtx:    context.Background(),
+					RedisConn: staleConn,
+				},
+				form: &PortfolioValuationForm{
+					ReportingCurrency: "INR",
+					Holdings:          []*PortfolioHolding{{Currency: "JPY", Amount: 100}},
+				},
+				headers: map[string]string{
+					"x-mock-api": "budget_exhausted",
+				},
+			},
+			hasErr: true,
+			err:    utils.ErrVendorBudgetExhausted.Error(),
+		},
+	}
+
+	for _, tCase := range testCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			// Setup
+			form := tCase.vars.form
+			ccc := &CurrencyConvertComponent{
+				BaseComponent: tCase.vars.component,
+			}
+			ctx := ccc.ReqCtx
+			ctx = context.WithValue(ctx, "x-mock-headers", tCase.vars.headers)
+			ccc.ReqCtx = ctx
+
+			// Run test
+			got, err := ccc.ValuePortfolio(form)
+
+			// Assert
+			if tCase.hasErr {
+				if assert.Errorf(t, err, "case: %v", tCase) {
+					assert.Containsf(t, err.Error(), tCase.err, "case: %v", tCase)
+				}
+			} else {
+				assert.NoErrorf(t, err, "case: %v", tCase)
+				tempWant := new(PortfolioValuationResponse)
+				_ = json.Unmarshal([]byte(tCase.want), tempWant)
+				assert.Equal(t, tempWant, got, "case: %v", tCase)
+			}
+		})
+	}
+}
@@ -0,0 +1,234 @@
+package cors
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/beego/beego/v2/core/config"
+	"github.com/beego/beego/v2/server/web"
+)
+
+// configSection is the section of the app config holding the CORS policy.
+const configSection = "CORS"
+
+// Headers of the CORS requests and responses.
+const (
+	HeaderOrigin           = "Origin"
+	HeaderRequestMethod    = "Access-Control-Request-Method"
+	HeaderRequestHeaders   = "Access-Control-Request-Headers"
+	HeaderAllowOrigin      = "Access-Control-Allow-Origin"
+	HeaderAllowMethods     = "Access-Control-Allow-Methods"
+	HeaderAllowHeaders     = "Access-Control-Allow-Headers"
+	HeaderAllowCredentials = "Access-Control-Allow-Credentials"
+	HeaderExposeHeaders    = "Access-Control-Expose-Headers"
+	HeaderMaxAge           = "Access-Control-Max-Age"
+)
+
+// wildcard allows any origin or header.
+const wildcard = "*"
+
+// varyHeaders are the request headers the CORS headers of the preflight responses depend on, so they aren't served
+// from a shared cache to another origin.
+var varyHeaders = []string{HeaderOrigin, HeaderRequestMethod, HeaderRequestHeaders}
+
+// defaultAllowMethods and defaultAllowHeaders are the methods and request headers allowed when the policy doesn't set
+// its own.
+var (
+	defaultAllowMethods = []string{http.MethodGet, http.MethodPost, http.MethodHead}
+	defaultAllowHeaders = []string{"Accept", "Content-Type", "Authorization", "If-None-Match", "If-Modified-Since", "X-API-Key", "X-Signature", "X-Signature-Timestamp", "X-Signature-Nonce"}
+)
+
+// Policy is the CORS policy of the browser clients, set by the `CORS` section of the app config.
+type Policy struct {
+	// AllowOrigins are the origins allowed to call the API, i.e. `https://app.example.com`. `*` matches any part of
+	// the host or the port, i.e. `https://*.example.com`, and a sole `*` allows any origin.
+	AllowOrigins []string `yaml:"AllowOrigins"`
+	// AllowMethods are the methods allowed to the origins, `GET`, `POST` and `HEAD` by default.
+	AllowMethods []string `yaml:"AllowMethods"`
+	// AllowHeaders are the request headers allowed to the origins, the ones used by the API by default. A sole `*`
+	// allows any header.
+	AllowHeaders []string `yaml:"AllowHeaders"`
+	// ExposeHeaders are the response headers readable by the origins, besides the CORS-safelisted ones.
+	ExposeHeaders []string `yaml:"ExposeHeaders"`
+	// AllowCredentials allows the origins to send cookies and authorization headers.
+	AllowCredentials bool `yaml:"AllowCredentials"`
+	// MaxAge is the time the browsers may cache the preflight responses, not sent when 0.
+	MaxAge time.Duration `yaml:"MaxAge"`
+
+	anyOrigin bool
+	origins   []*regexp.Regexp
+	anyHeader bool
+	methods   map[string]bool
+	headers   map[string]bool
+}
+
+var (
+	defaultPolicy     *Policy
+	defaultPolicyOnce sync.Once
+)
+
+// DefaultPolicy is used to get the policy set by the app config, loaded on first use.
+// It returns the policy, or nil when CORS isn't configured.
+func DefaultPolicy() *Policy {
+	defaultPolicyOnce.Do(func() {
+		policy, err := LoadPolicy(web.AppConfig)
+		if err != nil {
+			slog.Warn("CORS is disabled, error loading the app config", "section", configSection, "error", err)
+			return
+		}
+
+		defaultPolicy = policy
+	})
+
+	return defaultPolicy
+}
+
+// LoadPolicy is used to load the policy from the `CORS` section of the given app config.
+// It returns the policy, nil when the section isn't set or has no origins, and error.
+func LoadPolicy(cfg config.Configer) (*Policy, error) {
+	if _, err := cfg.DIY(configSection); err != nil {
+		return nil, nil
+	}
+
+	policy := &Policy{}
+	if err := cfg.Unmarshaler(configSection, policy); err != nil {
+		return nil, err
+	}
+	if len(policy.AllowOrigins) == 0 {
+		return nil, nil
+	}
+
+	return NewPolicy(*policy)
+}
+
+// NewPolicy is used to create a new policy, compiling its origin patterns.
+// It returns the policy instance and error if an origin is invalid.
+func NewPolicy(policy Policy) (*Policy, error) {
+	for _, origin := range policy.AllowOrigins {
+		origin = strings.TrimSpace(origin)
+		if origin == wildcard {
+			policy.anyOrigin = true
+			continue
+		}
+		if !strings.Contains(origin, "://") {
+			return nil, fmt.Errorf("invalid origin (%s), it should be `scheme://host[:port]`", origin)
+		}
+
+		// `*` matches within the host and port only, so `https://*.example.com` can't match `https://example.com.evil`.
+		pattern := strings.ReplaceAll(regexp.QuoteMeta(strings.ToLower(origin)), `\*`, `[a-z0-9.-]*`)
+		policy.origins = append(policy.origins, regexp.MustCompile("^"+pattern+"$"))
+	}
+
+	if len(policy.AllowMethods) == 0 {
+		policy.AllowMethods = append([]string{}, defaultAllowMethods...)
+	}
+	policy.methods = make(map[string]bool)
+	for i, method := range policy.AllowMethods {
+		policy.AllowMethods[i] = strings.ToUpper(strings.TrimSpace(method))
+		policy.methods[policy.AllowMethods[i]] = true
+	}
+
+	if len(policy.AllowHeaders) == 0 {
+		policy.AllowHeaders = defaultAllowHeaders
+	}
+	policy.headers = make(map[string]bool)
+	for _, header := range policy.AllowHeaders {
+		header = strings.TrimSpace(header)
+		if header == wildcard {
+			policy.anyHeader = true
+		}
+		policy.headers[http.CanonicalHeaderKey(header)] = true
+	}
+
+	return &policy, nil
+}
+
+// AllowsOrigin is used to check whether the given origin is allowed to call the API.
+func (p *Policy) AllowsOrigin(origin string) bool {
+	if origin == "" {
+		return false
+	}
+	if p.anyOrigin {
+		return true
+	}
+
+	origin = strings.ToLower(origin)
+	for _, pattern := range p.origins {
+		if pattern.MatchString(origin) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Headers is used to get the CORS headers of the response to the actual request of the given origin.
+// It returns the headers, having only `Vary` when the origin isn't allowed.
+func (p *Policy) Headers(origin string) map[string]string {
+	headers := map[string]string{"Vary": HeaderOrigin}
+	if !p.AllowsOrigin(origin) {
+		return headers
+	}
+
+	p.setOriginHeaders(headers, origin)
+	if len(p.ExposeHeaders) > 0 {
+		headers[HeaderExposeHeaders] = strings.Join(p.ExposeHeaders, ", ")
+	}
+
+	return headers
+}
+
+// PreflightHeaders is used to get the CORS headers of the response to the preflight request of the given origin,
+// asking for the given method and comma separated headers.
+// It returns the headers, and error telling why the preflight is rejected.
+func (p *Policy) PreflightHeaders(origin, method, requestHeaders string) (map[string]string, error) {
+	headers := map[string]string{"Vary": strings.Join(varyHeaders, ", ")}
+	if !p.AllowsOrigin(origin) {
+		return headers, fmt.Errorf("origin (%s) isn't allowed", origin)
+	}
+	if !p.methods[strings.ToUpper(method)] {
+		return headers, fmt.Errorf("method (%s) isn't allowed", method)
+	}
+
+	allowed := make([]string, 0)
+	for _, header := range strings.Split(requestHeaders, ",") {
+		header = strings.TrimSpace(header)
+		if header == "" {
+			continue
+		}
+		if !p.anyHeader && !p.headers[http.CanonicalHeaderKey(header)] {
+			return headers, fmt.Errorf("header (%s) isn't allowed", header)
+		}
+		allowed = append(allowed, header)
+	}
+
+	p.setOriginHeaders(headers, origin)
+	headers[HeaderAllowMethods] = strings.Join(p.AllowMethods, ", ")
+	if len(allowed) > 0 {
+		headers[HeaderAllowHeaders] = strings.Join(allowed, ", ")
+	}
+	if p.MaxAge > 0 {
+		headers[HeaderMaxAge] = strconv.Itoa(int(p.MaxAge.Seconds()))
+	}
+
+	return headers, nil
+}
+
+// setOriginHeaders sets the allowed origin, being the request origin unless any origin is allowed without
+// credentials, since browsers reject `*` along with credentials.
+func (p *Policy) setOriginHeaders(headers map[string]string, origin string) {
+	if p.anyOrigin && !p.AllowCredentials {
+		headers[HeaderAllowOrigin] = wildcard
+	} else {
+		headers[HeaderAllowOrigin] = origin
+	}
+	if p.AllowCredentials {
+		headers[HeaderAllowCredentials] = "true"
+	}
+}
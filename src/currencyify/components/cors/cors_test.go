@@ -0,0 +1,266 @@
+package cors
+
+import (
+	"testing"
+	"time"
+
+	"github.com/beego/beego/v2/core/config"
+	_ "github.com/beego/beego/v2/core/config/yaml"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadPolicy(t *testing.T) {
+	testCases := []struct {
+		name string
+
+		config string
+
+		want   *Policy
+		hasErr bool
+		err    string
+	}{
+		{
+			name: "should load the policy of the CORS section",
+			config: `
+AppName: "currencyify"
+CORS:
+  AllowOrigins: ["https://app.example.com", "https://*.example.com"]
+  AllowMethods: ["get", "POST"]
+  AllowHeaders: ["Content-Type", "X-API-Key"]
+  ExposeHeaders: ["ETag"]
+  AllowCredentials: true
+  MaxAge: 10m
+`,
+			want: &Policy{
+				AllowOrigins:     []string{"https://app.example.com", "https://*.example.com"},
+				AllowMethods:     []string{"GET", "POST"},
+				AllowHeaders:     []string{"Content-Type", "X-API-Key"},
+				ExposeHeaders:    []string{"ETag"},
+				AllowCredentials: true,
+				MaxAge:           10 * time.Minute,
+			},
+		},
+		{
+			name: "should use the default methods and headers",
+			config: `
+CORS:
+  AllowOrigins: ["*"]
+`,
+			want: &Policy{
+				AllowOrigins: []string{"*"},
+				AllowMethods: defaultAllowMethods,
+				AllowHeaders: defaultAllowHeaders,
+			},
+		},
+		{
+			name:   "should success without policy when the CORS section isn't set",
+			config: `AppName: "currencyify"`,
+		},
+		{
+			name: "should success without policy when the CORS section has no origins",
+			config: `
+CORS:
+  AllowCredentials: true
+`,
+		},
+		{
+			name: "should fail for the origin without scheme",
+			config: `
+CORS:
+  AllowOrigins: ["app.example.com"]
+`,
+			hasErr: true,
+			err:    "invalid origin (app.example.com), it should be `scheme://host[:port]`",
+		},
+	}
+
+	for _, tCase := range testCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			// Setup
+			cfg, err := config.NewConfigData("yaml", []byte(tCase.config))
+			assert.NoError(t, err)
+
+			// Run test
+			got, err := LoadPolicy(cfg)
+
+			// Assert
+			if tCase.hasErr {
+				if assert.Errorf(t, err, "case: %v", tCase) {
+					assert.EqualErrorf(t, err, tCase.err, "case: %v", tCase)
+				}
+			} else if assert.NoErrorf(t, err, "case: %v", tCase) && tCase.want == nil {
+				assert.Nil(t, got, "case: %v", tCase)
+			} else if assert.NotNil(t, got, "case: %v", tCase) {
+				assert.Equal(t, tCase.want.AllowOrigins, got.AllowOrigins, "case: %v", tCase)
+				assert.Equal(t, tCase.want.AllowMethods, got.AllowMethods, "case: %v", tCase)
+				assert.Equal(t, tCase.want.AllowHeaders, got.AllowHeaders, "case: %v", tCase)
+				assert.Equal(t, tCase.want.ExposeHeaders, got.ExposeHeaders, "case: %v", tCase)
+				assert.Equal(t, tCase.want.AllowCredentials, got.AllowCredentials, "case: %v", tCase)
+				assert.Equal(t, tCase.want.MaxAge, got.MaxAge, "case: %v", tCase)
+			}
+		})
+	}
+}
+
+func TestPolicy_AllowsOrigin(t *testing.T) {
+	policy, _ := NewPolicy(Policy{AllowOrigins: []string{"https://app.example.com", "https://*.example.com", "http://localhost:*"}})
+
+	testCases := []struct {
+		name string
+
+		origin string
+
+		want bool
+	}{
+		{name: "should allow the listed origin", origin: "https://app.example.com", want: true},
+		{name: "should allow the origin regardless of case", origin: "https://App.Example.com", want: true},
+		{name: "should allow the origin matching the wildcard host", origin: "https://admin.eu.example.com", want: true},
+		{name: "should allow the origin matching the wildcard port", origin: "http://localhost:3000", want: true},
+		{name: "should reject the origin of another scheme", origin: "http://app.example.com"},
+		{name: "should reject the origin suffixed to the wildcard host", origin: "https://app.example.com.evil.com"},
+		{name: "should reject the origin ending like the wildcard host", origin: "https://evilexample.com"},
+		{name: "should reject the empty origin", origin: ""},
+	}
+
+	for _, tCase := range testCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			// Run test
+			got := policy.AllowsOrigin(tCase.origin)
+
+			// Assert
+			assert.Equal(t, tCase.want, got, "case: %v", tCase)
+		})
+	}
+}
+
+func TestPolicy_Headers(t *testing.T) {
+	testCases := []struct {
+		name string
+
+		policy Policy
+		origin string
+
+		want map[string]string
+	}{
+		{
+			name:   "should allow the origin and expose the headers",
+			policy: Policy{AllowOrigins: []string{"https://*.example.com"}, ExposeHeaders: []string{"ETag", "Retry-After"}},
+			origin: "https://app.example.com",
+			want: map[string]string{
+				"Vary":              HeaderOrigin,
+				HeaderAllowOrigin:   "https://app.example.com",
+				HeaderExposeHeaders: "ETag, Retry-After",
+			},
+		},
+		{
+			name:   "should allow any origin",
+			policy: Policy{AllowOrigins: []string{"*"}},
+			origin: "https://app.example.com",
+			want:   map[string]string{"Vary": HeaderOrigin, HeaderAllowOrigin: "*"},
+		},
+		{
+			name:   "should allow the request origin when any origin is allowed with credentials",
+			policy: Policy{AllowOrigins: []string{"*"}, AllowCredentials: true},
+			origin: "https://app.example.com",
+			want:   map[string]string{"Vary": HeaderOrigin, HeaderAllowOrigin: "https://app.example.com", HeaderAllowCredentials: "true"},
+		},
+		{
+			name:   "should not allow the other origins",
+			policy: Policy{AllowOrigins: []string{"https://app.example.com"}},
+			origin: "https://evil.com",
+			want:   map[string]string{"Vary": HeaderOrigin},
+		},
+	}
+
+	for _, tCase := range testCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			// Setup
+			policy, err := NewPolicy(tCase.policy)
+			assert.NoError(t, err)
+
+			// Run test
+			got := policy.Headers(tCase.origin)
+
+			// Assert
+			assert.Equal(t, tCase.want, got, "case: %v", tCase)
+		})
+	}
+}
+
+func TestPolicy_PreflightHeaders(t *testing.T) {
+	policy, _ := NewPolicy(Policy{
+		AllowOrigins:     []string{"https://app.example.com"},
+		AllowMethods:     []string{"GET", "POST"},
+		AllowHeaders:     []string{"Content-Type", "X-API-Key"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	})
+	vary := "Origin, Access-Control-Request-Method, Access-Control-Request-Headers"
+
+	type vars struct {
+		origin  string
+		method  string
+		headers string
+	}
+
+	testCases := []struct {
+		name string
+
+		vars vars
+
+		want   map[string]string
+		hasErr bool
+		err    string
+	}{
+		{
+			name: "should allow the method and headers",
+			vars: vars{origin: "https://app.example.com", method: "POST", headers: "content-type, x-api-key"},
+			want: map[string]string{
+				"Vary":                 vary,
+				HeaderAllowOrigin:      "https://app.example.com",
+				HeaderAllowCredentials: "true",
+				HeaderAllowMethods:     "GET, POST",
+				HeaderAllowHeaders:     "content-type, x-api-key",
+				HeaderMaxAge:           "600",
+			},
+		},
+		{
+			name:   "should reject the origin not allowed",
+			vars:   vars{origin: "https://evil.com", method: "POST"},
+			want:   map[string]string{"Vary": vary},
+			hasErr: true,
+			err:    "origin (https://evil.com) isn't allowed",
+		},
+		{
+			name:   "should reject the method not allowed",
+			vars:   vars{origin: "https://app.example.com", method: "DELETE"},
+			want:   map[string]string{"Vary": vary},
+			hasErr: true,
+			err:    "method (DELETE) isn't allowed",
+		},
+		{
+			name:   "should reject the header not allowed",
+			vars:   vars{origin: "https://app.example.com", method: "GET", headers: "X-API-Key, X-Debug"},
+			want:   map[string]string{"Vary": vary},
+			hasErr: true,
+			err:    "header (X-Debug) isn't allowed",
+		},
+	}
+
+	for _, tCase := range testCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			// Run test
+			got, err := policy.PreflightHeaders(tCase.vars.origin, tCase.vars.method, tCase.vars.headers)
+
+			// Assert
+			if tCase.hasErr {
+				if assert.Errorf(t, err, "case: %v", tCase) {
+					assert.EqualErrorf(t, err, tCase.err, "case: %v", tCase)
+				}
+			} else {
+				assert.NoErrorf(t, err, "case: %v", tCase)
+			}
+			assert.Equal(t, tCase.want, got, "case: %v", tCase)
+		})
+	}
+}
@@ -6,19 +6,27 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"math"
 	"net/http"
-	"os"
 	"strconv"
 	"strings"
 	"time"
 
 	"currencyify/components"
+	"currencyify/components/registry"
 	"currencyify/constants"
+	"currencyify/metrics"
+	"currencyify/rpc/pb"
+	"currencyify/tracing"
 	"currencyify/utils"
 
 	"github.com/gomodule/redigo/redis"
 	"github.com/microcosm-cc/bluemonday"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 type CurrencyExchangeRateComponent struct {
@@ -27,8 +35,11 @@ type CurrencyExchangeRateComponent struct {
 
 type CurrencyExchangeRate interface {
 	GetCurrencyExchangeRate(*CurrencyExchangeRateForm) (*CurrencyExchangeRateResponse, error)
+	ListCurrencies() ([]string, error)
+	SubscribeCurrencyExchangeRate(*RateStreamForm) (*RateSubscription, error)
 
 	GetCurrencyExchangeRateForm() *CurrencyExchangeRateForm
+	GetRateStreamForm() *RateStreamForm
 	GetCurrencyExchangeRateAppError() *utils.AppError
 	SetCurrencyExchangeRateAppError(int, error)
 }
@@ -36,17 +47,26 @@ type CurrencyExchangeRate interface {
 type CurrencyExchangeRateForm struct {
 	BaseCurrency     string   `json:"base_currency"`
 	TargetCurrencies []string `json:"target_currencies"`
+	// Date is the day, in YYYY-MM-DD format, of the historical rates to get. Latest rates are got when empty.
+	Date string `json:"date,omitempty"`
 }
 
 type CurrencyExchangeRateResponse struct {
 	BaseCurrency  string              `json:"base_currency"`
 	ExchangeRates map[string]Currency `json:"exchange_rates"`
+
+	// CacheTTL is the number of seconds till the earliest of the returned rates expires from cache.
+	CacheTTL int `json:"-"`
 }
 
-var currencyCodesMap map[string]int
+// currencyRegistry gets the registry of the supported currencies, loaded once and only read by the forms.
+var currencyRegistry = registry.DefaultRegistry
 
 type Currency struct {
 	CurrencyExchangeRate string    `json:"currency_exchange_rate"`
+	Bid                  string    `json:"bid"`
+	Ask                  string    `json:"ask"`
+	Mid                  string    `json:"mid"`
 	LastUpdateTime       time.Time `json:"last_update_time"`
 }
 
@@ -55,7 +75,7 @@ type Currency struct {
 func (cec *CurrencyExchangeRateComponent) GetCurrencyExchangeRate(form *CurrencyExchangeRateForm) (*CurrencyExchangeRateResponse, error) {
 	resp := new(CurrencyExchangeRateResponse)
 	var err error
-	if err = form.Valid(); err != nil {
+	if err = cec.ValidForm(form); err != nil {
 		cec.AppError = &utils.AppError{
 			Status: http.StatusBadRequest,
 			Error:  err,
@@ -64,40 +84,150 @@ func (cec *CurrencyExchangeRateComponent) GetCurrencyExchangeRate(form *Currency
 		return nil, err
 	}
 
-	if currencyExchangeRates, err := cec.getCurrencyExchangeRate(form); err != nil {
+	if currencyExchangeRates, cacheTTL, err := cec.getCurrencyExchangeRate(form); err != nil {
 		cec.SetCurrencyExchangeRateAppError(http.StatusInternalServerError, err)
 		return nil, err
 	} else {
 		resp.ExchangeRates = currencyExchangeRates
 		resp.BaseCurrency = form.BaseCurrency
+		resp.CacheTTL = cacheTTL
 	}
 
 	return resp, nil
 }
 
-func (cec *CurrencyExchangeRateComponent) getCurrencyExchangeRate(form *CurrencyExchangeRateForm) (map[string]Currency, error) {
-	data := new(Currency)
+// LastModified is used to get the update time of the most recent of the exchange rates.
+// It returns the last update time.
+func (r *CurrencyExchangeRateResponse) LastModified() time.Time {
+	var lastModified time.Time
+	for _, currency := range r.ExchangeRates {
+		if currency.LastUpdateTime.After(lastModified) {
+			lastModified = currency.LastUpdateTime
+		}
+	}
+
+	return lastModified
+}
+
+// MarshalCSV is used to get the exchange rates as a CSV table, one row per currency sorted by currency code.
+// It returns the CSV records, along with the header.
+func (r *CurrencyExchangeRateResponse) MarshalCSV() [][]string {
+	records := [][]string{{"base_currency", "currency", "currency_exchange_rate", "bid", "ask", "mid", "last_update_time"}}
+	for _, currencyCode := range utils.SortedKeys(r.ExchangeRates) {
+		currency := r.ExchangeRates[currencyCode]
+		records = append(records, []string{
+			r.BaseCurrency,
+			currencyCode,
+			currency.CurrencyExchangeRate,
+			currency.Bid,
+			currency.Ask,
+			currency.Mid,
+			currency.LastUpdateTime.Format(time.RFC3339),
+		})
+	}
+
+	return records
+}
+
+// ToProto is used to convert the exchange rates to their protobuf message.
+// It returns the `GetExchangeRatesResponse` message.
+func (r *CurrencyExchangeRateResponse) ToProto() proto.Message {
+	msg := &pb.GetExchangeRatesResponse{
+		BaseCurrency:  r.BaseCurrency,
+		ExchangeRates: make(map[string]*pb.ExchangeRate, len(r.ExchangeRates)),
+	}
+	for currencyCode, rate := range r.ExchangeRates {
+		msg.ExchangeRates[currencyCode] = &pb.ExchangeRate{
+			CurrencyExchangeRate: rate.CurrencyExchangeRate,
+			Bid:                  rate.Bid,
+			Ask:                  rate.Ask,
+			Mid:                  rate.Mid,
+			LastUpdateTime:       timestamppb.New(rate.LastUpdateTime),
+		}
+	}
+
+	return msg
+}
+
+func (cec *CurrencyExchangeRateComponent) getCurrencyExchangeRate(form *CurrencyExchangeRateForm) (map[string]Currency, int, error) {
 	result := make(map[string]Currency)
 	pendingCurrencyCodes := make([]string, 0)
+	cacheTTL, _ := strconv.Atoi(constants.REDIS_DEFAULT_EXPIRY)
 	for _, currencyCode := range form.TargetCurrencies {
-		if isDataInCache(cec.RedisConn, fmt.Sprintf("%s-%s", form.BaseCurrency, currencyCode), data) {
+		cacheKey := rateCacheKey(form.BaseCurrency, currencyCode, form.Date)
+		data := new(Currency)
+		if isDataInCache(cec.ReqCtx, cec.RedisConn, cacheKey, data) {
+			if err := applySpread(data); err != nil {
+				return result, 0, err
+			}
 			result[currencyCode] = *data
+			if ttl, err := utils.GetTTL(cec.RedisConn, cacheKey); err != nil || ttl < cacheTTL {
+				cacheTTL = ttl
+			}
 		} else {
 			pendingCurrencyCodes = append(pendingCurrencyCodes, currencyCode)
 		}
 	}
 
 	if len(pendingCurrencyCodes) == 0 {
-		return result, nil
+		return result, cacheTTL, nil
+	}
+
+	var resp utils.Data
+	var err error
+	if form.Date == "" {
+		resp, err = fetchCurrencyExchangeRate(cec.ReqCtx, form.BaseCurrency, pendingCurrencyCodes)
+	} else {
+		resp, err = fetchHistoricalCurrencyExchangeRate(cec.ReqCtx, form.BaseCurrency, pendingCurrencyCodes, form.Date)
+	}
+	if errors.Is(err, utils.ErrVendorBudgetExhausted) && getStaleCurrencyExchangeRate(cec.ReqCtx, cec.RedisConn, form.BaseCurrency, form.Date, pendingCurrencyCodes, result) {
+		return result, 0, nil
+	} else if err != nil {
+		return result, 0, err
+	} else if err = processCurrencyExchangeRate(cec.ReqCtx, cec.RedisConn, form.BaseCurrency, form.Date, resp, result); err != nil {
+		return result, 0, err
 	}
 
-	if resp, err := fetchCurrencyExchangeRate(cec.ReqCtx, form.BaseCurrency, pendingCurrencyCodes); err != nil {
-		return result, err
-	} else if err = processCurrencyExchangeRate(cec.RedisConn, form.BaseCurrency, resp, result); err != nil {
-		return result, err
+	for _, currencyCode := range pendingCurrencyCodes {
+		if _, ok := result[currencyCode]; !ok {
+			return result, 0, &utils.RateNotFoundError{Currency: currencyCode}
+		}
 	}
 
-	return result, nil
+	return result, cacheTTL, nil
+}
+
+// getStaleCurrencyExchangeRate looks up the stale copies of the rates of the given currency codes, kept in cache after
+// the rates expire, adding them to the result when all of them are found.
+// It returns whether the stale rates are added.
+func getStaleCurrencyExchangeRate(ctx context.Context, redisConn redis.Conn, baseCurrencyCode, date string, currencyCodes []string, result map[string]Currency) bool {
+	staleResult := make(map[string]Currency)
+	for _, currencyCode := range currencyCodes {
+		data := new(Currency)
+		if !isDataInCache(ctx, redisConn, utils.StaleKey(rateCacheKey(baseCurrencyCode, currencyCode, date)), data) {
+			return false
+		} else if err := applySpread(data); err != nil {
+			return false
+		}
+		staleResult[currencyCode] = *data
+	}
+
+	for currencyCode, data := range staleResult {
+		result[currencyCode] = data
+	}
+	slog.WarnContext(ctx, "vendor API call budget exhausted, serving stale rates", "base", baseCurrencyCode, "currencies", strings.Join(currencyCodes, ","))
+
+	return true
+}
+
+// rateCacheKey builds the cache key of the exchange rate of the given currency code in accordance with base currency,
+// suffixed with the date for historical rates.
+func rateCacheKey(baseCurrencyCode, currencyCode, date string) string {
+	if date == "" {
+		return fmt.Sprintf("%s-%s", baseCurrencyCode, currencyCode)
+	}
+
+	return fmt.Sprintf("%s-%s@%s", baseCurrencyCode, currencyCode, date)
 }
 
 func fetchCurrencyExchangeRate(reqCtx context.Context, baseCurrencyCode string, pendingCurrencyCodes []string) (utils.Data, error) {
@@ -119,73 +249,173 @@ func fetchCurrencyExchangeRate(reqCtx context.Context, baseCurrencyCode string,
 	}
 	caMap, _ := resp.(map[string]interface{})
 
-	log.Printf("fetched latest currency exchange rates for: %s", currencyCodes)
+	slog.InfoContext(reqCtx, "fetched latest currency exchange rates", "base", baseCurrencyCode, "currencies", currencyCodes)
 
 	return caMap, nil
 }
 
-func processCurrencyExchangeRate(redisConn redis.Conn, baseCurrencyCode string, resp utils.Data, result map[string]Currency) error {
+func fetchHistoricalCurrencyExchangeRate(reqCtx context.Context, baseCurrencyCode string, pendingCurrencyCodes []string, date string) (utils.Data, error) {
+	url := fmt.Sprintf("%v", constants.FX_RATES_HISTORICAL_API_URL)
+	reqHeaders := map[string]string{"Content-Type": "application/json"}
+	currencyCodes := strings.Join(pendingCurrencyCodes, ",")
+	params := map[string]string{
+		"base":       baseCurrencyCode,
+		"currencies": currencyCodes,
+		"date":       date,
+		"amount":     "1",
+		"format":     "json",
+		"places":     "6",
+	}
+	var resp interface{}
+	var err error
+	if resp, err = utils.GetAPIResponse(reqCtx, "GetHistoricalCurrencyRate", url, http.MethodGet, nil, params, reqHeaders); err != nil {
+		return nil, err
+	}
+	caMap, _ := resp.(map[string]interface{})
+
+	slog.InfoContext(reqCtx, "fetched historical currency exchange rates", "base", baseCurrencyCode, "currencies", currencyCodes, "date", date)
+
+	return caMap, nil
+}
+
+func processCurrencyExchangeRate(ctx context.Context, redisConn redis.Conn, baseCurrencyCode, date string, resp utils.Data, result map[string]Currency) error {
 	if rates, ok := resp["rates"].(map[string]interface{}); ok {
 		parsedTime, _ := time.Parse(time.RFC3339, resp["date"].(string))
+		bids, _ := resp["bid"].(map[string]interface{})
+		asks, _ := resp["ask"].(map[string]interface{})
 		for currencyCode, rate := range rates {
 			data := new(Currency)
-			switch rate.(type) {
-			case float64:
-				data.CurrencyExchangeRate = strconv.FormatFloat(rate.(float64), 'f', -1, 64)
-			default:
-				data.CurrencyExchangeRate = rate.(string)
+			data.CurrencyExchangeRate = formatRate(rate)
+			if bid, ok := bids[currencyCode]; ok {
+				data.Bid = formatRate(bid)
+			}
+			if ask, ok := asks[currencyCode]; ok {
+				data.Ask = formatRate(ask)
+			}
+			if err := applySpread(data); err != nil {
+				return err
 			}
 
 			data.LastUpdateTime = parsedTime
-			cacheData(redisConn, fmt.Sprintf("%s-%s", baseCurrencyCode, currencyCode), data)
+			cacheData(ctx, redisConn, rateCacheKey(baseCurrencyCode, currencyCode, date), data)
 			result[currencyCode] = *data
 		}
 	} else {
 		return errors.New("error while processing exchange rates of vendor API data")
 	}
 
-	log.Printf("processed exchange rates data")
+	slog.DebugContext(ctx, "processed exchange rates data")
+
+	return nil
+}
+
+// formatRate converts the rate received from vendor API to its string representation.
+func formatRate(rate interface{}) string {
+	switch rate.(type) {
+	case float64:
+		return strconv.FormatFloat(rate.(float64), 'f', -1, 64)
+	default:
+		str, _ := rate.(string)
+		return str
+	}
+}
+
+// applySpread fills the mid, bid and ask rates of the given currency. Bid and ask supplied by the vendor are kept as is,
+// missing ones are derived from the mid rate using the configured spread percentage.
+func applySpread(data *Currency) error {
+	mid, err := strconv.ParseFloat(data.CurrencyExchangeRate, 64)
+	if err != nil {
+		return err
+	}
+	data.Mid = data.CurrencyExchangeRate
+
+	spread := 0.0
+	if constants.FX_RATES_SPREAD_PERCENTAGE != "" {
+		if spread, err = strconv.ParseFloat(constants.FX_RATES_SPREAD_PERCENTAGE, 64); err != nil {
+			return err
+		}
+	}
+	halfSpread := mid * spread / 100 / 2
+
+	if data.Bid == "" {
+		data.Bid = strconv.FormatFloat(roundRate(mid-halfSpread), 'f', -1, 64)
+	}
+	if data.Ask == "" {
+		data.Ask = strconv.FormatFloat(roundRate(mid+halfSpread), 'f', -1, 64)
+	}
 
 	return nil
 }
 
-func isDataInCache(redisConn redis.Conn, currencyCode string, cacheData *Currency) bool {
+// roundRate rounds the rate to the number of decimal places requested from vendor API.
+func roundRate(rate float64) float64 {
+	return math.Round(rate*1e6) / 1e6
+}
+
+// isDataInCache looks up the rate cached under the given key within its own span of the request trace.
+// It returns whether the rate is found, unmarshaled into the given data.
+func isDataInCache(ctx context.Context, redisConn redis.Conn, currencyCode string, cacheData *Currency) (found bool) {
+	_, span := tracing.Start(ctx, "cache lookup", trace.WithAttributes(attribute.String("cache.key", currencyCode)))
+	defer func() {
+		span.SetAttributes(attribute.Bool("cache.hit", found))
+		span.End()
+	}()
+
 	if redisConn == nil {
-		log.Printf("redis conn not found to fetch data from cache")
+		slog.WarnContext(ctx, "redis conn not found to fetch data from cache")
 		return false
 	}
 	dataStr, err := utils.GetData(redisConn, currencyCode)
 	if err != nil {
-		log.Printf("data not found in cache for: %v", currencyCode)
+		slog.DebugContext(ctx, "data not found in cache", "key", currencyCode)
 	} else if dataBytes, err := base64.StdEncoding.DecodeString(dataStr); err != nil {
-		log.Printf("error while decoding base64 cache data")
+		slog.ErrorContext(ctx, "error while decoding base64 cache data", "key", currencyCode, "error", err)
 	} else if err := json.Unmarshal(dataBytes, cacheData); err != nil {
-		log.Printf("error unmarshaling cache data")
+		slog.ErrorContext(ctx, "error unmarshaling cache data", "key", currencyCode, "error", err)
 	} else {
-		log.Printf("data found in cache for: %v", currencyCode)
+		slog.DebugContext(ctx, "data found in cache", "key", currencyCode)
+		metrics.ObserveCacheLookup(currencyCode, true)
+		metrics.ObserveCachedRate(currencyCode, cacheData.LastUpdateTime)
 		return true
 	}
 
+	metrics.ObserveCacheLookup(currencyCode, false)
 	return false
 }
 
-func cacheData(redisConn redis.Conn, currencyCode string, cacheData *Currency) {
+func cacheData(ctx context.Context, redisConn redis.Conn, currencyCode string, cacheData *Currency) {
 	if redisConn == nil {
-		log.Printf("redis conn not found to store in cache")
+		slog.WarnContext(ctx, "redis conn not found to store in cache")
 		return
 	}
 	if respBytes, err := json.Marshal(cacheData); err != nil {
-		log.Printf("error marshaling data to store in cache")
+		slog.ErrorContext(ctx, "error marshaling data to store in cache", "key", currencyCode, "error", err)
 	} else if respStr := base64.StdEncoding.EncodeToString(respBytes); respStr != "" {
 		ttl, _ := strconv.Atoi(constants.REDIS_DEFAULT_EXPIRY)
-		if status, err := utils.SetData(redisConn, currencyCode, respStr, ttl); err != nil || !status {
-			log.Printf("error setting data in cache")
+		if status, err := utils.SetRateData(redisConn, currencyCode, respStr, ttl); err != nil || !status {
+			slog.ErrorContext(ctx, "error setting data in cache", "key", currencyCode, "error", err)
 		} else {
-			log.Printf("data succesfully stored in cache")
+			slog.DebugContext(ctx, "data succesfully stored in cache", "key", currencyCode)
+			metrics.ObserveCachedRate(currencyCode, cacheData.LastUpdateTime)
+		}
+		if status, err := utils.SetStaleData(redisConn, currencyCode, respStr); err != nil || !status {
+			slog.ErrorContext(ctx, "error setting stale data in cache", "key", currencyCode, "error", err)
 		}
 	}
 }
 
+// ListCurrencies is used to list the supported currencies.
+// It returns the sorted international-standard 3-letter ISO currency codes and error.
+func (cec *CurrencyExchangeRateComponent) ListCurrencies() ([]string, error) {
+	currencies, err := currencyRegistry()
+	if err != nil {
+		cec.SetCurrencyExchangeRateAppError(http.StatusInternalServerError, err)
+		return nil, err
+	}
+
+	return currencies.Codes(), nil
+}
+
 // GetCurrencyExchangeRateForm is used to create a new currency exchange rate form instance.
 // It returns currency exchange rate form instance.
 func (cec *CurrencyExchangeRateComponent) GetCurrencyExchangeRateForm() *CurrencyExchangeRateForm {
@@ -208,56 +438,45 @@ func (cec *CurrencyExchangeRateComponent) SetCurrencyExchangeRateAppError(status
 
 // Valid validates and sanitizes the currency converter form.
 func (f *CurrencyExchangeRateForm) Valid() error {
-	currencyCodesStr, err := os.ReadFile(constants.CURRENCY_CODES_JSON_FILE_NAME)
+	currencies, err := currencyRegistry()
 	if err != nil {
 		return err
 	}
 
-	if err = json.Unmarshal(currencyCodesStr, &currencyCodesMap); err != nil {
-		return err
-	}
-
-	errMsg := ""
-	addErrMsg := func(msg string) {
-		if errMsg != "" {
-			errMsg += "\n"
-		}
-		errMsg += msg
-	}
+	errs := utils.ValidationErrors{}
+	p := bluemonday.UGCPolicy()
 
-	checkCurrency := func(currency string) bool {
-		_, ok := currencyCodesMap[strings.ToLower(currency)]
-		return ok
-	}
+	checkCurrency := currencies.Has
 
 	if f.BaseCurrency == "" {
-		addErrMsg("`base_currency` parameter is required")
+		errs.Add(utils.ErrCodeRequired, "base_currency", nil, "`base_currency` parameter is required")
 	} else if !checkCurrency(f.BaseCurrency) {
-		addErrMsg("`base_currency` not found in our database. Please check the `base_currency` input param, it should be a valid international-standard 3-letter ISO currency code")
+		errs.Add(utils.ErrCodeUnknownCurrency, "base_currency", p.Sanitize(f.BaseCurrency), "`base_currency` not found in our database. Please check the `base_currency` input param, it should be a valid international-standard 3-letter ISO currency code")
 	} else {
 		f.BaseCurrency = strings.ToUpper(f.BaseCurrency)
 	}
 
 	if len(f.TargetCurrencies) == 0 {
-		addErrMsg("`target_currencies` parameter is required")
+		errs.Add(utils.ErrCodeRequired, "target_currencies", nil, "`target_currencies` parameter is required")
 	} else {
 		for index, targetCurrency := range f.TargetCurrencies {
 			if !checkCurrency(targetCurrency) {
-				addErrMsg(fmt.Sprintf("`target_currency` (%s) not found in our database. Please check the `target_currencies` input param, it should be a valid international-standard 3-letter ISO currency code", targetCurrency))
+				errs.Add(utils.ErrCodeUnknownCurrency, fmt.Sprintf("target_currencies[%d]", index), p.Sanitize(targetCurrency), fmt.Sprintf("`target_currency` (%s) not found in our database. Please check the `target_currencies` input param, it should be a valid international-standard 3-letter ISO currency code", targetCurrency))
 			} else {
 				f.TargetCurrencies[index] = strings.ToUpper(targetCurrency)
 			}
 		}
 	}
 
-	p := bluemonday.UGCPolicy()
-	f.BaseCurrency = p.Sanitize(f.BaseCurrency)
-
-	if errMsg != "" {
-		return errors.New(errMsg)
+	if f.Date != "" {
+		if _, err = time.Parse(time.DateOnly, f.Date); err != nil {
+			errs.Add(utils.ErrCodeInvalidFormat, "date", p.Sanitize(f.Date), "`date` parameter should be in YYYY-MM-DD format")
+		}
 	}
 
-	return nil
+	f.BaseCurrency = p.Sanitize(f.BaseCurrency)
+
+	return errs.Err()
 }
 
 func init() {
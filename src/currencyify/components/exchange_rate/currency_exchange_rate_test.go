@@ -2,11 +2,13 @@ package exchange_rate
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"testing"
+	"time"
 
 	"currencyify/components"
 	"currencyify/constants"
@@ -71,6 +73,18 @@ func TestCurrencyExchangeRateForm_Valid(t *testing.T) {
 			hasErr: true,
 			err:    "`target_currency` (India) not found in our database. Please check the `target_currencies` input param, it should be a valid international-standard 3-letter ISO currency code",
 		},
+		{
+			name: "should fail when date format is not YYYY-MM-DD",
+			vars: vars{
+				form: &CurrencyExchangeRateForm{
+					BaseCurrency:     "USD",
+					TargetCurrencies: []string{"INR"},
+					Date:             "15-01-2024",
+				},
+			},
+			hasErr: true,
+			err:    "`date` parameter should be in YYYY-MM-DD format",
+		},
 		{
 			name: "should success to validate the currency exchange rate input form",
 			vars: vars{
@@ -235,6 +249,19 @@ func TestCurrencyExchangeRateComponent_SetCurrencyExchangeRateAppError(t *testin
 
 func TestCurrencyExchangeRateComponent_GetCurrencyExchangeRate(t *testing.T) {
 	constants.CURRENCY_CODES_JSON_FILE_NAME = "../../currency_codes.json"
+	staleConn := utils.NewMockRedisConn()
+	for cacheKey, rate := range map[string]string{"USD-INR": "83.1", "USD-JPY": "151.2"} {
+		dataBytes, _ := json.Marshal(&Currency{CurrencyExchangeRate: rate, LastUpdateTime: time.Date(2024, time.February, 25, 12, 0, 0, 0, time.UTC)})
+		_, _ = utils.SetStaleData(staleConn, cacheKey, base64.StdEncoding.EncodeToString(dataBytes))
+	}
+	cachedConn := utils.NewMockRedisConn()
+	for cacheKey, data := range map[string]string{
+		"USD-INR": `{"currency_exchange_rate":"82.771291","bid":"82.7","ask":"82.8","last_update_time":"2024-02-26T12:04:00Z"}`,
+		// cached before the bid and ask rates were added
+		"USD-JPY": `{"currency_exchange_rate":"150.608807","last_update_time":"2024-02-26T12:04:00Z"}`,
+	} {
+		_, _ = utils.SetData(cachedConn, cacheKey, base64.StdEncoding.EncodeToString([]byte(data)), 60)
+	}
 
 	type vars struct {
 		component components.BaseComponent
@@ -242,6 +269,8 @@ func TestCurrencyExchangeRateComponent_GetCurrencyExchangeRate(t *testing.T) {
 		form *CurrencyExchangeRateForm
 
 		headers map[string]string
+
+		spread string
 	}
 
 	testCases := []struct {
@@ -267,7 +296,58 @@ func TestCurrencyExchangeRateComponent_GetCurrencyExchangeRate(t *testing.T) {
 					"x-mock-api": "default",
 				},
 			},
-			want: ` { "base_currency": "USD", "exchange_rates": { "INR": { "currency_exchange_rate": "82.771291", "last_update_time": "2024-02-26T12:04:00Z" }, "JPY": { "currency_exchange_rate": "150.608807", "last_update_time": "2024-02-26T12:04:00Z" } } }`,
+			want: ` { "base_currency": "USD", "exchange_rates": { "INR": { "currency_exchange_rate": "82.771291", "bid": "82.771291", "ask": "82.771291", "mid": "82.771291", "last_update_time": "2024-02-26T12:04:00Z" }, "JPY": { "currency_exchange_rate": "150.608807", "bid": "150.608807", "ask": "150.608807", "mid": "150.608807", "last_update_time": "2024-02-26T12:04:00Z" } } }`,
+		},
+		{
+			name: "should success to derive bid and ask rates from the configured spread",
+			vars: vars{
+				component: components.BaseComponent{
+					ReqCtx: context.Background(),
+				},
+				form: &CurrencyExchangeRateForm{
+					BaseCurrency:     "USD",
+					TargetCurrencies: []string{"INR", "JPY"},
+				},
+				headers: map[string]string{
+					"x-mock-api": "default",
+				},
+				spread: "1",
+			},
+			want: ` { "base_currency": "USD", "exchange_rates": { "INR": { "currency_exchange_rate": "82.771291", "bid": "82.357435", "ask": "83.185147", "mid": "82.771291", "last_update_time": "2024-02-26T12:04:00Z" }, "JPY": { "currency_exchange_rate": "150.608807", "bid": "149.855763", "ask": "151.361851", "mid": "150.608807", "last_update_time": "2024-02-26T12:04:00Z" } } }`,
+		},
+		{
+			name: "should success to use the bid and ask rates supplied by the vendor",
+			vars: vars{
+				component: components.BaseComponent{
+					ReqCtx: context.Background(),
+				},
+				form: &CurrencyExchangeRateForm{
+					BaseCurrency:     "USD",
+					TargetCurrencies: []string{"INR", "JPY"},
+				},
+				headers: map[string]string{
+					"x-mock-api": "bid_ask",
+				},
+				spread: "1",
+			},
+			want: ` { "base_currency": "USD", "exchange_rates": { "INR": { "currency_exchange_rate": "82.771291", "bid": "82.7", "ask": "82.8", "mid": "82.771291", "last_update_time": "2024-02-26T12:04:00Z" }, "JPY": { "currency_exchange_rate": "150.608807", "bid": "150.5", "ask": "150.7", "mid": "150.608807", "last_update_time": "2024-02-26T12:04:00Z" } } }`,
+		},
+		{
+			name: "should success to fetch the historical currency exchange rates of the given date",
+			vars: vars{
+				component: components.BaseComponent{
+					ReqCtx: context.Background(),
+				},
+				form: &CurrencyExchangeRateForm{
+					BaseCurrency:     "USD",
+					TargetCurrencies: []string{"INR", "JPY"},
+					Date:             "2024-01-15",
+				},
+				headers: map[string]string{
+					"x-mock-api": "default",
+				},
+			},
+			want: ` { "base_currency": "USD", "exchange_rates": { "INR": { "currency_exchange_rate": "83", "bid": "83", "ask": "83", "mid": "83", "last_update_time": "2024-01-15T23:59:00Z" }, "JPY": { "currency_exchange_rate": "147", "bid": "147", "ask": "147", "mid": "147", "last_update_time": "2024-01-15T23:59:00Z" }, "EUR": { "currency_exchange_rate": "0.9", "bid": "0.9", "ask": "0.9", "mid": "0.9", "last_update_time": "2024-01-15T23:59:00Z" }, "USD": { "currency_exchange_rate": "1", "bid": "1", "ask": "1", "mid": "1", "last_update_time": "2024-01-15T23:59:00Z" } } }`,
 		},
 		{
 			name: "should fail to fetch the currency exchange rates of the given currency codes in accordance with base currency",
@@ -286,6 +366,72 @@ func TestCurrencyExchangeRateComponent_GetCurrencyExchangeRate(t *testing.T) {
 			hasErr: true,
 			err:    "error",
 		},
+		{
+			name: "should fail when vendor API doesn't have the exchange rate of the given currency code",
+			vars: vars{
+				component: components.BaseComponent{
+					ReqCtx: context.Background(),
+				},
+				form: &CurrencyExchangeRateForm{
+					BaseCurrency:     "USD",
+					TargetCurrencies: []string{"INR", "EUR"},
+				},
+				headers: map[string]string{
+					"x-mock-api": "default",
+				},
+			},
+			hasErr: true,
+			err:    "exchange rate not found for: EUR",
+		},
+		{
+			name: "should success to serve the cached rates without carrying the bid and ask rates of one to another",
+			vars: vars{
+				component: components.BaseComponent{
+					ReqCtx:    context.Background(),
+					RedisConn: cachedConn,
+				},
+				form: &CurrencyExchangeRateForm{
+					BaseCurrency:     "USD",
+					TargetCurrencies: []string{"INR", "JPY"},
+				},
+			},
+			want: ` { "base_currency": "USD", "exchange_rates": { "INR": { "currency_exchange_rate": "82.771291", "bid": "82.7", "ask": "82.8", "mid": "82.771291", "last_update_time": "2024-02-26T12:04:00Z" }, "JPY": { "currency_exchange_rate": "150.608807", "bid": "150.608807", "ask": "150.608807", "mid": "150.608807", "last_update_time": "2024-02-26T12:04:00Z" } } }`,
+		},
+		{
+			name: "should serve the stale rates when vendor API call budget is exhausted",
+			vars: vars{
+				component: components.BaseComponent{
+					ReqCtx:    context.Background(),
+					RedisConn: staleConn,
+				},
+				form: &CurrencyExchangeRateForm{
+					BaseCurrency:     "USD",
+					TargetCurrencies: []string{"INR", "JPY"},
+				},
+				headers: map[string]string{
+					"x-mock-api": "budget_exhausted",
+				},
+			},
+			want: ` { "base_currency": "USD", "exchange_rates": { "INR": { "currency_exchange_rate": "83.1", "bid": "83.1", "ask": "83.1", "mid": "83.1", "last_update_time": "2024-02-25T12:00:00Z" }, "JPY": { "currency_exchange_rate": "151.2", "bid": "151.2", "ask": "151.2", "mid": "151.2", "last_update_time": "2024-02-25T12:00:00Z" } } }`,
+		},
+		{
+			name: "should fail when vendor API call budget is exhausted and the stale rates aren't in cache",
+			vars: vars{
+				component: components.BaseComponent{
+					ReqCtx:    context.Background(),
+					RedisConn: staleConn,
+				},
+				form: &CurrencyExchangeRateForm{
+					BaseCurrency:     "USD",
+					TargetCurrencies: []string{"INR", "EUR"},
+				},
+				headers: map[string]string{
+					"x-mock-api": "budget_exhausted",
+				},
+			},
+			hasErr: true,
+			err:    "vendor API call budget exhausted",
+		},
 	}
 
 	for _, tCase := range testCases {
@@ -298,6 +444,11 @@ func TestCurrencyExchangeRateComponent_GetCurrencyExchangeRate(t *testing.T) {
 			ctx := ttc.ReqCtx
 			ctx = context.WithValue(ctx, "x-mock-headers", tCase.vars.headers)
 			ttc.ReqCtx = ctx
+			spread := constants.FX_RATES_SPREAD_PERCENTAGE
+			t.Cleanup(func() {
+				constants.FX_RATES_SPREAD_PERCENTAGE = spread
+			})
+			constants.FX_RATES_SPREAD_PERCENTAGE = tCase.vars.spread
 
 			// Run test
 			got, err := ttc.GetCurrencyExchangeRate(form)
@@ -318,3 +469,114 @@ func TestCurrencyExchangeRateComponent_GetCurrencyExchangeRate(t *testing.T) {
 	}
 
 }
+
+func TestCurrencyExchangeRateResponse_MarshalCSV(t *testing.T) {
+	// Setup
+	lastUpdateTime, _ := time.Parse(time.RFC3339, "2024-02-26T12:04:00Z")
+	resp := &CurrencyExchangeRateResponse{
+		BaseCurrency: "USD",
+		ExchangeRates: map[string]Currency{
+			"JPY": {CurrencyExchangeRate: "150.608807", Bid: "150.5", Ask: "150.7", Mid: "150.608807", LastUpdateTime: lastUpdateTime},
+			"INR": {CurrencyExchangeRate: "82.771291", Bid: "82.7", Ask: "82.8", Mid: "82.771291", LastUpdateTime: lastUpdateTime},
+		},
+	}
+
+	// Run test
+	got := resp.MarshalCSV()
+
+	// Assert
+	assert.Equal(t, [][]string{
+		{"base_currency", "currency", "currency_exchange_rate", "bid", "ask", "mid", "last_update_time"},
+		{"USD", "INR", "82.771291", "82.7", "82.8", "82.771291", "2024-02-26T12:04:00Z"},
+		{"USD", "JPY", "150.608807", "150.5", "150.7", "150.608807", "2024-02-26T12:04:00Z"},
+	}, got)
+}
+
+func TestCurrencyExchangeRateResponse_LastModified(t *testing.T) {
+	older := time.Date(2024, time.February, 25, 12, 0, 0, 0, time.UTC)
+	newer := time.Date(2024, time.February, 26, 12, 4, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name string
+
+		exchangeRates map[string]Currency
+
+		want time.Time
+	}{
+		{
+			name:          "should get the update time of the most recent exchange rate",
+			exchangeRates: map[string]Currency{"INR": {LastUpdateTime: older}, "JPY": {LastUpdateTime: newer}},
+			want:          newer,
+		},
+		{
+			name: "should get the zero time without exchange rates",
+		},
+	}
+
+	for _, tCase := range testCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			// Setup
+			resp := &CurrencyExchangeRateResponse{BaseCurrency: "USD", ExchangeRates: tCase.exchangeRates}
+
+			// Run test
+			got := resp.LastModified()
+
+			// Assert
+			assert.Equalf(t, tCase.want, got, "case: %v", tCase)
+		})
+	}
+}
+
+func TestCurrencyExchangeRateComponent_GetCurrencyExchangeRate_cacheTTL(t *testing.T) {
+	constants.CURRENCY_CODES_JSON_FILE_NAME = "../../currency_codes.json"
+	constants.REDIS_DEFAULT_EXPIRY = "3600"
+
+	testCases := []struct {
+		name string
+
+		cacheTTLs map[string]int
+
+		want int
+	}{
+		{
+			name:      "should be the remaining TTL of the cached rate expiring first",
+			cacheTTLs: map[string]int{"USD-INR": 120, "USD-JPY": 60},
+			want:      60,
+		},
+		{
+			name:      "should be the remaining TTL of the cached rate when the others are fetched",
+			cacheTTLs: map[string]int{"USD-INR": 120},
+			want:      120,
+		},
+		{
+			name: "should be the default expiry when all the rates are fetched",
+			want: 3600,
+		},
+	}
+
+	for _, tCase := range testCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			// Setup
+			conn := utils.NewMockRedisConn()
+			for cacheKey, ttl := range tCase.cacheTTLs {
+				dataBytes, _ := json.Marshal(&Currency{CurrencyExchangeRate: "1", LastUpdateTime: time.Date(2024, time.February, 26, 12, 4, 0, 0, time.UTC)})
+				_, _ = utils.SetData(conn, cacheKey, base64.StdEncoding.EncodeToString(dataBytes), ttl)
+			}
+			cec := &CurrencyExchangeRateComponent{
+				BaseComponent: components.BaseComponent{
+					ReqCtx:    context.WithValue(context.Background(), "x-mock-headers", map[string]string{"x-mock-api": "default"}),
+					RedisConn: conn,
+				},
+			}
+
+			// Run test
+			got, err := cec.GetCurrencyExchangeRate(&CurrencyExchangeRateForm{BaseCurrency: "USD", TargetCurrencies: []string{"INR", "JPY"}})
+
+			// Assert
+			if assert.NoErrorf(t, err, "case: %v", tCase) {
+				assert.Equalf(t, tCase.want, got.CacheTTL, "case: %v", tCase)
+			}
+		})
+	}
+	constants.REDIS_DEFAULT_EXPIRY = ""
+}
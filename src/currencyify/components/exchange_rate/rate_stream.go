@@ -0,0 +1,310 @@
+package exchange_rate
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"currencyify/components"
+	"currencyify/constants"
+	"currencyify/utils"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+const defaultRateStreamInterval = 5 * time.Second
+
+type RateStreamForm struct {
+	CurrencyExchangeRateForm
+	// Threshold is the minimum change, in percentage, of a rate to push it to the subscriber again.
+	Threshold float64 `json:"threshold"`
+}
+
+type RateSubscription struct {
+	form    *RateStreamForm
+	updates chan *CurrencyExchangeRateResponse
+	// lastSent holds the rates last pushed to the subscriber, to compare with the threshold.
+	lastSent map[string]float64
+
+	hub  *rateHub
+	once sync.Once
+}
+
+// rateHub shares one rate poller per base currency among all the subscribers of that base, so the number of cache and
+// vendor API lookups doesn't grow with the number of subscribers.
+type rateHub struct {
+	mu      sync.Mutex
+	pollers map[string]*ratePoller
+
+	reqCtx    context.Context
+	redisConn func() (redis.Conn, error)
+	interval  time.Duration
+}
+
+type ratePoller struct {
+	baseCurrency string
+	subscribers  map[*RateSubscription]struct{}
+	kick         chan struct{}
+	stop         chan struct{}
+}
+
+var hub = newRateHub(context.Background(), utils.Conn)
+
+func newRateHub(reqCtx context.Context, redisConn func() (redis.Conn, error)) *rateHub {
+	return &rateHub{
+		pollers:   make(map[string]*ratePoller),
+		reqCtx:    reqCtx,
+		redisConn: redisConn,
+	}
+}
+
+// SubscribeCurrencyExchangeRate is used to subscribe to the exchange rates of the given currency codes. The current
+// rates are pushed right away, later ones only when they change beyond the threshold of the subscription.
+// It returns the subscription, which must be closed once done, and error.
+func (cec *CurrencyExchangeRateComponent) SubscribeCurrencyExchangeRate(form *RateStreamForm) (*RateSubscription, error) {
+	if err := cec.ValidForm(form); err != nil {
+		cec.SetCurrencyExchangeRateAppError(http.StatusBadRequest, err)
+		return nil, err
+	}
+
+	return hub.subscribe(form), nil
+}
+
+// Updates is used to receive the exchange rates pushed to the subscription. Only the rates which changed are pushed.
+// It returns the channel of updates.
+func (s *RateSubscription) Updates() <-chan *CurrencyExchangeRateResponse {
+	return s.updates
+}
+
+// Close is used to unsubscribe, stopping the poller of the base currency when it was the last subscriber.
+func (s *RateSubscription) Close() {
+	s.once.Do(func() {
+		s.hub.unsubscribe(s)
+	})
+}
+
+func (h *rateHub) subscribe(form *RateStreamForm) *RateSubscription {
+	s := &RateSubscription{
+		form:     form,
+		updates:  make(chan *CurrencyExchangeRateResponse, 1),
+		lastSent: make(map[string]float64),
+		hub:      h,
+	}
+
+	h.mu.Lock()
+	p, ok := h.pollers[form.BaseCurrency]
+	if !ok {
+		p = &ratePoller{
+			baseCurrency: form.BaseCurrency,
+			subscribers:  make(map[*RateSubscription]struct{}),
+			kick:         make(chan struct{}, 1),
+			stop:         make(chan struct{}),
+		}
+		h.pollers[form.BaseCurrency] = p
+		go h.run(p)
+	}
+	p.subscribers[s] = struct{}{}
+	h.mu.Unlock()
+
+	// poll right away, so the new subscriber gets the current rates without waiting for the next tick
+	select {
+	case p.kick <- struct{}{}:
+	default:
+	}
+
+	return s
+}
+
+func (h *rateHub) unsubscribe(s *RateSubscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	p, ok := h.pollers[s.form.BaseCurrency]
+	if !ok {
+		return
+	}
+
+	delete(p.subscribers, s)
+	if len(p.subscribers) == 0 {
+		delete(h.pollers, s.form.BaseCurrency)
+		close(p.stop)
+	}
+}
+
+func (h *rateHub) run(p *ratePoller) {
+	ticker := time.NewTicker(h.pollInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-p.kick:
+			h.poll(p)
+		case <-ticker.C:
+			h.poll(p)
+		}
+	}
+}
+
+// poll looks up the rates of all the currencies subscribed for the base currency, and pushes the changed ones.
+func (h *rateHub) poll(p *ratePoller) {
+	h.mu.Lock()
+	subscribers := make([]*RateSubscription, 0, len(p.subscribers))
+	targetCurrencies := make([]string, 0)
+	seen := make(map[string]bool)
+	for s := range p.subscribers {
+		subscribers = append(subscribers, s)
+		for _, currencyCode := range s.form.TargetCurrencies {
+			if !seen[currencyCode] {
+				seen[currencyCode] = true
+				targetCurrencies = append(targetCurrencies, currencyCode)
+			}
+		}
+	}
+	h.mu.Unlock()
+
+	if len(subscribers) == 0 {
+		return
+	}
+
+	resp, err := h.getCurrencyExchangeRate(p.baseCurrency, targetCurrencies)
+	if err != nil {
+		slog.Error("error polling exchange rates", "base", p.baseCurrency, "error", err)
+		return
+	}
+
+	for _, s := range subscribers {
+		s.push(resp)
+	}
+}
+
+func (h *rateHub) getCurrencyExchangeRate(baseCurrency string, targetCurrencies []string) (*CurrencyExchangeRateResponse, error) {
+	conn, err := h.redisConn()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if conn != nil {
+			_ = conn.Close()
+		}
+	}()
+
+	cec := &CurrencyExchangeRateComponent{
+		BaseComponent: components.BaseComponent{
+			ReqCtx:    h.reqCtx,
+			AppError:  new(utils.AppError),
+			RedisConn: conn,
+		},
+	}
+	form := &CurrencyExchangeRateForm{
+		BaseCurrency:     baseCurrency,
+		TargetCurrencies: targetCurrencies,
+	}
+	result, _, err := cec.getCurrencyExchangeRate(form)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CurrencyExchangeRateResponse{BaseCurrency: baseCurrency, ExchangeRates: result}, nil
+}
+
+func (h *rateHub) pollInterval() time.Duration {
+	if h.interval > 0 {
+		return h.interval
+	}
+
+	if constants.RATE_STREAM_POLL_INTERVAL != "" {
+		if d, err := time.ParseDuration(constants.RATE_STREAM_POLL_INTERVAL); err == nil && d > 0 {
+			return d
+		}
+		slog.Warn("Error parsing duration RATE_STREAM_POLL_INTERVAL", "value", constants.RATE_STREAM_POLL_INTERVAL)
+	}
+
+	return defaultRateStreamInterval
+}
+
+// push sends the rates of the subscribed currencies which changed beyond the threshold since they were last sent.
+// A pending update not yet received by the subscriber is merged with the new one, so a slow subscriber never blocks the poller.
+func (s *RateSubscription) push(resp *CurrencyExchangeRateResponse) {
+	update := &CurrencyExchangeRateResponse{
+		BaseCurrency:  resp.BaseCurrency,
+		ExchangeRates: make(map[string]Currency),
+	}
+
+	s.hub.mu.Lock()
+	for _, currencyCode := range s.form.TargetCurrencies {
+		currency, ok := resp.ExchangeRates[currencyCode]
+		if !ok {
+			continue
+		}
+		rate, err := strconv.ParseFloat(currency.CurrencyExchangeRate, 64)
+		if err != nil {
+			continue
+		}
+
+		if last, ok := s.lastSent[currencyCode]; ok && !exceedsThreshold(last, rate, s.form.Threshold) {
+			continue
+		}
+		s.lastSent[currencyCode] = rate
+		update.ExchangeRates[currencyCode] = currency
+	}
+	s.hub.mu.Unlock()
+
+	if len(update.ExchangeRates) == 0 {
+		return
+	}
+
+	select {
+	case pending := <-s.updates:
+		for currencyCode, currency := range update.ExchangeRates {
+			pending.ExchangeRates[currencyCode] = currency
+		}
+		update = pending
+	default:
+	}
+
+	select {
+	case s.updates <- update:
+	default:
+	}
+}
+
+func exceedsThreshold(last, rate, threshold float64) bool {
+	if rate == last {
+		return false
+	}
+	if last == 0 {
+		return true
+	}
+
+	return math.Abs(rate-last)/last*100 >= threshold
+}
+
+// GetRateStreamForm is used to create a new rate stream form instance.
+// It returns rate stream form instance.
+func (cec *CurrencyExchangeRateComponent) GetRateStreamForm() *RateStreamForm {
+	return new(RateStreamForm)
+}
+
+// Valid validates and sanitizes the rate stream form.
+func (f *RateStreamForm) Valid() error {
+	if err := f.CurrencyExchangeRateForm.Valid(); err != nil {
+		return err
+	}
+
+	errs := utils.ValidationErrors{}
+	if f.Threshold < 0 {
+		errs.Add(utils.ErrCodeOutOfRange, "threshold", f.Threshold, "`threshold` parameter should not be negative")
+	}
+
+	if f.Date != "" {
+		errs.Add(utils.ErrCodeUnsupported, "date", f.Date, "`date` parameter is not supported for streaming, only the latest rates are streamed")
+	}
+
+	return errs.Err()
+}
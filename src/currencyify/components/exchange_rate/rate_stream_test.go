@@ -0,0 +1,189 @@
+package exchange_rate
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"currencyify/constants"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateStreamForm_Valid(t *testing.T) {
+	constants.CURRENCY_CODES_JSON_FILE_NAME = "../../currency_codes.json"
+
+	type vars struct {
+		form *RateStreamForm
+	}
+
+	testCases := []struct {
+		name string
+
+		vars vars
+
+		hasErr bool
+		err    string
+	}{
+		{
+			name: "should fail when base currency code is empty",
+			vars: vars{
+				form: &RateStreamForm{
+					CurrencyExchangeRateForm: CurrencyExchangeRateForm{
+						TargetCurrencies: []string{"inr"},
+					},
+				},
+			},
+			hasErr: true,
+			err:    "`base_currency` parameter is required",
+		},
+		{
+			name: "should fail when threshold is negative",
+			vars: vars{
+				form: &RateStreamForm{
+					CurrencyExchangeRateForm: CurrencyExchangeRateForm{
+						BaseCurrency:     "USD",
+						TargetCurrencies: []string{"inr"},
+					},
+					Threshold: -1,
+				},
+			},
+			hasErr: true,
+			err:    "`threshold` parameter should not be negative",
+		},
+		{
+			name: "should success to validate the rate stream input form",
+			vars: vars{
+				form: &RateStreamForm{
+					CurrencyExchangeRateForm: CurrencyExchangeRateForm{
+						BaseCurrency:     "USD",
+						TargetCurrencies: []string{"inr"},
+					},
+					Threshold: 0.5,
+				},
+			},
+		},
+	}
+
+	for _, tCase := range testCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			// Run test
+			err := tCase.vars.form.Valid()
+
+			// Assert
+			if tCase.hasErr {
+				if assert.Errorf(t, err, "case: %v", tCase) {
+					assert.Containsf(t, err.Error(), tCase.err, "case: %v", tCase)
+				}
+			} else {
+				assert.NoErrorf(t, err, "case: %v", tCase)
+			}
+		})
+	}
+}
+
+func Test_exceedsThreshold(t *testing.T) {
+	type vars struct {
+		last      float64
+		rate      float64
+		threshold float64
+	}
+
+	testCases := []struct {
+		name string
+
+		vars vars
+
+		want bool
+	}{
+		{
+			name: "should not push the rate when it didn't change",
+			vars: vars{last: 82.5, rate: 82.5, threshold: 0},
+			want: false,
+		},
+		{
+			name: "should push any change when threshold is zero",
+			vars: vars{last: 82.5, rate: 82.500001, threshold: 0},
+			want: true,
+		},
+		{
+			name: "should not push the rate when change is below threshold",
+			vars: vars{last: 100, rate: 100.4, threshold: 0.5},
+			want: false,
+		},
+		{
+			name: "should push the rate when change reaches threshold",
+			vars: vars{last: 100, rate: 99.5, threshold: 0.5},
+			want: true,
+		},
+	}
+
+	for _, tCase := range testCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			// Run test
+			got := exceedsThreshold(tCase.vars.last, tCase.vars.rate, tCase.vars.threshold)
+
+			// Assert
+			assert.Equal(t, tCase.want, got, "case: %v", tCase)
+		})
+	}
+}
+
+func TestRateHub_subscribe(t *testing.T) {
+	constants.CURRENCY_CODES_JSON_FILE_NAME = "../../currency_codes.json"
+	constants.FX_RATES_SPREAD_PERCENTAGE = ""
+
+	// Setup
+	ctx := context.WithValue(context.Background(), "x-mock-headers", map[string]string{"x-mock-api": "default"})
+	h := newRateHub(ctx, func() (redis.Conn, error) {
+		return nil, nil
+	})
+	h.interval = 10 * time.Millisecond
+
+	form := &RateStreamForm{
+		CurrencyExchangeRateForm: CurrencyExchangeRateForm{
+			BaseCurrency:     "USD",
+			TargetCurrencies: []string{"INR"},
+		},
+	}
+	otherForm := &RateStreamForm{
+		CurrencyExchangeRateForm: CurrencyExchangeRateForm{
+			BaseCurrency:     "USD",
+			TargetCurrencies: []string{"JPY"},
+		},
+	}
+
+	// Run test
+	s := h.subscribe(form)
+	other := h.subscribe(otherForm)
+
+	// Assert
+	assert.Len(t, h.pollers, 1, "subscribers of the same base currency should share the poller")
+
+	select {
+	case got := <-s.Updates():
+		assert.Equal(t, "USD", got.BaseCurrency)
+		assert.Equal(t, "82.771291", got.ExchangeRates["INR"].CurrencyExchangeRate)
+		assert.NotContains(t, got.ExchangeRates, "JPY")
+	case <-time.After(time.Second):
+		assert.Fail(t, "current rates should be pushed right away")
+	}
+
+	select {
+	case got := <-other.Updates():
+		assert.Equal(t, "150.608807", got.ExchangeRates["JPY"].CurrencyExchangeRate)
+	case <-time.After(time.Second):
+		assert.Fail(t, "current rates should be pushed right away")
+	}
+
+	select {
+	case got := <-s.Updates():
+		assert.Fail(t, "unchanged rates should not be pushed again", "got: %v", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	s.Close()
+	other.Close()
+	assert.Empty(t, h.pollers, "poller should be stopped once the last subscriber leaves")
+}
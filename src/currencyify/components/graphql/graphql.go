@@ -0,0 +1,99 @@
+package graphql
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"net/http"
+
+	"currencyify/components"
+	"currencyify/utils"
+
+	graphqlgo "github.com/graph-gophers/graphql-go"
+	gqlerrors "github.com/graph-gophers/graphql-go/errors"
+)
+
+//go:embed schema.graphql
+var schemaSDL string
+
+type GraphQLComponent struct {
+	components.BaseComponent
+}
+
+type GraphQL interface {
+	Exec(*GraphQLForm) (*graphqlgo.Response, error)
+
+	GetGraphQLForm() *GraphQLForm
+	GetGraphQLAppError() *utils.AppError
+	SetGraphQLAppError(int, error)
+}
+
+type GraphQLForm struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+type loaderCtxKey struct{}
+
+var schema = graphqlgo.MustParseSchema(schemaSDL, &resolver{}, graphqlgo.UseFieldResolvers())
+
+// Exec is used to execute the given GraphQL query. Rate lookups of all the fields of the query are batched, so the
+// rates of a base currency are looked up only once per query.
+// It returns the GraphQL response, with the field level errors in it, and error when the query couldn't be executed.
+func (gc *GraphQLComponent) Exec(form *GraphQLForm) (*graphqlgo.Response, error) {
+	if err := gc.ValidForm(form); err != nil {
+		gc.SetGraphQLAppError(http.StatusBadRequest, err)
+		return &graphqlgo.Response{Errors: []*gqlerrors.QueryError{gqlerrors.Errorf("%s", err)}}, err
+	}
+
+	loader := newRateLoader(&gc.BaseComponent)
+	ctx := context.WithValue(gc.ReqCtx, loaderCtxKey{}, loader)
+	resp := schema.Exec(ctx, form.Query, form.OperationName, form.Variables)
+	if resp.Data == nil && len(resp.Errors) > 0 {
+		// the query itself is invalid, i.e. it doesn't match the schema
+		err := errors.New(resp.Errors[0].Message)
+		gc.SetGraphQLAppError(http.StatusBadRequest, err)
+		return resp, err
+	}
+
+	return resp, nil
+}
+
+// GetGraphQLForm is used to create a new GraphQL form instance.
+// It returns GraphQL form instance.
+func (gc *GraphQLComponent) GetGraphQLForm() *GraphQLForm {
+	return new(GraphQLForm)
+}
+
+// GetGraphQLAppError is used to retrieve app error from the GraphQL component.
+// It returns app error of the component.
+func (gc *GraphQLComponent) GetGraphQLAppError() *utils.AppError {
+	return gc.AppError
+}
+
+// SetGraphQLAppError is used to set the app error for the GraphQL component.
+func (gc *GraphQLComponent) SetGraphQLAppError(status int, err error) {
+	gc.AppError = &utils.AppError{
+		Status: status,
+		Error:  err,
+	}
+}
+
+// Valid validates the GraphQL form.
+func (f *GraphQLForm) Valid() error {
+	errs := utils.ValidationErrors{}
+	if f.Query == "" {
+		errs.Add(utils.ErrCodeRequired, "query", nil, "`query` parameter is required")
+	}
+
+	return errs.Err()
+}
+
+func init() {
+	components.ComponentMap["GraphQL"] = func(bc *components.BaseComponent) interface{} {
+		c := &GraphQLComponent{BaseComponent: *bc}
+
+		return GraphQL(c)
+	}
+}
@@ -0,0 +1,308 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"currencyify/components"
+	"currencyify/components/audit"
+	"currencyify/components/exchange_rate"
+	"currencyify/constants"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGraphQLComponent_Exec(t *testing.T) {
+	constants.CURRENCY_CODES_JSON_FILE_NAME = "../../currency_codes.json"
+	constants.FX_RATES_SPREAD_PERCENTAGE = ""
+
+	type vars struct {
+		form *GraphQLForm
+
+		headers map[string]string
+	}
+
+	testCases := []struct {
+		name string
+
+		vars vars
+
+		want       string
+		wantErrors []string
+		hasErr     bool
+		err        string
+	}{
+		{
+			name: "should success to convert the given amounts",
+			vars: vars{
+				form: &GraphQLForm{
+					Query: `{ inr: convert(from: "usd", to: "INR", amount: 2) { from to amount rate convertedAmount } jpy: convert(from: "USD", to: "JPY", amount: 1) { convertedAmount lastUpdateTime } }`,
+				},
+				headers: map[string]string{
+					"x-mock-api": "default",
+				},
+			},
+			want: `{"inr":{"from":"USD","to":"INR","amount":2,"rate":82.771291,"convertedAmount":165.542582},"jpy":{"convertedAmount":150.608807,"lastUpdateTime":"2024-02-26T12:04:00Z"}}`,
+		},
+		{
+			name: "should success to fetch the exchange rates of the given currency codes using variables",
+			vars: vars{
+				form: &GraphQLForm{
+					Query:     `query Rates($base: String!) { rates(base: $base, symbols: ["JPY", "INR"]) { base date rates { currency rate bid ask mid } } }`,
+					Variables: map[string]interface{}{"base": "USD"},
+				},
+				headers: map[string]string{
+					"x-mock-api": "default",
+				},
+			},
+			want: `{"rates":{"base":"USD","date":null,"rates":[{"currency":"JPY","rate":150.608807,"bid":150.608807,"ask":150.608807,"mid":150.608807},{"currency":"INR","rate":82.771291,"bid":82.771291,"ask":82.771291,"mid":82.771291}]}}`,
+		},
+		{
+			name: "should success to fetch the historical exchange rates of the given date",
+			vars: vars{
+				form: &GraphQLForm{
+					Query: `{ rates(base: "USD", symbols: ["INR"], date: "2024-01-15") { date rates { currency rate lastUpdateTime } } }`,
+				},
+				headers: map[string]string{
+					"x-mock-api": "default",
+				},
+			},
+			want: `{"rates":{"date":"2024-01-15","rates":[{"currency":"INR","rate":83,"lastUpdateTime":"2024-01-15T23:59:00Z"}]}}`,
+		},
+		{
+			name: "should report field level errors along with the other fields",
+			vars: vars{
+				form: &GraphQLForm{
+					Query: `{ inr: convert(from: "USD", to: "INR", amount: 2) { convertedAmount } bad: convert(from: "USD", to: "India", amount: 2) { convertedAmount } }`,
+				},
+				headers: map[string]string{
+					"x-mock-api": "default",
+				},
+			},
+			want:       `{"inr":{"convertedAmount":165.542582},"bad":null}`,
+			wantErrors: []string{"`target_currency` not found in our database"},
+		},
+		{
+			name: "should report the vendor API errors in the fields",
+			vars: vars{
+				form: &GraphQLForm{
+					Query: `{ convert(from: "USD", to: "INR", amount: 2) { convertedAmount } }`,
+				},
+				headers: map[string]string{
+					"x-mock-api": "error_response",
+				},
+			},
+			want:       `{"convert":null}`,
+			wantErrors: []string{"error"},
+		},
+		{
+			name: "should fail when query is empty",
+			vars: vars{
+				form: &GraphQLForm{},
+			},
+			hasErr: true,
+			err:    "`query` parameter is required",
+		},
+		{
+			name: "should fail when query doesn't match the schema",
+			vars: vars{
+				form: &GraphQLForm{
+					Query: `{ convert(from: "USD") { convertedAmount } }`,
+				},
+			},
+			hasErr: true,
+			err:    "Field \"convert\" argument \"to\" of type \"String!\" is required but not provided.",
+		},
+	}
+
+	for _, tCase := range testCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			// Setup
+			ttc := &GraphQLComponent{
+				BaseComponent: components.BaseComponent{
+					ReqCtx: context.WithValue(context.Background(), "x-mock-headers", tCase.vars.headers),
+				},
+			}
+
+			// Run test
+			got, err := ttc.Exec(tCase.vars.form)
+
+			// Assert
+			if tCase.hasErr {
+				if assert.Errorf(t, err, "case: %v", tCase) {
+					assert.Containsf(t, err.Error(), tCase.err, "case: %v", tCase)
+					assert.Equal(t, 400, ttc.GetGraphQLAppError().Status)
+					assert.NotEmpty(t, got.Errors)
+				}
+			} else {
+				assert.NoErrorf(t, err, "case: %v", tCase)
+				assert.JSONEq(t, tCase.want, string(got.Data), "case: %v", tCase)
+				assert.Len(t, got.Errors, len(tCase.wantErrors), "case: %v", tCase)
+				for index, wantErr := range tCase.wantErrors {
+					if index < len(got.Errors) {
+						assert.Contains(t, got.Errors[index].Message, wantErr, "case: %v", tCase)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestRateLoader_load(t *testing.T) {
+	constants.CURRENCY_CODES_JSON_FILE_NAME = "../../currency_codes.json"
+	constants.FX_RATES_SPREAD_PERCENTAGE = ""
+
+	// Setup
+	l := newRateLoader(&components.BaseComponent{
+		ReqCtx: context.WithValue(context.Background(), "x-mock-headers", map[string]string{"x-mock-api": "default"}),
+	})
+
+	// Run test
+	inr := l.load("USD", []string{"INR"}, "")
+	jpy := l.load("USD", []string{"JPY", "INR"}, "")
+	historical := l.load("USD", []string{"INR"}, "2024-01-15")
+
+	// Assert
+	assert.Len(t, l.batches, 2, "rates of the same base currency and date should be batched together")
+	assert.Equal(t, []string{"INR", "JPY"}, l.batches["USD@"].form.TargetCurrencies)
+	assert.Len(t, l.pending, 2, "rates shouldn't be looked up before they are waited for")
+
+	inrRates, err := inr()
+	assert.NoError(t, err)
+	assert.Equal(t, "82.771291", inrRates["INR"].CurrencyExchangeRate)
+	assert.NotContains(t, inrRates, "JPY")
+
+	jpyRates, err := jpy()
+	assert.NoError(t, err)
+	assert.Equal(t, "150.608807", jpyRates["JPY"].CurrencyExchangeRate)
+	assert.Equal(t, "82.771291", jpyRates["INR"].CurrencyExchangeRate)
+
+	historicalRates, err := historical()
+	assert.NoError(t, err)
+	assert.Equal(t, "83", historicalRates["INR"].CurrencyExchangeRate)
+
+	inr = l.load("USD", []string{"INR"}, "")
+	assert.Empty(t, l.pending, "rates already looked up shouldn't be looked up again")
+	inrRates, err = inr()
+	assert.NoError(t, err)
+	assert.Equal(t, "82.771291", inrRates["INR"].CurrencyExchangeRate)
+}
+
+// countingExchangeRate counts the rate lookups of the currency exchange rate component.
+type countingExchangeRate struct {
+	exchange_rate.CurrencyExchangeRate
+
+	mu      *sync.Mutex
+	lookups map[string]int
+}
+
+func (c countingExchangeRate) GetCurrencyExchangeRate(form *exchange_rate.CurrencyExchangeRateForm) (*exchange_rate.CurrencyExchangeRateResponse, error) {
+	c.mu.Lock()
+	c.lookups[form.BaseCurrency]++
+	c.mu.Unlock()
+
+	return c.CurrencyExchangeRate.GetCurrencyExchangeRate(form)
+}
+
+func TestGraphQLComponent_Exec_batchesConcurrentFields(t *testing.T) {
+	constants.CURRENCY_CODES_JSON_FILE_NAME = "../../currency_codes.json"
+	constants.FX_RATES_SPREAD_PERCENTAGE = ""
+
+	// Setup
+	lookups := make(map[string]int)
+	newExchangeRate := components.ComponentMap["CurrencyExchangeRate"]
+	components.ComponentMap["CurrencyExchangeRate"] = func(bc *components.BaseComponent) interface{} {
+		component, _ := newExchangeRate(bc).(exchange_rate.CurrencyExchangeRate)
+		return exchange_rate.CurrencyExchangeRate(countingExchangeRate{CurrencyExchangeRate: component, mu: new(sync.Mutex), lookups: lookups})
+	}
+	defer func() {
+		components.ComponentMap["CurrencyExchangeRate"] = newExchangeRate
+	}()
+	// the window is widened, so the fields are all resolved within it even on a loaded machine
+	window := rateBatchWindow
+	rateBatchWindow = 100 * time.Millisecond
+	defer func() {
+		rateBatchWindow = window
+	}()
+
+	var query strings.Builder
+	query.WriteString("{ ")
+	for i := 0; i < 15; i++ {
+		fmt.Fprintf(&query, `c%d: convert(from: "usd", to: "INR", amount: %d) { convertedAmount } `, i, i+1)
+		fmt.Fprintf(&query, `r%d: rates(base: "USD", symbols: ["JPY"]) { rates { rate } } `, i)
+	}
+	query.WriteString("}")
+
+	ttc := &GraphQLComponent{
+		BaseComponent: components.BaseComponent{
+			ReqCtx: context.WithValue(context.Background(), "x-mock-headers", map[string]string{"x-mock-api": "default"}),
+		},
+	}
+
+	// Run test
+	got, err := ttc.Exec(&GraphQLForm{Query: query.String()})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Empty(t, got.Errors)
+	assert.Contains(t, string(got.Data), `"c14":{"convertedAmount":1241.569365}`)
+	assert.Contains(t, string(got.Data), `"r14":{"rates":[{"rate":150.608807}]}`)
+	assert.Equal(t, map[string]int{"USD": 1}, lookups, "rates of a base currency should be looked up once per query")
+}
+
+type mockAuditSink struct {
+	records []audit.Record
+}
+
+func (s *mockAuditSink) Write(records []audit.Record) error {
+	s.records = append(s.records, records...)
+	return nil
+}
+
+func (s *mockAuditSink) Close() error {
+	return nil
+}
+
+func TestGraphQLComponent_Exec_audit(t *testing.T) {
+	constants.CURRENCY_CODES_JSON_FILE_NAME = "../../currency_codes.json"
+	constants.FX_RATES_API_URL = "https://api.fxratesapi.com/latest"
+	constants.FX_RATES_SPREAD_PERCENTAGE = ""
+
+	// Setup
+	sink := &mockAuditSink{}
+	logger := audit.NewLogger(sink, 10)
+	auditLogger = func() *audit.Logger {
+		return logger
+	}
+	ctx := audit.NewContext(context.Background(), audit.Request{ID: "req-1", Client: "api_key:a1b2c3"})
+	ttc := &GraphQLComponent{
+		BaseComponent: components.BaseComponent{
+			ReqCtx: context.WithValue(ctx, "x-mock-headers", map[string]string{"x-mock-api": "default"}),
+		},
+	}
+
+	// Run test
+	got, err := ttc.Exec(&GraphQLForm{Query: `{ inr: convert(from: "USD", to: "INR", amount: 100) { rate convertedAmount lastUpdateTime } jpy: convert(from: "USD", to: "JPY", amount: 1) { convertedAmount } echo: convert(from: "USD", to: "INR", amount: 5) { from to } }`})
+	assert.NoError(t, logger.Close())
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Empty(t, got.Errors)
+	if assert.Len(t, sink.records, 2, "each conversion served should be recorded once") {
+		sort.Slice(sink.records, func(i, j int) bool {
+			return sink.records[i].TargetCurrency < sink.records[j].TargetCurrency
+		})
+		assert.Equal(t, "req-1", sink.records[0].RequestID)
+		assert.Equal(t, "INR", sink.records[0].TargetCurrency)
+		assert.InDelta(t, 8277.1291, sink.records[0].ConvertedAmount, 1e-6)
+		assert.Equal(t, "api.fxratesapi.com", sink.records[0].Provider)
+		assert.Equal(t, "JPY", sink.records[1].TargetCurrency)
+		assert.InDelta(t, 150.608807, sink.records[1].ConvertedAmount, 1e-6)
+	}
+	auditLogger = audit.DefaultLogger
+}
@@ -0,0 +1,157 @@
+package graphql
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"currencyify/components"
+	"currencyify/components/exchange_rate"
+)
+
+// rateBatchWindow is how long the lookup of the rates waits for the fields resolved concurrently to add their rates to
+// the batches, restarted by each rate added.
+var rateBatchWindow = 5 * time.Millisecond
+
+// rateLoader batches the rate lookups of a query, so the rates asked by all its fields are looked up in one call per
+// base currency and date. The fields of the query only add the rates they need to the batches when they are resolved,
+// and all the pending batches are looked up when the rates are first waited for, once no rate was added for the batch
+// window, since the fields are resolved concurrently.
+type rateLoader struct {
+	base *components.BaseComponent
+
+	mu       sync.Mutex
+	batches  map[string]*rateBatch
+	pending  []*rateBatch
+	loadedAt time.Time
+
+	// dispatchMu serializes the lookups, since they share the redis connection of the request.
+	dispatchMu sync.Mutex
+}
+
+type rateBatch struct {
+	key  string
+	form *exchange_rate.CurrencyExchangeRateForm
+	done chan struct{}
+
+	dispatched bool
+	resp       *exchange_rate.CurrencyExchangeRateResponse
+	err        error
+}
+
+func newRateLoader(base *components.BaseComponent) *rateLoader {
+	return &rateLoader{
+		base:    base,
+		batches: make(map[string]*rateBatch),
+	}
+}
+
+// load adds the given currency codes to the batch of the base currency and date, without waiting for the lookup.
+// It returns the function waiting for the lookup, which returns the rates of the given currency codes and error.
+func (l *rateLoader) load(baseCurrency string, targetCurrencies []string, date string) func() (map[string]exchange_rate.Currency, error) {
+	key := fmt.Sprintf("%s@%s", baseCurrency, date)
+
+	l.mu.Lock()
+	b, ok := l.batches[key]
+	if ok && b.dispatched && !containsCurrencies(b.form.TargetCurrencies, targetCurrencies) {
+		// the batch is already looked up without these currency codes, they are looked up in a new batch
+		ok = false
+	}
+	if !ok {
+		b = &rateBatch{
+			key:  key,
+			form: &exchange_rate.CurrencyExchangeRateForm{BaseCurrency: baseCurrency, Date: date},
+			done: make(chan struct{}),
+		}
+		l.batches[key] = b
+		l.pending = append(l.pending, b)
+	}
+	for _, currencyCode := range targetCurrencies {
+		if !containsCurrency(b.form.TargetCurrencies, currencyCode) {
+			b.form.TargetCurrencies = append(b.form.TargetCurrencies, currencyCode)
+		}
+	}
+	l.loadedAt = time.Now()
+	l.mu.Unlock()
+
+	return func() (map[string]exchange_rate.Currency, error) {
+		l.dispatch(b)
+		<-b.done
+		if b.err != nil {
+			return nil, b.err
+		}
+
+		rates := make(map[string]exchange_rate.Currency)
+		for _, currencyCode := range targetCurrencies {
+			rate, ok := b.resp.ExchangeRates[currencyCode]
+			if !ok {
+				return nil, fmt.Errorf("exchange rate of currency (%s) not found", currencyCode)
+			}
+			rates[currencyCode] = rate
+		}
+
+		return rates, nil
+	}
+}
+
+// dispatch looks up the rates of all the pending batches, along with the given batch unless it is already looked up,
+// using the currency exchange rate component. Concurrent calls wait for the lookups in progress.
+func (l *rateLoader) dispatch(b *rateBatch) {
+	l.dispatchMu.Lock()
+	defer l.dispatchMu.Unlock()
+
+	for {
+		l.mu.Lock()
+		dispatched, wait := b.dispatched, rateBatchWindow-time.Since(l.loadedAt)
+		l.mu.Unlock()
+		if dispatched {
+			return
+		} else if wait <= 0 {
+			break
+		}
+		time.Sleep(wait)
+	}
+
+	l.mu.Lock()
+	batches := l.pending
+	l.pending = nil
+	// the lookups get copies of the forms, since the forms are sanitized by the lookups while the fields read them
+	forms := make(map[*rateBatch]*exchange_rate.CurrencyExchangeRateForm, len(batches))
+	for _, b := range batches {
+		b.dispatched = true
+		form := *b.form
+		form.TargetCurrencies = append([]string(nil), b.form.TargetCurrencies...)
+		forms[b] = &form
+	}
+	l.mu.Unlock()
+
+	sort.Slice(batches, func(i, j int) bool {
+		return batches[i].key < batches[j].key
+	})
+	for _, b := range batches {
+		component, _ := components.ComponentMap["CurrencyExchangeRate"](l.base).(exchange_rate.CurrencyExchangeRate)
+		b.resp, b.err = component.GetCurrencyExchangeRate(forms[b])
+		close(b.done)
+	}
+}
+
+func containsCurrency(currencyCodes []string, currencyCode string) bool {
+	for _, code := range currencyCodes {
+		if code == currencyCode {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsCurrencies(currencyCodes, targetCurrencies []string) bool {
+	for _, currencyCode := range targetCurrencies {
+		if !containsCurrency(currencyCodes, currencyCode) {
+			return false
+		}
+	}
+
+	return true
+}
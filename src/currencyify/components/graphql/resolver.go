@@ -0,0 +1,210 @@
+package graphql
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"currencyify/components"
+	"currencyify/components/audit"
+	"currencyify/components/convert"
+	"currencyify/components/exchange_rate"
+	"currencyify/constants"
+	"currencyify/utils"
+)
+
+type resolver struct{}
+
+type exchangeRatesResolver struct {
+	Base string
+	Date *string
+
+	rates func() (map[string]exchange_rate.Currency, error)
+	order []string
+}
+
+type rateResolver struct {
+	Currency       string
+	Rate           float64
+	Bid            float64
+	Ask            float64
+	Mid            float64
+	LastUpdateTime string
+}
+
+type conversionResolver struct {
+	From   string
+	To     string
+	Amount float64
+
+	ctx   context.Context
+	rates func() (map[string]exchange_rate.Currency, error)
+	// audited records the conversion in the audit log once, whichever of its fields is resolved first.
+	audited sync.Once
+}
+
+// auditLogger gets the logger of the audit records of the conversions, nil when the audit log isn't configured.
+var auditLogger = audit.DefaultLogger
+
+func (r *resolver) Currencies(ctx context.Context) ([]string, error) {
+	base := &components.BaseComponent{
+		ReqCtx:   ctx,
+		AppError: new(utils.AppError),
+	}
+	component, _ := components.ComponentMap["CurrencyExchangeRate"](base).(exchange_rate.CurrencyExchangeRate)
+
+	return component.ListCurrencies()
+}
+
+func (r *resolver) Rates(ctx context.Context, args struct {
+	Base    string
+	Symbols []string
+	Date    *string
+}) (*exchangeRatesResolver, error) {
+	form := &exchange_rate.CurrencyExchangeRateForm{
+		BaseCurrency:     args.Base,
+		TargetCurrencies: args.Symbols,
+	}
+	if args.Date != nil {
+		form.Date = *args.Date
+	}
+	if err := form.Valid(); err != nil {
+		return nil, err
+	}
+
+	return &exchangeRatesResolver{
+		Base:  form.BaseCurrency,
+		Date:  args.Date,
+		rates: loaderFrom(ctx).load(form.BaseCurrency, form.TargetCurrencies, form.Date),
+		order: form.TargetCurrencies,
+	}, nil
+}
+
+func (r *resolver) Convert(ctx context.Context, args struct {
+	From   string
+	To     string
+	Amount float64
+}) (*conversionResolver, error) {
+	form := &convert.CurrencyConverterForm{
+		SourceCurrency: args.From,
+		TargetCurrency: args.To,
+		Amount:         args.Amount,
+	}
+	if err := form.Valid(); err != nil {
+		return nil, err
+	}
+
+	return &conversionResolver{
+		From:   form.SourceCurrency,
+		To:     form.TargetCurrency,
+		Amount: form.Amount,
+		ctx:    ctx,
+		rates:  loaderFrom(ctx).load(form.SourceCurrency, []string{form.TargetCurrency}, ""),
+	}, nil
+}
+
+func (er *exchangeRatesResolver) Rates() ([]*rateResolver, error) {
+	rates, err := er.rates()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*rateResolver, 0, len(er.order))
+	for _, currencyCode := range er.order {
+		rate, err := newRateResolver(currencyCode, rates[currencyCode])
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, rate)
+	}
+
+	return result, nil
+}
+
+func (cr *conversionResolver) Rate() (float64, error) {
+	rate, err := cr.rate()
+	if err != nil {
+		return 0, err
+	}
+
+	return rate.Rate, nil
+}
+
+func (cr *conversionResolver) ConvertedAmount() (float64, error) {
+	rate, err := cr.rate()
+	if err != nil {
+		return 0, err
+	}
+
+	return cr.Amount * rate.Rate, nil
+}
+
+func (cr *conversionResolver) LastUpdateTime() (string, error) {
+	rate, err := cr.rate()
+	if err != nil {
+		return "", err
+	}
+
+	return rate.LastUpdateTime, nil
+}
+
+func (cr *conversionResolver) rate() (*rateResolver, error) {
+	rates, err := cr.rates()
+	if err != nil {
+		return nil, err
+	}
+
+	rate, err := newRateResolver(cr.To, rates[cr.To])
+	if err != nil {
+		return nil, err
+	}
+	cr.audited.Do(func() {
+		cr.audit(rate, rates[cr.To].LastUpdateTime)
+	})
+
+	return rate, nil
+}
+
+// audit queues the audit record of the conversion served using the given rate. It doesn't wait for the record to be
+// written.
+func (cr *conversionResolver) audit(rate *rateResolver, rateTimestamp time.Time) {
+	logger := auditLogger()
+	if logger == nil {
+		return
+	}
+
+	logger.Log(cr.ctx, audit.Record{
+		SourceCurrency:  cr.From,
+		TargetCurrency:  cr.To,
+		Amount:          cr.Amount,
+		Rate:            rate.Rate,
+		RateTimestamp:   rateTimestamp,
+		Provider:        utils.VendorProvider(constants.FX_RATES_API_URL),
+		SourceAmount:    cr.Amount,
+		ConvertedAmount: cr.Amount * rate.Rate,
+	})
+}
+
+func newRateResolver(currencyCode string, currency exchange_rate.Currency) (*rateResolver, error) {
+	r := &rateResolver{
+		Currency:       currencyCode,
+		LastUpdateTime: currency.LastUpdateTime.Format(time.RFC3339),
+	}
+
+	var err error
+	for value, rate := range map[*float64]string{&r.Rate: currency.CurrencyExchangeRate, &r.Bid: currency.Bid, &r.Ask: currency.Ask, &r.Mid: currency.Mid} {
+		if *value, err = strconv.ParseFloat(rate, 64); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+// loaderFrom gets the rate loader of the query from the given context.
+func loaderFrom(ctx context.Context) *rateLoader {
+	l, _ := ctx.Value(loaderCtxKey{}).(*rateLoader)
+
+	return l
+}
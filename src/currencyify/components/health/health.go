@@ -0,0 +1,165 @@
+package health
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"currencyify/components/registry"
+	"currencyify/constants"
+	"currencyify/utils"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// Statuses of the dependencies.
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// Statuses of the service.
+const (
+	StatusReady    = "ready"
+	StatusNotReady = "not_ready"
+)
+
+// defaultVendorMaxAge is the duration a successful vendor API call keeps the rates ready, by default.
+const defaultVendorMaxAge = time.Hour
+
+// Checker checks whether the dependencies of the service are ready to serve the requests.
+type Checker struct {
+	// RedisConn opens the redis connection holding the cached rates and the vendor API calls.
+	RedisConn func() (redis.Conn, error)
+	// Registry gets the currency registry loaded at startup.
+	Registry func() (*registry.Registry, error)
+	// Now gets the current time.
+	Now func() time.Time
+}
+
+type Report struct {
+	// Status is `ready` when all the dependencies are up, `not_ready` otherwise.
+	Status string `json:"status"`
+	Checks Checks `json:"checks"`
+}
+
+type Checks struct {
+	Redis            Check            `json:"redis"`
+	CurrencyRegistry CurrencyRegistry `json:"currency_registry"`
+	Rates            Rates            `json:"rates"`
+}
+
+type Check struct {
+	// Status is `up` or `down`.
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type CurrencyRegistry struct {
+	Check
+	Currencies int `json:"currencies"`
+}
+
+type Rates struct {
+	Check
+	// Provider is the vendor API provider whose call succeeded last.
+	Provider      string     `json:"provider,omitempty"`
+	LastSuccessAt *time.Time `json:"last_success_at,omitempty"`
+	// CachedAt is the time the rates were last cached, nil when the cache doesn't hold non-expired rates.
+	CachedAt *time.Time `json:"cached_at,omitempty"`
+}
+
+// NewChecker is used to create a new checker of the dependencies of the service.
+// It returns the checker instance.
+func NewChecker() *Checker {
+	return &Checker{RedisConn: utils.Conn, Registry: registry.DefaultRegistry, Now: time.Now}
+}
+
+// Ready is used to check whether the service is ready to serve the requests: redis is reachable, the currency registry
+// is loaded, and the rates can be served, as a vendor API call succeeded within `READINESS_VENDOR_MAX_AGE`, 1 hour by
+// default, or else the cache holds non-expired rates.
+// It returns the report of the checks.
+func (c *Checker) Ready() Report {
+	report := Report{Status: StatusReady}
+
+	conn, err := c.RedisConn()
+	if err == nil {
+		defer func() {
+			if err := conn.Close(); err != nil {
+				slog.Error("error closing redis connection", "error", err)
+			}
+		}()
+		_, err = conn.Do("PING")
+	}
+	report.Checks.Redis = newCheck(err)
+	report.Checks.CurrencyRegistry = c.checkCurrencyRegistry()
+	if err != nil {
+		report.Checks.Rates.Check = newCheck(errors.New("redis is unreachable"))
+	} else {
+		report.Checks.Rates = c.checkRates(conn)
+	}
+
+	for _, check := range []Check{report.Checks.Redis, report.Checks.CurrencyRegistry.Check, report.Checks.Rates.Check} {
+		if check.Status != StatusUp {
+			report.Status = StatusNotReady
+		}
+	}
+
+	return report
+}
+
+// checkCurrencyRegistry is used to check that the currency registry is loaded, reporting the in-memory registry the
+// requests are validated against rather than re-reading its file.
+func (c *Checker) checkCurrencyRegistry() CurrencyRegistry {
+	currencies, err := c.Registry()
+	if err != nil {
+		return CurrencyRegistry{Check: newCheck(err)}
+	}
+
+	return CurrencyRegistry{Check: newCheck(nil), Currencies: currencies.Len()}
+}
+
+// checkRates is used to check that the rates can be served, fetched from a vendor API or else from the cache.
+func (c *Checker) checkRates(conn redis.Conn) Rates {
+	maxAge := defaultVendorMaxAge
+	if constants.READINESS_VENDOR_MAX_AGE != "" {
+		if d, err := time.ParseDuration(constants.READINESS_VENDOR_MAX_AGE); err == nil && d > 0 {
+			maxAge = d
+		} else {
+			slog.Warn("Error parsing duration READINESS_VENDOR_MAX_AGE", "value", constants.READINESS_VENDOR_MAX_AGE)
+		}
+	}
+
+	var rates Rates
+	provider, lastSuccessAt, err := utils.LastVendorSuccess(conn)
+	if err != nil {
+		return Rates{Check: newCheck(err)}
+	} else if !lastSuccessAt.IsZero() {
+		rates.Provider, rates.LastSuccessAt = provider, &lastSuccessAt
+	}
+
+	cachedAt, err := utils.GetRatesCachedAt(conn)
+	if err != nil {
+		return Rates{Check: newCheck(err)}
+	} else if !cachedAt.IsZero() {
+		rates.CachedAt = &cachedAt
+	}
+
+	if rates.CachedAt == nil && (rates.LastSuccessAt == nil || c.Now().Sub(lastSuccessAt) > maxAge) {
+		rates.Check = newCheck(fmt.Errorf("no vendor API call succeeded in the last %v and the cache holds no rates", maxAge))
+	} else {
+		rates.Check = newCheck(nil)
+	}
+
+	return rates
+}
+
+// newCheck is used to get the check of the dependency, down with the given error if any.
+func newCheck(err error) Check {
+	if err != nil {
+		return Check{Status: StatusDown, Error: err.Error()}
+	}
+
+	return Check{Status: StatusUp}
+}
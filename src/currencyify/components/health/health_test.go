@@ -0,0 +1,143 @@
+package health
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"currencyify/components/registry"
+	"currencyify/utils"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChecker_Ready(t *testing.T) {
+	now := time.Date(2026, time.March, 31, 12, 0, 0, 0, time.UTC)
+
+	type vars struct {
+		redisErr    error
+		registryErr error
+		successAt   time.Time
+		ratesCached bool
+	}
+
+	testCases := []struct {
+		name string
+
+		vars vars
+
+		wantStatus   string
+		wantRedis    string
+		wantRegistry string
+		wantRates    string
+		wantErr      string
+	}{
+		{
+			name: "should be ready when a vendor API call succeeded recently",
+			vars: vars{
+				successAt: now.Add(-10 * time.Minute),
+			},
+			wantStatus:   StatusReady,
+			wantRedis:    StatusUp,
+			wantRegistry: StatusUp,
+			wantRates:    StatusUp,
+		},
+		{
+			name: "should be ready when the cache holds rates, though no vendor API call succeeded recently",
+			vars: vars{
+				successAt:   now.Add(-2 * time.Hour),
+				ratesCached: true,
+			},
+			wantStatus:   StatusReady,
+			wantRedis:    StatusUp,
+			wantRegistry: StatusUp,
+			wantRates:    StatusUp,
+		},
+		{
+			name: "should not be ready when no vendor API call succeeded recently and the cache holds no rates",
+			vars: vars{
+				successAt: now.Add(-2 * time.Hour),
+			},
+			wantStatus:   StatusNotReady,
+			wantRedis:    StatusUp,
+			wantRegistry: StatusUp,
+			wantRates:    StatusDown,
+			wantErr:      "no vendor API call succeeded in the last 1h0m0s and the cache holds no rates",
+		},
+		{
+			name: "should not be ready when redis is unreachable",
+			vars: vars{
+				redisErr: errors.New("connection refused"),
+			},
+			wantStatus:   StatusNotReady,
+			wantRedis:    StatusDown,
+			wantRegistry: StatusUp,
+			wantRates:    StatusDown,
+			wantErr:      "redis is unreachable",
+		},
+		{
+			name: "should not be ready when the currency registry isn't loaded",
+			vars: vars{
+				registryErr: errors.New("open missing_currency_codes.json: no such file or directory"),
+				ratesCached: true,
+			},
+			wantStatus:   StatusNotReady,
+			wantRedis:    StatusUp,
+			wantRegistry: StatusDown,
+			wantRates:    StatusUp,
+		},
+	}
+
+	currencies, err := registry.LoadRegistry("../../currency_codes.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tCase := range testCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			// Setup
+			conn := utils.NewMockRedisConn()
+			if !tCase.vars.successAt.IsZero() {
+				_, _ = conn.Do("HSET", "vendor-usage:successes", "api.fxratesapi.com", tCase.vars.successAt.Format(time.RFC3339))
+			}
+			if tCase.vars.ratesCached {
+				_, _ = utils.SetRateData(conn, "INR", "cached", 3600)
+			}
+			checker := &Checker{
+				RedisConn: func() (redis.Conn, error) {
+					if tCase.vars.redisErr != nil {
+						return nil, tCase.vars.redisErr
+					}
+					return conn, nil
+				},
+				Registry: func() (*registry.Registry, error) {
+					if tCase.vars.registryErr != nil {
+						return nil, tCase.vars.registryErr
+					}
+					return currencies, nil
+				},
+				Now: func() time.Time {
+					return now
+				},
+			}
+
+			// Run test
+			report := checker.Ready()
+
+			// Assert
+			assert.Equal(t, tCase.wantStatus, report.Status, "case: %v", tCase)
+			assert.Equal(t, tCase.wantRedis, report.Checks.Redis.Status, "case: %v", tCase)
+			assert.Equal(t, tCase.wantRegistry, report.Checks.CurrencyRegistry.Status, "case: %v", tCase)
+			assert.Equal(t, tCase.wantRates, report.Checks.Rates.Status, "case: %v", tCase)
+			if tCase.wantErr != "" {
+				assert.Equal(t, tCase.wantErr, report.Checks.Rates.Error, "case: %v", tCase)
+			}
+			if tCase.wantRegistry == StatusUp {
+				assert.Equal(t, currencies.Len(), report.Checks.CurrencyRegistry.Currencies, "case: %v", tCase)
+			} else {
+				assert.Equal(t, tCase.vars.registryErr.Error(), report.Checks.CurrencyRegistry.Error, "case: %v", tCase)
+			}
+		})
+	}
+}
@@ -0,0 +1,401 @@
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"currencyify/constants"
+	"currencyify/utils"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// Tiers of the clients. Clients having no tier are in the default tier, and anonymous clients are identified by IP.
+// Tiers not having their own limits or quotas get the ones of the default tier.
+const (
+	TierDefault   = "default"
+	TierAnonymous = "anonymous"
+)
+
+const (
+	bucketKeyPrefix = "rate-limit:bucket"
+	quotaKeyPrefix  = "rate-limit:quota"
+)
+
+// maxTxAttempts is the number of attempts to take a token when the bucket is concurrently updated by other instances.
+const maxTxAttempts = 5
+
+// Limit is the token bucket holding up to `Burst` tokens, refilled at `Burst` tokens per `Period`. Each request takes
+// a token.
+type Limit struct {
+	Burst  int
+	Period time.Duration
+}
+
+// Quota is the number of requests allowed per UTC day and month, 0 for unlimited.
+type Quota struct {
+	Daily   int
+	Monthly int
+}
+
+type Config struct {
+	// Limits maps the tiers to the limits of the route path prefixes, "" being the limit of the other routes.
+	Limits map[string]map[string]Limit
+	Quotas map[string]Quota
+}
+
+// Result is the outcome of taking a token for the request.
+type Result struct {
+	Allowed bool
+	// Limit is the burst of the bucket, 0 when the route isn't rate limited.
+	Limit     int
+	Remaining int
+	// Reset is the time till the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is the time till the request can be retried when it isn't allowed.
+	RetryAfter time.Duration
+	// Reason tells the limit or quota exceeded when the request isn't allowed.
+	Reason string
+}
+
+// Limiter limits the requests of the clients using the token buckets and quota counters stored in redis, so the limits
+// are shared by all the instances.
+type Limiter struct {
+	// RedisConn opens the redis connection holding the buckets and quota counters.
+	RedisConn func() (redis.Conn, error)
+	Config    Config
+
+	now func() time.Time
+}
+
+var (
+	defaultLimiter     *Limiter
+	defaultLimiterOnce sync.Once
+)
+
+// NewLimiter is used to create a new limiter having the given config.
+// It returns the limiter instance.
+func NewLimiter(redisConn func() (redis.Conn, error), config Config) *Limiter {
+	return &Limiter{RedisConn: redisConn, Config: config, now: time.Now}
+}
+
+// DefaultLimiter is used to get the limiter configured by the `RATE_LIMIT*` env vars, created on first use.
+// It returns the limiter, or nil when neither limits nor quotas are configured.
+func DefaultLimiter() *Limiter {
+	defaultLimiterOnce.Do(func() {
+		if constants.RATE_LIMITS == "" && constants.RATE_LIMIT_DAILY_QUOTAS == "" && constants.RATE_LIMIT_MONTHLY_QUOTAS == "" {
+			return
+		}
+
+		limits, err := ParseLimits(constants.RATE_LIMITS)
+		if err != nil {
+			slog.Warn("rate limiting is disabled, error parsing RATE_LIMITS", "error", err)
+			return
+		}
+		daily, err := ParseQuotas(constants.RATE_LIMIT_DAILY_QUOTAS)
+		if err != nil {
+			slog.Warn("rate limiting is disabled, error parsing RATE_LIMIT_DAILY_QUOTAS", "error", err)
+			return
+		}
+		monthly, err := ParseQuotas(constants.RATE_LIMIT_MONTHLY_QUOTAS)
+		if err != nil {
+			slog.Warn("rate limiting is disabled, error parsing RATE_LIMIT_MONTHLY_QUOTAS", "error", err)
+			return
+		}
+
+		quotas := make(map[string]Quota)
+		for tier, count := range daily {
+			quota := quotas[tier]
+			quota.Daily = count
+			quotas[tier] = quota
+		}
+		for tier, count := range monthly {
+			quota := quotas[tier]
+			quota.Monthly = count
+			quotas[tier] = quota
+		}
+
+		defaultLimiter = NewLimiter(utils.Conn, Config{Limits: limits, Quotas: quotas})
+	})
+
+	return defaultLimiter
+}
+
+// ParseLimits is used to parse the comma separated limits of the tiers, i.e. `default:60/1m,default:/convert:30/1m`,
+// each being the tier, the optional route path prefix, and the burst per period.
+// It returns the limits of the tiers and error.
+func ParseLimits(value string) (map[string]map[string]Limit, error) {
+	limits := make(map[string]map[string]Limit)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+
+		parts := strings.Split(item, ":")
+		tier, route, limitStr := parts[0], "", parts[len(parts)-1]
+		if len(parts) == 3 && strings.HasPrefix(parts[1], "/") {
+			route = strings.TrimSuffix(parts[1], "/")
+		} else if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid limit (%s), it should be `tier:burst/period` or `tier:/route:burst/period`", item)
+		}
+
+		burstStr, periodStr, _ := strings.Cut(limitStr, "/")
+		burst, err := strconv.Atoi(burstStr)
+		if err != nil || burst <= 0 {
+			return nil, fmt.Errorf("invalid burst of the limit (%s), it should be a positive integer", item)
+		}
+		period, err := time.ParseDuration(periodStr)
+		if err != nil || period <= 0 {
+			return nil, fmt.Errorf("invalid period of the limit (%s), it should be a positive duration, i.e. 1m", item)
+		}
+
+		if _, ok := limits[tier]; !ok {
+			limits[tier] = make(map[string]Limit)
+		}
+		limits[tier][route] = Limit{Burst: burst, Period: period}
+	}
+
+	return limits, nil
+}
+
+// ParseQuotas is used to parse the comma separated quotas of the tiers, i.e. `default:1000,premium:100000`.
+// It returns the quotas of the tiers and error.
+func ParseQuotas(value string) (map[string]int, error) {
+	quotas := make(map[string]int)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+
+		tier, countStr, _ := strings.Cut(item, ":")
+		count, err := strconv.Atoi(countStr)
+		if err != nil || count <= 0 {
+			return nil, fmt.Errorf("invalid quota (%s), it should be `tier:count` having a positive count", item)
+		}
+		quotas[tier] = count
+	}
+
+	return quotas, nil
+}
+
+// Allow is used to take a token from the bucket of the client for the given path, and to count the request against
+// the daily and monthly quotas of the client when a token is taken.
+// It returns the result and error.
+func (l *Limiter) Allow(client, tier, path string) (*Result, error) {
+	return l.check(client, tier, path, true)
+}
+
+// Peek is used to check whether the client has a token in its bucket for the given path and quota left, without taking
+// the token or counting the request against the quotas.
+// It returns the result and error.
+func (l *Limiter) Peek(client, tier, path string) (*Result, error) {
+	return l.check(client, tier, path, false)
+}
+
+// check is used to check the bucket and quotas of the client for the given path, taking the token and counting the
+// request when take is set.
+// It returns the result and error.
+func (l *Limiter) check(client, tier, path string, take bool) (*Result, error) {
+	if tier == "" {
+		tier = TierDefault
+	}
+
+	conn, err := l.RedisConn()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := conn.Close(); err != nil {
+			slog.Error("error closing redis connection", "error", err)
+		}
+	}()
+
+	now := l.now()
+	result := &Result{Allowed: true}
+	if route, limit, ok := l.limit(tier, path); ok {
+		if route == "" {
+			route = "*"
+		}
+		key := fmt.Sprintf("%s:%s:%s", bucketKeyPrefix, client, route)
+		if take {
+			result, err = takeToken(conn, key, limit, now)
+		} else {
+			result, err = peekToken(conn, key, limit, now)
+		}
+		if err != nil || !result.Allowed {
+			return result, err
+		}
+	}
+
+	quota, ok := l.Config.Quotas[tier]
+	if !ok {
+		quota = l.Config.Quotas[TierDefault]
+	}
+
+	now = now.UTC()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	windows := []struct {
+		name  string
+		count int
+		key   string
+		end   time.Time
+	}{
+		{name: "daily", count: quota.Daily, key: day.Format("2006-01-02"), end: day.AddDate(0, 0, 1)},
+		{name: "monthly", count: quota.Monthly, key: month.Format("2006-01"), end: month.AddDate(0, 1, 0)},
+	}
+	for _, window := range windows {
+		if window.count <= 0 {
+			continue
+		}
+
+		key := fmt.Sprintf("%s:%s:%s", quotaKeyPrefix, client, window.key)
+		if !take {
+			count, err := redis.Int(conn.Do("GET", key))
+			if err != nil && !errors.Is(err, redis.ErrNil) {
+				return nil, errors.New("failed to get request count from Redis")
+			}
+			if count >= window.count {
+				result.Allowed = false
+				result.RetryAfter = window.end.Sub(now)
+				result.Reason = fmt.Sprintf("%s quota of %d requests exceeded", window.name, window.count)
+				return result, nil
+			}
+			continue
+		}
+
+		count, err := redis.Int(conn.Do("INCR", key))
+		if err != nil {
+			return nil, errors.New("failed to count request in Redis")
+		}
+		if count == 1 {
+			if _, err = conn.Do("EXPIREAT", key, window.end.Unix()); err != nil {
+				return nil, errors.New("failed to set quota expiry in Redis")
+			}
+		}
+
+		if count > window.count {
+			result.Allowed = false
+			result.RetryAfter = window.end.Sub(now)
+			result.Reason = fmt.Sprintf("%s quota of %d requests exceeded", window.name, window.count)
+			return result, nil
+		}
+	}
+
+	return result, nil
+}
+
+// limit is used to find the limit of the given tier for the path, matching the longest route path prefix, and falling
+// back to the limits of the default tier.
+// It returns the route path prefix, the limit and whether the path is rate limited.
+func (l *Limiter) limit(tier, path string) (string, Limit, bool) {
+	routes, ok := l.Config.Limits[tier]
+	if !ok {
+		routes = l.Config.Limits[TierDefault]
+	}
+
+	matched, found := "", false
+	for route := range routes {
+		if route != "" && path != route && !strings.HasPrefix(path, route+"/") {
+			continue
+		}
+		if !found || len(route) > len(matched) {
+			matched, found = route, true
+		}
+	}
+
+	return matched, routes[matched], found
+}
+
+// peekToken is used to check whether the bucket having the given key has a token, refilled for the time elapsed since
+// its last update, without taking it.
+// It returns the result and error.
+func peekToken(conn redis.Conn, key string, limit Limit, now time.Time) (*Result, error) {
+	values, err := redis.Strings(conn.Do("HMGET", key, "tokens", "updated_at"))
+	if err != nil {
+		return nil, errors.New("failed to get rate limit bucket from Redis")
+	}
+
+	result, _ := bucketResult(values, limit, now)
+
+	return result, nil
+}
+
+// takeToken is used to take a token from the bucket having the given key, refilling it for the time elapsed since its
+// last update. The bucket is updated in a transaction watching it, retried when another request updates it meanwhile.
+// It returns the result and error.
+func takeToken(conn redis.Conn, key string, limit Limit, now time.Time) (*Result, error) {
+	rate := float64(limit.Burst) / limit.Period.Seconds()
+
+	for attempt := 0; attempt < maxTxAttempts; attempt++ {
+		if _, err := conn.Do("WATCH", key); err != nil {
+			return nil, errors.New("failed to watch rate limit bucket in Redis")
+		}
+		values, err := redis.Strings(conn.Do("HMGET", key, "tokens", "updated_at"))
+		if err != nil {
+			return nil, errors.New("failed to get rate limit bucket from Redis")
+		}
+
+		result, tokens := bucketResult(values, limit, now)
+		if !result.Allowed {
+			if _, err = conn.Do("UNWATCH"); err != nil {
+				return nil, errors.New("failed to unwatch rate limit bucket in Redis")
+			}
+			return result, nil
+		}
+
+		tokens--
+		result.Remaining = int(tokens)
+		result.Reset = seconds((float64(limit.Burst) - tokens) / rate)
+
+		// The bucket is full again after the period, so it is dropped then.
+		_ = conn.Send("MULTI")
+		_ = conn.Send("HSET", key, "tokens", strconv.FormatFloat(tokens, 'f', -1, 64), "updated_at", now.UnixMilli())
+		_ = conn.Send("PEXPIRE", key, limit.Period.Milliseconds())
+		reply, err := conn.Do("EXEC")
+		if err != nil {
+			return nil, errors.New("failed to set rate limit bucket in Redis")
+		}
+		if reply != nil {
+			return result, nil
+		}
+	}
+
+	return nil, errors.New("failed to set rate limit bucket in Redis, it is updated concurrently")
+}
+
+// bucketResult is used to get the result of the bucket having the given stored tokens and update time, refilled for the
+// time elapsed since. A missing bucket is full.
+// It returns the result and the tokens in the bucket.
+func bucketResult(values []string, limit Limit, now time.Time) (*Result, float64) {
+	rate := float64(limit.Burst) / limit.Period.Seconds()
+
+	tokens := float64(limit.Burst)
+	if stored, err := strconv.ParseFloat(values[0], 64); err == nil {
+		if updatedAt, err := strconv.ParseInt(values[1], 10, 64); err == nil {
+			elapsed := math.Max(now.Sub(time.UnixMilli(updatedAt)).Seconds(), 0)
+			tokens = math.Min(stored+elapsed*rate, float64(limit.Burst))
+		}
+	}
+
+	result := &Result{Allowed: tokens >= 1, Limit: limit.Burst, Remaining: int(tokens)}
+	result.Reset = seconds((float64(limit.Burst) - tokens) / rate)
+	if !result.Allowed {
+		result.Remaining = 0
+		result.RetryAfter = seconds((1 - tokens) / rate)
+		result.Reason = fmt.Sprintf("rate limit of %d requests per %s exceeded", limit.Burst, limit.Period)
+	}
+
+	return result, tokens
+}
+
+// seconds converts the given number of seconds to duration.
+func seconds(value float64) time.Duration {
+	return time.Duration(value * float64(time.Second))
+}
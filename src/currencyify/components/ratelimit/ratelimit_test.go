@@ -0,0 +1,301 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"currencyify/utils"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLimits(t *testing.T) {
+	testCases := []struct {
+		name string
+
+		value string
+
+		want   map[string]map[string]Limit
+		hasErr bool
+		err    string
+	}{
+		{
+			name:  "should parse the limits of the tiers and routes",
+			value: "default:60/1m, default:/convert/:30/1m,premium:10/1s",
+			want: map[string]map[string]Limit{
+				"default": {"": {Burst: 60, Period: time.Minute}, "/convert": {Burst: 30, Period: time.Minute}},
+				"premium": {"": {Burst: 10, Period: time.Second}},
+			},
+		},
+		{
+			name:  "should success for empty limits",
+			value: "",
+			want:  map[string]map[string]Limit{},
+		},
+		{
+			name:   "should fail when the route isn't a path",
+			value:  "default:convert:30/1m",
+			hasErr: true,
+			err:    "invalid limit (default:convert:30/1m)",
+		},
+		{
+			name:   "should fail for the invalid burst",
+			value:  "default:0/1m",
+			hasErr: true,
+			err:    "invalid burst of the limit (default:0/1m)",
+		},
+		{
+			name:   "should fail for the invalid period",
+			value:  "default:60/minute",
+			hasErr: true,
+			err:    "invalid period of the limit (default:60/minute)",
+		},
+	}
+
+	for _, tCase := range testCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			// Run test
+			got, err := ParseLimits(tCase.value)
+
+			// Assert
+			if tCase.hasErr {
+				if assert.Errorf(t, err, "case: %v", tCase) {
+					assert.Containsf(t, err.Error(), tCase.err, "case: %v", tCase)
+				}
+			} else {
+				assert.NoErrorf(t, err, "case: %v", tCase)
+				assert.Equal(t, tCase.want, got, "case: %v", tCase)
+			}
+		})
+	}
+}
+
+func TestParseQuotas(t *testing.T) {
+	testCases := []struct {
+		name string
+
+		value string
+
+		want   map[string]int
+		hasErr bool
+		err    string
+	}{
+		{
+			name:  "should parse the quotas of the tiers",
+			value: "default:1000, premium:100000",
+			want:  map[string]int{"default": 1000, "premium": 100000},
+		},
+		{
+			name:   "should fail for the invalid count",
+			value:  "default:many",
+			hasErr: true,
+			err:    "invalid quota (default:many)",
+		},
+	}
+
+	for _, tCase := range testCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			// Run test
+			got, err := ParseQuotas(tCase.value)
+
+			// Assert
+			if tCase.hasErr {
+				if assert.Errorf(t, err, "case: %v", tCase) {
+					assert.Containsf(t, err.Error(), tCase.err, "case: %v", tCase)
+				}
+			} else {
+				assert.NoErrorf(t, err, "case: %v", tCase)
+				assert.Equal(t, tCase.want, got, "case: %v", tCase)
+			}
+		})
+	}
+}
+
+func TestLimiter_Allow(t *testing.T) {
+	config := Config{
+		Limits: map[string]map[string]Limit{
+			TierDefault: {"": {Burst: 2, Period: time.Minute}, "/convert": {Burst: 1, Period: time.Minute}},
+			"premium":   {"": {Burst: 3, Period: time.Second}},
+		},
+		Quotas: map[string]Quota{
+			TierDefault: {Daily: 3, Monthly: 4},
+		},
+	}
+	start := time.Date(2026, time.March, 30, 23, 59, 0, 0, time.UTC)
+
+	type request struct {
+		client  string
+		tier    string
+		path    string
+		elapsed time.Duration
+	}
+
+	testCases := []struct {
+		name string
+
+		config   Config
+		requests []request
+
+		want *Result
+	}{
+		{
+			name:     "should allow the request taking a token from the full bucket",
+			config:   config,
+			requests: []request{{client: "a", path: "/exchange-rate/currency-exchange-rate"}},
+			want:     &Result{Allowed: true, Limit: 2, Remaining: 1, Reset: 30 * time.Second},
+		},
+		{
+			name:   "should reject the request when the bucket is empty",
+			config: config,
+			requests: []request{
+				{client: "a", path: "/exchange-rate/currency-exchange-rate"},
+				{client: "a", path: "/exchange-rate/currency-exchange-rate"},
+				{client: "a", path: "/exchange-rate/currency-exchange-rate", elapsed: 15 * time.Second},
+			},
+			want: &Result{Limit: 2, Reset: 45 * time.Second, RetryAfter: 15 * time.Second, Reason: "rate limit of 2 requests per 1m0s exceeded"},
+		},
+		{
+			name:   "should allow the request when the bucket is refilled",
+			config: config,
+			requests: []request{
+				{client: "a", path: "/exchange-rate/currency-exchange-rate"},
+				{client: "a", path: "/exchange-rate/currency-exchange-rate"},
+				{client: "a", path: "/exchange-rate/currency-exchange-rate", elapsed: 30 * time.Second},
+			},
+			want: &Result{Allowed: true, Limit: 2, Remaining: 0, Reset: time.Minute},
+		},
+		{
+			name:   "should use the bucket of the route having its own limit",
+			config: config,
+			requests: []request{
+				{client: "a", path: "/exchange-rate/currency-exchange-rate"},
+				{client: "a", path: "/convert/currency-convert"},
+				{client: "a", path: "/convert/portfolio-valuation"},
+			},
+			want: &Result{Limit: 1, Reset: time.Minute, RetryAfter: time.Minute, Reason: "rate limit of 1 requests per 1m0s exceeded"},
+		},
+		{
+			name:   "should limit the clients separately",
+			config: config,
+			requests: []request{
+				{client: "a", path: "/convert/currency-convert"},
+				{client: "b", path: "/convert/currency-convert"},
+			},
+			want: &Result{Allowed: true, Limit: 1, Remaining: 0, Reset: time.Minute},
+		},
+		{
+			name:   "should use the limits of the tier",
+			config: config,
+			requests: []request{
+				{client: "a", tier: "premium", path: "/convert/currency-convert"},
+			},
+			want: &Result{Allowed: true, Limit: 3, Remaining: 2, Reset: time.Second / 3},
+		},
+		{
+			name:   "should reject the request when the daily quota is exceeded",
+			config: Config{Quotas: config.Quotas},
+			requests: []request{
+				{client: "a", path: "/convert/currency-convert"},
+				{client: "a", path: "/convert/currency-convert"},
+				{client: "a", path: "/convert/currency-convert"},
+				{client: "a", path: "/convert/currency-convert"},
+			},
+			want: &Result{RetryAfter: time.Minute, Reason: "daily quota of 3 requests exceeded"},
+		},
+		{
+			name:   "should reject the request when the monthly quota is exceeded",
+			config: Config{Quotas: config.Quotas},
+			requests: []request{
+				{client: "a", path: "/convert/currency-convert"},
+				{client: "a", path: "/convert/currency-convert"},
+				{client: "a", path: "/convert/currency-convert"},
+				{client: "a", path: "/convert/currency-convert", elapsed: 2 * time.Minute},
+				{client: "a", path: "/convert/currency-convert"},
+			},
+			want: &Result{RetryAfter: 24*time.Hour - time.Minute, Reason: "monthly quota of 4 requests exceeded"},
+		},
+	}
+
+	for _, tCase := range testCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			// Setup
+			conn := utils.NewMockRedisConn()
+			now := start
+			limiter := NewLimiter(func() (redis.Conn, error) {
+				return conn, nil
+			}, tCase.config)
+			limiter.now = func() time.Time {
+				return now
+			}
+
+			// Run test
+			var got *Result
+			var err error
+			for _, req := range tCase.requests {
+				now = now.Add(req.elapsed)
+				got, err = limiter.Allow(req.client, req.tier, req.path)
+			}
+
+			// Assert
+			assert.NoErrorf(t, err, "case: %v", tCase)
+			assert.Equal(t, tCase.want, got, "case: %v", tCase)
+		})
+	}
+}
+
+func TestLimiter_Peek(t *testing.T) {
+	config := Config{
+		Limits: map[string]map[string]Limit{
+			TierDefault: {"": {Burst: 2, Period: time.Minute}},
+		},
+		Quotas: map[string]Quota{
+			TierDefault: {Daily: 3},
+		},
+	}
+	start := time.Date(2026, time.March, 30, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name string
+
+		taken int
+
+		want *Result
+	}{
+		{
+			name:  "should allow the client having a token without taking it",
+			taken: 1,
+			want:  &Result{Allowed: true, Limit: 2, Remaining: 1, Reset: 30 * time.Second},
+		},
+		{
+			name:  "should reject the client having an empty bucket",
+			taken: 2,
+			want:  &Result{Limit: 2, Reset: time.Minute, RetryAfter: 30 * time.Second, Reason: "rate limit of 2 requests per 1m0s exceeded"},
+		},
+	}
+
+	for _, tCase := range testCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			// Setup
+			conn := utils.NewMockRedisConn()
+			limiter := NewLimiter(func() (redis.Conn, error) {
+				return conn, nil
+			}, config)
+			limiter.now = func() time.Time {
+				return start
+			}
+			for i := 0; i < tCase.taken; i++ {
+				_, _ = limiter.Allow("a", "", "/convert")
+			}
+
+			// Run test
+			got, err := limiter.Peek("a", "", "/convert")
+			again, _ := limiter.Peek("a", "", "/convert")
+
+			// Assert
+			assert.NoErrorf(t, err, "case: %v", tCase)
+			assert.Equal(t, tCase.want, got, "case: %v", tCase)
+			assert.Equal(t, got, again, "case: %v, peeking shouldn't take a token", tCase)
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package registry
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"currencyify/constants"
+)
+
+// Registry holds the supported international-standard 3-letter ISO currency codes. It is read-only once loaded, so it
+// is safe for concurrent use.
+type Registry struct {
+	codes map[string]int
+}
+
+var (
+	defaultRegistry     *Registry
+	defaultRegistryErr  error
+	defaultRegistryOnce sync.Once
+)
+
+// minorUnits holds the ISO 4217 minor units of the currencies not having 2 decimal places.
+var minorUnits = map[string]int{
+	"bif": 0, "clp": 0, "djf": 0, "gnf": 0, "isk": 0, "jpy": 0, "kmf": 0, "krw": 0, "pyg": 0,
+	"rwf": 0, "ugx": 0, "uyi": 0, "vnd": 0, "vuv": 0, "xaf": 0, "xof": 0, "xpf": 0,
+	"bhd": 3, "iqd": 3, "jod": 3, "kwd": 3, "lyd": 3, "omr": 3, "tnd": 3,
+	"clf": 4, "uyw": 4,
+}
+
+// LoadRegistry is used to load the registry from the given JSON file, mapping the lowercase currency codes to their IDs.
+// It returns the registry and error when the file can't be read, parsed or is empty.
+func LoadRegistry(fileName string) (*Registry, error) {
+	currencyCodesStr, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	currencyCodesMap := make(map[string]int)
+	if err = json.Unmarshal(currencyCodesStr, &currencyCodesMap); err != nil {
+		return nil, err
+	} else if len(currencyCodesMap) == 0 {
+		return nil, errors.New("currency registry is empty")
+	}
+
+	codes := make(map[string]int, len(currencyCodesMap))
+	for currencyCode, id := range currencyCodesMap {
+		codes[strings.ToLower(currencyCode)] = id
+	}
+
+	return &Registry{codes: codes}, nil
+}
+
+// DefaultRegistry is used to get the registry loaded from `CURRENCY_CODES_JSON_FILE_NAME`, loaded once on first use.
+// It returns the registry and error when it couldn't be loaded.
+func DefaultRegistry() (*Registry, error) {
+	defaultRegistryOnce.Do(func() {
+		defaultRegistry, defaultRegistryErr = LoadRegistry(constants.CURRENCY_CODES_JSON_FILE_NAME)
+	})
+
+	return defaultRegistry, defaultRegistryErr
+}
+
+// Has is used to check whether the given currency code, in any case, is supported.
+func (r *Registry) Has(currencyCode string) bool {
+	_, ok := r.codes[strings.ToLower(currencyCode)]
+
+	return ok
+}
+
+// Codes is used to list the supported currency codes.
+// It returns the sorted uppercase currency codes.
+func (r *Registry) Codes() []string {
+	currencyCodes := make([]string, 0, len(r.codes))
+	for currencyCode := range r.codes {
+		currencyCodes = append(currencyCodes, strings.ToUpper(currencyCode))
+	}
+	sort.Strings(currencyCodes)
+
+	return currencyCodes
+}
+
+// Len is used to get the number of the supported currencies.
+func (r *Registry) Len() int {
+	return len(r.codes)
+}
+
+// MinorUnits is used to get the number of decimal places of the given currency code, in any case, as per ISO 4217.
+// It returns 2 for the currencies not listed otherwise.
+func MinorUnits(currencyCode string) int {
+	if units, ok := minorUnits[strings.ToLower(currencyCode)]; ok {
+		return units
+	}
+
+	return 2
+}
@@ -0,0 +1,124 @@
+package registry
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadRegistry(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(name, content string) string {
+		fileName := filepath.Join(dir, name)
+		if err := os.WriteFile(fileName, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return fileName
+	}
+
+	testCases := []struct {
+		name string
+
+		fileName string
+
+		wantCodes []string
+		hasErr    bool
+		err       string
+	}{
+		{
+			name:      "should load the currency codes of the file",
+			fileName:  writeFile("codes.json", `{"usd": 1, "EUR": 2, "inr": 3}`),
+			wantCodes: []string{"EUR", "INR", "USD"},
+		},
+		{
+			name:     "should fail for the missing file",
+			fileName: filepath.Join(dir, "missing.json"),
+			hasErr:   true,
+		},
+		{
+			name:     "should fail for the invalid JSON",
+			fileName: writeFile("invalid.json", `["usd"]`),
+			hasErr:   true,
+		},
+		{
+			name:     "should fail for the empty registry",
+			fileName: writeFile("empty.json", `{}`),
+			hasErr:   true,
+			err:      "currency registry is empty",
+		},
+	}
+
+	for _, tCase := range testCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			// Run test
+			registry, err := LoadRegistry(tCase.fileName)
+
+			// Assert
+			if tCase.hasErr {
+				assert.Error(t, err, "case: %v", tCase.name)
+				if tCase.err != "" {
+					assert.EqualError(t, err, tCase.err, "case: %v", tCase.name)
+				}
+				return
+			}
+			assert.NoError(t, err, "case: %v", tCase.name)
+			assert.Equal(t, tCase.wantCodes, registry.Codes(), "case: %v", tCase.name)
+			assert.Equal(t, len(tCase.wantCodes), registry.Len(), "case: %v", tCase.name)
+			for _, currencyCode := range []string{"usd", "USD", "eur", "Inr"} {
+				assert.True(t, registry.Has(currencyCode), "case: %v", tCase.name)
+			}
+			assert.False(t, registry.Has("xyz"), "case: %v", tCase.name)
+		})
+	}
+}
+
+func TestMinorUnits(t *testing.T) {
+	testCases := []struct {
+		name string
+
+		currencyCode string
+
+		want int
+	}{
+		{name: "should get 2 decimal places of the currency having cents", currencyCode: "USD", want: 2},
+		{name: "should get no decimal places of the currency without minor units", currencyCode: "jpy", want: 0},
+		{name: "should get 3 decimal places of the currency having fils", currencyCode: "Kwd", want: 3},
+		{name: "should get 2 decimal places of the unlisted currency", currencyCode: "xyz", want: 2},
+	}
+
+	for _, tCase := range testCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			// Run test
+			got := MinorUnits(tCase.currencyCode)
+
+			// Assert
+			assert.Equal(t, tCase.want, got, "case: %v", tCase.name)
+		})
+	}
+}
+
+func TestRegistryConcurrentReads(t *testing.T) {
+	// Setup
+	registry, err := LoadRegistry("../../currency_codes.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Run test
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			registry.Has("usd")
+			registry.Codes()
+		}()
+	}
+	wg.Wait()
+
+	// Assert
+	assert.True(t, registry.Has("USD"))
+}
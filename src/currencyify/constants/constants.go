@@ -10,19 +10,119 @@ const (
 
 var (
 	FX_RATES_API_URL              = ""
+	FX_RATES_HISTORICAL_API_URL   = ""
+	FX_RATES_SPREAD_PERCENTAGE    = ""
 	CURRENCY_CODES_JSON_FILE_NAME = ""
+	HTTP_CLIENT_TIMEOUT           = ""
+
+	BATCH_CONVERT_MAX_ITEMS = ""
+
+	RATE_STREAM_POLL_INTERVAL = ""
+
+	GRPC_PORT = ""
+
+	AUTH_ENABLED = ""
+
+	JWT_JWKS_URL              = ""
+	JWT_JWKS_FILE             = ""
+	JWT_JWKS_REFRESH_INTERVAL = ""
+	JWT_ISSUER                = ""
+	JWT_AUDIENCE              = ""
+	JWT_SCOPES_CLAIM          = ""
+	JWT_SCOPE_MAPPING         = ""
+
+	SIGNATURE_MAX_SKEW = ""
+
+	VENDOR_CALL_BUDGET_PER_MINUTE = ""
+	VENDOR_CALL_BUDGET_PER_DAY    = ""
+	VENDOR_CALL_BUDGET_PER_MONTH  = ""
+
+	RATE_LIMITS               = ""
+	RATE_LIMIT_DAILY_QUOTAS   = ""
+	RATE_LIMIT_MONTHLY_QUOTAS = ""
+	TRUSTED_PROXIES           = ""
+
+	AUDIT_LOG_SINK        = ""
+	AUDIT_LOG_FILE        = ""
+	AUDIT_LOG_SQL_DRIVER  = ""
+	AUDIT_LOG_SQL_DSN     = ""
+	AUDIT_LOG_SQL_TABLE   = ""
+	AUDIT_LOG_BUFFER_SIZE = ""
 
 	REDIS_HOST           = ""
 	REDIS_PORT           = ""
 	REDIS_DEFAULT_EXPIRY = ""
+	STALE_CACHE_EXPIRY   = ""
+
+	REDIS_POOL_MAX_IDLE   = ""
+	REDIS_POOL_MAX_ACTIVE = ""
+
+	TRACING_EXPORTER      = ""
+	TRACING_OTLP_ENDPOINT = ""
+	TRACING_SAMPLE_RATIO  = ""
+
+	LOG_FORMAT = ""
+	LOG_LEVEL  = ""
+
+	READINESS_VENDOR_MAX_AGE = ""
 )
 
 func InitConstantsVars() {
 	FX_RATES_API_URL = os.Getenv("FX_RATES_API_URL")
+	FX_RATES_HISTORICAL_API_URL = os.Getenv("FX_RATES_HISTORICAL_API_URL")
+	FX_RATES_SPREAD_PERCENTAGE = os.Getenv("FX_RATES_SPREAD_PERCENTAGE")
 
 	CURRENCY_CODES_JSON_FILE_NAME = os.Getenv("CURRENCY_CODES_JSON_FILE_NAME")
+	HTTP_CLIENT_TIMEOUT = os.Getenv("HTTP_CLIENT_TIMEOUT")
+
+	BATCH_CONVERT_MAX_ITEMS = os.Getenv("BATCH_CONVERT_MAX_ITEMS")
+
+	RATE_STREAM_POLL_INTERVAL = os.Getenv("RATE_STREAM_POLL_INTERVAL")
+
+	GRPC_PORT = os.Getenv("GRPC_PORT")
+
+	AUTH_ENABLED = os.Getenv("AUTH_ENABLED")
+
+	JWT_JWKS_URL = os.Getenv("JWT_JWKS_URL")
+	JWT_JWKS_FILE = os.Getenv("JWT_JWKS_FILE")
+	JWT_JWKS_REFRESH_INTERVAL = os.Getenv("JWT_JWKS_REFRESH_INTERVAL")
+	JWT_ISSUER = os.Getenv("JWT_ISSUER")
+	JWT_AUDIENCE = os.Getenv("JWT_AUDIENCE")
+	JWT_SCOPES_CLAIM = os.Getenv("JWT_SCOPES_CLAIM")
+	JWT_SCOPE_MAPPING = os.Getenv("JWT_SCOPE_MAPPING")
+
+	SIGNATURE_MAX_SKEW = os.Getenv("SIGNATURE_MAX_SKEW")
+
+	VENDOR_CALL_BUDGET_PER_MINUTE = os.Getenv("VENDOR_CALL_BUDGET_PER_MINUTE")
+	VENDOR_CALL_BUDGET_PER_DAY = os.Getenv("VENDOR_CALL_BUDGET_PER_DAY")
+	VENDOR_CALL_BUDGET_PER_MONTH = os.Getenv("VENDOR_CALL_BUDGET_PER_MONTH")
+
+	RATE_LIMITS = os.Getenv("RATE_LIMITS")
+	RATE_LIMIT_DAILY_QUOTAS = os.Getenv("RATE_LIMIT_DAILY_QUOTAS")
+	RATE_LIMIT_MONTHLY_QUOTAS = os.Getenv("RATE_LIMIT_MONTHLY_QUOTAS")
+	TRUSTED_PROXIES = os.Getenv("TRUSTED_PROXIES")
+
+	AUDIT_LOG_SINK = os.Getenv("AUDIT_LOG_SINK")
+	AUDIT_LOG_FILE = os.Getenv("AUDIT_LOG_FILE")
+	AUDIT_LOG_SQL_DRIVER = os.Getenv("AUDIT_LOG_SQL_DRIVER")
+	AUDIT_LOG_SQL_DSN = os.Getenv("AUDIT_LOG_SQL_DSN")
+	AUDIT_LOG_SQL_TABLE = os.Getenv("AUDIT_LOG_SQL_TABLE")
+	AUDIT_LOG_BUFFER_SIZE = os.Getenv("AUDIT_LOG_BUFFER_SIZE")
 
 	REDIS_HOST = os.Getenv("REDIS_HOST")
 	REDIS_PORT = os.Getenv("REDIS_PORT")
 	REDIS_DEFAULT_EXPIRY = os.Getenv("REDIS_DEFAULT_EXPIRY")
+	STALE_CACHE_EXPIRY = os.Getenv("STALE_CACHE_EXPIRY")
+
+	REDIS_POOL_MAX_IDLE = os.Getenv("REDIS_POOL_MAX_IDLE")
+	REDIS_POOL_MAX_ACTIVE = os.Getenv("REDIS_POOL_MAX_ACTIVE")
+
+	TRACING_EXPORTER = os.Getenv("TRACING_EXPORTER")
+	TRACING_OTLP_ENDPOINT = os.Getenv("TRACING_OTLP_ENDPOINT")
+	TRACING_SAMPLE_RATIO = os.Getenv("TRACING_SAMPLE_RATIO")
+
+	LOG_FORMAT = os.Getenv("LOG_FORMAT")
+	LOG_LEVEL = os.Getenv("LOG_LEVEL")
+
+	READINESS_VENDOR_MAX_AGE = os.Getenv("READINESS_VENDOR_MAX_AGE")
 }
@@ -0,0 +1,35 @@
+package admin
+
+import (
+	"log/slog"
+	"net/http"
+
+	"currencyify/components/admin"
+	"currencyify/controllers"
+	"currencyify/utils"
+)
+
+type VendorUsageController struct {
+	controllers.BaseController
+	Component admin.VendorUsage
+}
+
+// UpdateComponent is used to update the component object.
+func (c *VendorUsageController) UpdateComponent(component interface{}) {
+	c.Component, _ = component.(admin.VendorUsage)
+}
+
+func (c *VendorUsageController) GetVendorUsage() {
+	var status int
+
+	d, err := c.Component.GetVendorUsage()
+	if err != nil {
+		status = c.ErrorStatus(err, c.Component.GetVendorUsageAppError())
+		slog.ErrorContext(c.ReqCtx, "Some error occurred", "error", err)
+	} else {
+		status = http.StatusOK
+	}
+
+	c.AddHeaders(status, map[string]bool{"no_cache": true})
+	c.ServeResponse(utils.PrepareResponse(d, err, status))
+}
@@ -2,19 +2,33 @@ package controllers
 
 import (
 	"context"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"strings"
+	"time"
 
 	"currencyify/components"
+	"currencyify/components/audit"
+	"currencyify/components/auth"
+	"currencyify/filters"
+	"currencyify/logging"
+	"currencyify/tracing"
 	"currencyify/utils"
 
 	"github.com/beego/beego/v2/server/web"
 	"github.com/gomodule/redigo/redis"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// redisConn gets a connection of the redis pool for the requests.
+var redisConn = utils.Conn
+
 type Preparer interface {
 	UpdateComponent(interface{})
 }
@@ -23,13 +37,19 @@ type BaseController struct {
 	web.Controller
 	ReqCtx    context.Context
 	RedisConn redis.Conn
+
+	// span is the span of the http action, the parent of the spans of the components.
+	span trace.Span
 }
 
 // Prepare is called before the http action is processes, to initialize.
 func (c *BaseController) Prepare() {
-	requestCtx := c.Ctx.Request.Context()
-	c.ReqCtx = requestCtx
-	conn, err := utils.Conn()
+	var requestCtx context.Context
+	requestCtx, c.span = c.startSpan()
+	auditRequest := c.auditRequest()
+	c.span.SetAttributes(attribute.String("request.id", auditRequest.ID))
+	c.ReqCtx = audit.NewContext(requestCtx, auditRequest)
+	conn, err := redisConn()
 	if err != nil {
 		c.Error(err)
 	} else {
@@ -47,12 +67,71 @@ func (c *BaseController) Prepare() {
 
 // Finish is called after the http action is processed, to clean-up
 func (c *BaseController) Finish() {
-	defer func(RedisConn redis.Conn) {
-		err := RedisConn.Close()
-		if err != nil {
-			log.Printf("error closing redis connection")
-		}
-	}(c.RedisConn)
+	c.endSpan(nil)
+	c.ReleaseRedisConn()
+}
+
+// ReleaseRedisConn closes the redis connection of the request, returning it to the pool. Long-lived actions not using
+// the connection, like the streams, call it up front so the connection isn't held till they end.
+func (c *BaseController) ReleaseRedisConn() {
+	if c.RedisConn == nil {
+		return
+	}
+
+	if err := c.RedisConn.Close(); err != nil {
+		slog.ErrorContext(c.ReqCtx, "error closing redis connection", "error", err)
+	}
+	c.RedisConn = nil
+}
+
+// startSpan starts the server span of the http action, continuing the trace of the `traceparent` header if any.
+// It returns the context carrying the span, and the span.
+func (c *BaseController) startSpan() (context.Context, trace.Span) {
+	controller, action := c.GetControllerAndAction()
+	route, _ := c.Ctx.Input.GetData("RouterPattern").(string)
+
+	return tracing.Start(tracing.Extract(c.Ctx.Request.Context(), c.Ctx.Request.Header), controller+"."+action,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(attribute.String("http.method", c.Ctx.Input.Method()), attribute.String("http.route", route)),
+	)
+}
+
+// endSpan ends the span of the http action along with its response status, marking it failed with the given error, or
+// else when the status is a server error.
+func (c *BaseController) endSpan(err error) {
+	if c.span == nil {
+		return
+	}
+
+	status := c.Ctx.ResponseWriter.Status
+	if status == 0 {
+		status = c.Ctx.Output.Status
+	}
+	if status == 0 {
+		status = http.StatusOK
+	}
+	if err == nil && status >= http.StatusInternalServerError {
+		err = errors.New(http.StatusText(status))
+	}
+
+	c.span.SetAttributes(attribute.Int("http.status_code", status))
+	tracing.End(c.span, err)
+	c.span = nil
+}
+
+// auditRequest identifies the request in the audit records, by the request ID of its context, or else a new request ID,
+// and by the authenticated principal, or else the client IP.
+// It returns the audit request.
+func (c *BaseController) auditRequest() audit.Request {
+	req := audit.Request{ID: logging.RequestID(c.Ctx.Request.Context()), Client: "ip:" + utils.ClientIP(c.Ctx.Request)}
+	if req.ID == "" {
+		req.ID = logging.NewRequestID()
+	}
+	if principal, ok := c.Ctx.Input.GetData(filters.PrincipalKey).(*auth.Principal); ok {
+		req.Client, req.ClientName = principal.Client(), principal.Name
+	}
+
+	return req
 }
 
 // InitComponent initializes the component whose methods needs to be called.
@@ -91,12 +170,25 @@ func (c *BaseController) GetRequestBody() []byte {
 	return body
 }
 
+// ErrorStatus is used to get the HTTP status of the error occurred while processing the request, i.e. 400/422 for bad
+// input, 404 for the currencies unknown to vendor API, 502 for the vendor API failures and 504 for the vendor API
+// timeouts. Errors which aren't typed keep the status of the component app error.
+// It returns the HTTP status.
+func (c *BaseController) ErrorStatus(err error, appError *utils.AppError) int {
+	status := http.StatusInternalServerError
+	if appError != nil && appError.Status != 0 {
+		status = appError.Status
+	}
+
+	return utils.HTTPStatus(err, status)
+}
+
 // Error is used to stop execution, if any fatal error has occurred.
 func (c *BaseController) Error(err error) {
-	log.Printf("Some error occurred: %v", err)
-	c.Data["json"] = utils.PrepareResponse(nil, err, http.StatusInternalServerError)
+	slog.ErrorContext(c.ReqCtx, "Some error occurred", "error", err)
 	c.Ctx.Output.SetStatus(http.StatusInternalServerError)
-	_ = c.ServeJSON()
+	c.ServeResponse(utils.PrepareResponse(nil, err, http.StatusInternalServerError))
+	c.endSpan(err)
 	c.StopRun() // stop controller execution immediately
 }
 
@@ -108,3 +200,112 @@ func (c *BaseController) AddHeaders(status int, opts map[string]bool) {
 
 	c.Ctx.Output.SetStatus(status)
 }
+
+// varyByAccept adds `Accept` to the `Vary` header unless it is already there, keeping the `Vary: Origin` of the CORS
+// responses.
+func (c *BaseController) varyByAccept() {
+	header := c.Ctx.ResponseWriter.Header()
+	for _, value := range header.Values("Vary") {
+		for _, field := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(field), "Accept") {
+				return
+			}
+		}
+	}
+
+	header.Add("Vary", "Accept")
+}
+
+// AddCacheHeaders adds the validators (`ETag` derived from the data and `Last-Modified`) and the freshness (`max-age`)
+// headers, letting clients and proxies cache the response for the given number of seconds, along with `Vary: Accept`
+// as the ETag depends on the negotiated content type.
+// It returns true if the validators sent by the client with `If-None-Match` or `If-Modified-Since` still match,
+// in which case the response should be served as 304 Not Modified without body.
+func (c *BaseController) AddCacheHeaders(data interface{}, lastModified time.Time, maxAge int) bool {
+	dataBytes, err := json.Marshal(data)
+	if err != nil {
+		slog.ErrorContext(c.ReqCtx, "error marshaling data to generate ETag", "error", err)
+		c.Ctx.Output.Header("Cache-Control", "no-store, max-age=0")
+		return false
+	}
+	if contentType := utils.NegotiateContentType(c.Ctx.Input.Header("Accept")); contentType != utils.MIMEJSON {
+		// each representation of the data needs its own ETag
+		dataBytes = append([]byte(contentType), dataBytes...)
+	}
+	etag := fmt.Sprintf(`"%x"`, sha256.Sum256(dataBytes))
+
+	c.varyByAccept()
+	c.Ctx.Output.Header("ETag", etag)
+	if !lastModified.IsZero() {
+		c.Ctx.Output.Header("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+	if maxAge > 0 {
+		c.Ctx.Output.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", maxAge))
+	} else {
+		c.Ctx.Output.Header("Cache-Control", "no-cache")
+	}
+
+	if ifNoneMatch := c.Ctx.Input.Header("If-None-Match"); ifNoneMatch != "" {
+		for _, tag := range strings.Split(ifNoneMatch, ",") {
+			if tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/"); tag == etag || tag == "*" {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	if ifModifiedSince := c.Ctx.Input.Header("If-Modified-Since"); ifModifiedSince != "" && !lastModified.IsZero() {
+		if since, err := http.ParseTime(ifModifiedSince); err == nil && !lastModified.Truncate(time.Second).After(since) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ServeResponse serves the API response in the content type negotiated from the `Accept` header, i.e. XML for
+// `application/xml`, CSV for `text/csv` and the `APIResponse` protobuf message for `application/x-protobuf`.
+// Responses which can't be served in the negotiated content type, i.e. non-tabular data as CSV, are served as JSON.
+// Error responses are served as RFC 7807 problem details to the clients accepting `application/problem+json`.
+func (c *BaseController) ServeResponse(resp utils.APIResponse) {
+	c.varyByAccept()
+
+	if resp.Error != "" && utils.AcceptsProblemJSON(c.Ctx.Input.Header("Accept")) {
+		c.Ctx.Output.Header("Content-Type", utils.MIMEProblemJSON)
+		if body, err := json.Marshal(utils.NewProblem(resp)); err != nil {
+			slog.ErrorContext(c.ReqCtx, "error marshaling problem details", "error", err)
+		} else {
+			_ = c.Ctx.Output.Body(body)
+			return
+		}
+	}
+
+	var body []byte
+	var ok bool
+	var err error
+	contentType := utils.NegotiateContentType(c.Ctx.Input.Header("Accept"))
+	switch contentType {
+	case utils.MIMEXML:
+		body, err = utils.MarshalXML(resp)
+		ok = true
+	case utils.MIMECSV:
+		body, ok, err = utils.MarshalCSV(resp)
+	case utils.MIMEProtobuf:
+		body, ok, err = utils.MarshalProto(resp)
+	}
+
+	if err != nil {
+		slog.ErrorContext(c.ReqCtx, "error marshaling response", "content_type", contentType, "error", err)
+	} else if ok {
+		if contentType != utils.MIMEProtobuf {
+			contentType = fmt.Sprintf("%s; charset=utf-8", contentType)
+		}
+		c.Ctx.Output.Header("Content-Type", contentType)
+		_ = c.Ctx.Output.Body(body)
+		return
+	}
+
+	c.Data["json"] = resp
+	_ = c.ServeJSON()
+}
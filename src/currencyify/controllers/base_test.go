@@ -0,0 +1,244 @@
+package controllers
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"currencyify/filters"
+	"currencyify/utils"
+
+	_ "github.com/beego/beego/v2/core/config/yaml"
+	"github.com/beego/beego/v2/server/web"
+	"github.com/beego/beego/v2/server/web/context"
+	"github.com/gomodule/redigo/redis"
+	"github.com/stretchr/testify/assert"
+)
+
+type testController struct {
+	BaseController
+}
+
+func (c *testController) Get() {
+	c.AddHeaders(http.StatusOK, map[string]bool{"no_cache": true})
+	c.ServeResponse(utils.PrepareResponse(map[string]string{"currency": "USD"}, nil, http.StatusOK))
+}
+
+type streamController struct {
+	BaseController
+}
+
+func (c *streamController) Get() {
+	conn, _ := c.RedisConn.(*closeCountingConn)
+	c.ReleaseRedisConn()
+	conn.closesInAction = conn.closes
+	c.AddHeaders(http.StatusOK, nil)
+}
+
+type closeCountingConn struct {
+	*utils.MockRedisConn
+	closes         int
+	closesInAction int
+}
+
+func (c *closeCountingConn) Close() error {
+	c.closes++
+	return nil
+}
+
+func TestBaseController_ReleaseRedisConn(t *testing.T) {
+	if err := web.LoadAppConfig("yaml", "../conf/local.app.yaml"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Setup
+	conn := &closeCountingConn{MockRedisConn: utils.NewMockRedisConn()}
+	redisConn = func() (redis.Conn, error) {
+		return conn, nil
+	}
+	defer func() {
+		redisConn = utils.Conn
+	}()
+	handler := web.NewControllerRegister()
+	handler.Add("/stream", &streamController{})
+	rr := httptest.NewRecorder()
+
+	// Run test
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/stream", nil))
+
+	// Assert
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, 1, conn.closesInAction, "connection should be closed before the action ends")
+	assert.Equal(t, 1, conn.closes, "released connection should not be closed again when the action finishes")
+}
+
+func TestBaseController_ServeResponse_vary(t *testing.T) {
+	if err := web.LoadAppConfig("yaml", "../conf/local.app.yaml"); err != nil {
+		t.Fatal(err)
+	}
+	redisConn = func() (redis.Conn, error) {
+		return utils.NewMockRedisConn(), nil
+	}
+	handler := web.NewControllerRegister()
+	handler.InsertFilter("/*", web.BeforeRouter, filters.CORS)
+	handler.Add("/currency", &testController{})
+
+	testCases := []struct {
+		name string
+
+		headers map[string]string
+
+		wantVary   []string
+		wantOrigin string
+	}{
+		{
+			name:       "should vary by the origin and the accepted content type of the CORS request",
+			headers:    map[string]string{"Origin": "http://localhost:3000", "Accept": "application/xml"},
+			wantVary:   []string{"Origin", "Accept"},
+			wantOrigin: "http://localhost:3000",
+		},
+		{
+			name:     "should vary by the origin of the request of the other origins",
+			headers:  map[string]string{"Origin": "https://evil.com"},
+			wantVary: []string{"Origin", "Accept"},
+		},
+	}
+
+	for _, tCase := range testCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			// Setup
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/currency", nil)
+			for header, value := range tCase.headers {
+				req.Header.Set(header, value)
+			}
+
+			// Run test
+			handler.ServeHTTP(rr, req)
+
+			// Assert
+			assert.Equal(t, http.StatusOK, rr.Code, "case: %v", tCase)
+			assert.Equal(t, tCase.wantVary, rr.Header().Values("Vary"), "case: %v", tCase)
+			assert.Equal(t, tCase.wantOrigin, rr.Header().Get("Access-Control-Allow-Origin"), "case: %v", tCase)
+		})
+	}
+	redisConn = utils.Conn
+}
+
+func TestBaseController_AddCacheHeaders(t *testing.T) {
+	data := map[string]string{"currency": "USD"}
+	dataBytes, _ := json.Marshal(data)
+	etag := fmt.Sprintf(`"%x"`, sha256.Sum256(dataBytes))
+	xmlETag := fmt.Sprintf(`"%x"`, sha256.Sum256(append([]byte(utils.MIMEXML), dataBytes...)))
+	lastModified := time.Date(2024, time.February, 26, 12, 4, 0, 500, time.UTC)
+
+	type vars struct {
+		data         interface{}
+		lastModified time.Time
+		maxAge       int
+		headers      map[string]string
+	}
+
+	testCases := []struct {
+		name string
+
+		vars vars
+
+		want        bool
+		wantHeaders map[string]string
+	}{
+		{
+			name: "should add the validators and the max-age of the remaining TTL",
+			vars: vars{data: data, lastModified: lastModified, maxAge: 120},
+			wantHeaders: map[string]string{
+				"ETag":          etag,
+				"Last-Modified": "Mon, 26 Feb 2024 12:04:00 GMT",
+				"Cache-Control": "public, max-age=120",
+				"Vary":          "Accept",
+			},
+		},
+		{
+			name: "should make the clients revalidate the data not cached",
+			vars: vars{data: data},
+			wantHeaders: map[string]string{
+				"ETag":          etag,
+				"Last-Modified": "",
+				"Cache-Control": "no-cache",
+			},
+		},
+		{
+			name:        "should derive the ETag of the other representations from their content type",
+			vars:        vars{data: data, maxAge: 120, headers: map[string]string{"Accept": "application/xml"}},
+			wantHeaders: map[string]string{"ETag": xmlETag},
+		},
+		{
+			name:        "should be not modified when the ETag matches",
+			vars:        vars{data: data, maxAge: 120, headers: map[string]string{"If-None-Match": `"other", ` + etag}},
+			want:        true,
+			wantHeaders: map[string]string{"ETag": etag, "Vary": "Accept"},
+		},
+		{
+			name: "should be not modified when the weak ETag matches",
+			vars: vars{data: data, maxAge: 120, headers: map[string]string{"If-None-Match": "W/" + etag}},
+			want: true,
+		},
+		{
+			name: "should be not modified for any ETag",
+			vars: vars{data: data, maxAge: 120, headers: map[string]string{"If-None-Match": "*"}},
+			want: true,
+		},
+		{
+			name: "should be modified when the ETag doesn't match, regardless of If-Modified-Since",
+			vars: vars{data: data, lastModified: lastModified, maxAge: 120, headers: map[string]string{
+				"If-None-Match":     `"other"`,
+				"If-Modified-Since": "Mon, 26 Feb 2024 12:04:00 GMT",
+			}},
+		},
+		{
+			name:        "should be not modified since the last modified time",
+			vars:        vars{data: data, lastModified: lastModified, maxAge: 120, headers: map[string]string{"If-Modified-Since": "Mon, 26 Feb 2024 12:04:00 GMT"}},
+			want:        true,
+			wantHeaders: map[string]string{"Vary": "Accept"},
+		},
+		{
+			name: "should be modified after the If-Modified-Since time",
+			vars: vars{data: data, lastModified: lastModified, maxAge: 120, headers: map[string]string{"If-Modified-Since": "Mon, 26 Feb 2024 12:03:59 GMT"}},
+		},
+		{
+			name: "should be modified when the last modified time isn't known",
+			vars: vars{data: data, maxAge: 120, headers: map[string]string{"If-Modified-Since": "Mon, 26 Feb 2024 12:04:00 GMT"}},
+		},
+		{
+			name:        "should not cache the data which can't be marshaled",
+			vars:        vars{data: make(chan int), maxAge: 120, headers: map[string]string{"If-None-Match": "*"}},
+			wantHeaders: map[string]string{"ETag": "", "Cache-Control": "no-store, max-age=0"},
+		},
+	}
+
+	for _, tCase := range testCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			// Setup
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/currency", nil)
+			for header, value := range tCase.vars.headers {
+				req.Header.Set(header, value)
+			}
+			c := &BaseController{}
+			c.Ctx = context.NewContext()
+			c.Ctx.Reset(rr, req)
+
+			// Run test
+			got := c.AddCacheHeaders(tCase.vars.data, tCase.vars.lastModified, tCase.vars.maxAge)
+
+			// Assert
+			assert.Equalf(t, tCase.want, got, "case: %v", tCase.name)
+			for header, value := range tCase.wantHeaders {
+				assert.Equalf(t, value, rr.Header().Get(header), "case: %v", tCase.name)
+			}
+		})
+	}
+}
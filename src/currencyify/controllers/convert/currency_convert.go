@@ -2,14 +2,21 @@ package convert
 
 import (
 	"encoding/json"
-	"log"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
+	"net/url"
 
 	"currencyify/components/convert"
 	"currencyify/controllers"
 	"currencyify/utils"
 )
 
+// maxFormFieldSize is the max size of the form fields of the multipart CSV conversions, in bytes.
+const maxFormFieldSize = 1 << 10
+
 type CurrencyConvertController struct {
 	controllers.BaseController
 	Component convert.CurrencyConverter
@@ -27,19 +34,155 @@ func (c *CurrencyConvertController) ConvertCurrency() {
 
 	form := c.Component.GetCurrencyConverterForm()
 
-	if err = json.Unmarshal(c.GetRequestBody(), form); err != nil {
-		status = http.StatusInternalServerError
+	if c.Ctx.Input.IsGet() {
+		if err = c.parseQuery(form); err != nil {
+			status = http.StatusBadRequest
+		}
+	} else if err = json.Unmarshal(c.GetRequestBody(), form); err != nil {
+		err = utils.NewMalformedRequestError(err)
+		status = c.ErrorStatus(err, nil)
+	}
+
+	if err != nil {
+		// do nothing
 	} else if d, err = c.Component.ConvertCurrency(form); err != nil {
-		status = c.Component.GetCurrencyConverterAppError().Status
+		status = c.ErrorStatus(err, c.Component.GetCurrencyConverterAppError())
 	}
 
 	if err != nil {
-		log.Printf("Some error occurred: %v", err)
+		slog.ErrorContext(c.ReqCtx, "Some error occurred", "error", err)
 	} else {
 		status = http.StatusOK
 	}
 
-	c.Data["json"] = utils.PrepareResponse(d, err, status)
+	if err == nil && c.Ctx.Input.IsGet() {
+		if c.AddCacheHeaders(d, d.RateTimestamp, d.CacheTTL) {
+			c.AddHeaders(http.StatusNotModified, nil)
+			return
+		}
+
+		c.AddHeaders(status, nil)
+		c.ServeResponse(utils.PrepareResponse(d, err, status))
+		return
+	}
+
 	c.AddHeaders(status, map[string]bool{"no_cache": true})
-	_ = c.ServeJSON()
+	c.ServeResponse(utils.PrepareResponse(d, err, status))
+}
+
+func (c *CurrencyConvertController) ConvertCurrencies() {
+	var d *convert.CurrencyBatchConverterResponse
+	var err error
+	var status int
+
+	form := c.Component.GetCurrencyBatchConverterForm()
+
+	if err = json.Unmarshal(c.GetRequestBody(), form); err != nil {
+		err = utils.NewMalformedRequestError(err)
+		status = c.ErrorStatus(err, nil)
+	} else if d, err = c.Component.ConvertCurrencies(form); err != nil {
+		status = c.ErrorStatus(err, c.Component.GetCurrencyConverterAppError())
+	}
+
+	if err != nil {
+		slog.ErrorContext(c.ReqCtx, "Some error occurred", "error", err)
+	} else {
+		status = http.StatusOK
+	}
+
+	c.AddHeaders(status, map[string]bool{"no_cache": true})
+	c.ServeResponse(utils.PrepareResponse(d, err, status))
+}
+
+func (c *CurrencyConvertController) ConvertCSV() {
+	var err error
+	var status int
+
+	form := c.Component.GetCurrencyCSVConverterForm()
+
+	file, err := c.nextFilePart()
+	if err != nil {
+		status = http.StatusBadRequest
+	} else if err = c.ParseForm(form); err != nil {
+		status = http.StatusBadRequest
+	} else {
+		c.Ctx.Output.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Ctx.Output.Header("Content-Disposition", "attachment; filename=converted.csv")
+		c.AddHeaders(http.StatusOK, map[string]bool{"no_cache": true})
+		if err = c.Component.ConvertCSV(form, file, c.Ctx.ResponseWriter); err != nil {
+			status = c.ErrorStatus(err, c.Component.GetCurrencyConverterAppError())
+		}
+	}
+
+	if err == nil {
+		return
+	}
+
+	slog.ErrorContext(c.ReqCtx, "Some error occurred", "error", err)
+	if c.Ctx.ResponseWriter.Started {
+		// rows are already streamed to the client, so the response can't be replaced with an error
+		return
+	}
+
+	c.Ctx.ResponseWriter.Header().Del("Content-Disposition")
+	c.AddHeaders(status, map[string]bool{"no_cache": true})
+	c.ServeResponse(utils.PrepareResponse(nil, err, status))
+}
+
+// nextFilePart streams the multipart body of the CSV conversion till its `file` part, adding the form fields sent
+// before the file to the request form, so the ledger is read by the converter as it is received.
+// It returns the file part and error if the body isn't multipart or has no file part.
+func (c *CurrencyConvertController) nextFilePart() (io.Reader, error) {
+	reader, err := utils.MultipartReader(c.Ctx.Request)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Ctx.Request.Form == nil {
+		c.Ctx.Request.Form = url.Values{}
+	}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil, errors.New("`file` part is required")
+		} else if err != nil {
+			return nil, err
+		}
+
+		if part.FormName() == "file" {
+			return part, nil
+		} else if part.FileName() != "" {
+			continue
+		}
+
+		value, err := io.ReadAll(io.LimitReader(part, maxFormFieldSize+1))
+		if err != nil {
+			return nil, err
+		} else if len(value) > maxFormFieldSize {
+			return nil, fmt.Errorf("`%s` part is too large", part.FormName())
+		}
+		c.Ctx.Request.Form.Add(part.FormName(), string(value))
+	}
+}
+
+// parseQuery fills the currency converter form from the query string, i.e. `?from=USD&to=INR&amount=10`.
+func (c *CurrencyConvertController) parseQuery(form *convert.CurrencyConverterForm) error {
+	form.SourceCurrency = c.GetString("from")
+	form.TargetCurrency = c.GetString("to")
+
+	for param, amount := range map[string]*float64{"amount": &form.Amount, "target_amount": &form.TargetAmount} {
+		if c.GetString(param) == "" {
+			continue
+		}
+
+		value, err := c.GetFloat(param)
+		if err != nil {
+			errs := utils.ValidationErrors{}
+			errs.Add(utils.ErrCodeInvalidFormat, param, c.GetString(param), fmt.Sprintf("`%s` parameter should be a number", param))
+			return errs
+		}
+		*amount = value
+	}
+
+	return nil
 }
@@ -0,0 +1,147 @@
+package convert
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"currencyify/components"
+	"currencyify/components/convert"
+	"currencyify/constants"
+	"currencyify/utils"
+
+	beecontext "github.com/beego/beego/v2/server/web/context"
+	"github.com/stretchr/testify/assert"
+)
+
+type currencyConverterAPIResponse struct {
+	Code   int                                `json:"code"`
+	Data   *convert.CurrencyConverterResponse `json:"data"`
+	Error  string                             `json:"error"`
+	Errors []utils.ValidationError            `json:"errors"`
+}
+
+func TestCurrencyConvertController_ConvertCurrency_query(t *testing.T) {
+	constants.CURRENCY_CODES_JSON_FILE_NAME = "../../currency_codes.json"
+
+	testCases := []struct {
+		name string
+
+		query string
+
+		wantStatus  int
+		want        string
+		wantHeaders map[string]string
+		wantErrors  []utils.ValidationError
+	}{
+		{
+			name:       "should convert the amount given in the query string",
+			query:      "from=USD&to=INR&amount=100",
+			wantStatus: http.StatusOK,
+			want:       `{ "source_currency": "USD", "target_currency": "INR", "amount": 100, "converted_amount": 8277.1291 }`,
+			wantHeaders: map[string]string{
+				"Cache-Control": "no-cache",
+				"Last-Modified": "Mon, 26 Feb 2024 12:04:00 GMT",
+			},
+		},
+		{
+			name:       "should compute the source amount needed to receive the target amount given in the query string",
+			query:      "from=USD&to=INR&target_amount=8277.1291",
+			wantStatus: http.StatusOK,
+			want:       `{ "source_currency": "USD", "target_currency": "INR", "amount": 100, "target_amount": 8277.1291, "converted_amount": 8277.1291 }`,
+			wantHeaders: map[string]string{
+				"Cache-Control": "no-cache",
+				"Last-Modified": "Mon, 26 Feb 2024 12:04:00 GMT",
+			},
+		},
+		{
+			name:       "should fail for the amount which isn't a number",
+			query:      "from=USD&to=INR&amount=ten",
+			wantStatus: http.StatusBadRequest,
+			wantErrors: []utils.ValidationError{
+				{Code: utils.ErrCodeInvalidFormat, Field: "amount", Value: "ten", Message: "`amount` parameter should be a number"},
+			},
+			wantHeaders: map[string]string{
+				"Cache-Control": "no-store, max-age=0",
+			},
+		},
+		{
+			name:       "should fail for the target amount which isn't a number",
+			query:      "from=USD&to=INR&target_amount=1e",
+			wantStatus: http.StatusBadRequest,
+			wantErrors: []utils.ValidationError{
+				{Code: utils.ErrCodeInvalidFormat, Field: "target_amount", Value: "1e", Message: "`target_amount` parameter should be a number"},
+			},
+		},
+		{
+			name:       "should fail when the target currency isn't given",
+			query:      "from=USD&amount=100",
+			wantStatus: http.StatusBadRequest,
+			wantErrors: []utils.ValidationError{
+				{Code: utils.ErrCodeRequired, Field: "target_currency"},
+			},
+		},
+		{
+			name:       "should fail for the unknown currency",
+			query:      "from=USD&to=XYZ&amount=100",
+			wantStatus: http.StatusBadRequest,
+			wantErrors: []utils.ValidationError{
+				{Code: utils.ErrCodeUnknownCurrency, Field: "target_currency", Value: "XYZ"},
+			},
+		},
+	}
+
+	for _, tCase := range testCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			// Setup
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/convert/currency-convert?"+tCase.query, nil)
+			c := &CurrencyConvertController{}
+			ctx := beecontext.NewContext()
+			ctx.Reset(rr, req)
+			c.Init(ctx, "CurrencyConvertController", "ConvertCurrency", c)
+			c.ReqCtx = context.WithValue(context.Background(), "x-mock-headers", map[string]string{"x-mock-api": "default"})
+			c.UpdateComponent(components.ComponentMap["CurrencyConvert"](&components.BaseComponent{
+				ReqCtx:    c.ReqCtx,
+				RedisConn: utils.NewMockRedisConn(),
+			}))
+
+			// Run test
+			c.ConvertCurrency()
+
+			// Assert
+			assert.Equal(t, tCase.wantStatus, rr.Code, "case: %v", tCase)
+			for header, want := range tCase.wantHeaders {
+				assert.Equal(t, want, rr.Header().Get(header), "case: %v", tCase)
+			}
+			if tCase.wantStatus == http.StatusOK {
+				assert.NotEmpty(t, rr.Header().Get("ETag"), "case: %v", tCase)
+			}
+			got := new(currencyConverterAPIResponse)
+			if !assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), got), "case: %v", tCase) {
+				return
+			}
+			if tCase.want != "" {
+				want := new(convert.CurrencyConverterResponse)
+				_ = json.Unmarshal([]byte(tCase.want), want)
+				assert.Equal(t, want, got.Data, "case: %v", tCase)
+			}
+			assert.Len(t, got.Errors, len(tCase.wantErrors), "case: %v", tCase)
+			for i, wantErr := range tCase.wantErrors {
+				if i >= len(got.Errors) {
+					break
+				}
+				assert.Equal(t, wantErr.Code, got.Errors[i].Code, "case: %v", tCase)
+				assert.Equal(t, wantErr.Field, got.Errors[i].Field, "case: %v", tCase)
+				if wantErr.Value != nil {
+					assert.Equal(t, wantErr.Value, got.Errors[i].Value, "case: %v", tCase)
+				}
+				if wantErr.Message != "" {
+					assert.Equal(t, wantErr.Message, got.Errors[i].Message, "case: %v", tCase)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package convert
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"currencyify/components/convert"
+	"currencyify/controllers"
+	"currencyify/utils"
+)
+
+type PortfolioValuationController struct {
+	controllers.BaseController
+	Component convert.PortfolioValuator
+}
+
+// UpdateComponent is used to update the component object.
+func (c *PortfolioValuationController) UpdateComponent(component interface{}) {
+	c.Component, _ = component.(convert.PortfolioValuator)
+}
+
+func (c *PortfolioValuationController) ValuePortfolio() {
+	var d *convert.PortfolioValuationResponse
+	var err error
+	var status int
+
+	form := c.Component.GetPortfolioValuationForm()
+
+	if err = json.Unmarshal(c.GetRequestBody(), form); err != nil {
+		err = utils.NewMalformedRequestError(err)
+		status = c.ErrorStatus(err, nil)
+	} else if d, err = c.Component.ValuePortfolio(form); err != nil {
+		status = c.ErrorStatus(err, c.Component.GetCurrencyConverterAppError())
+	}
+
+	if err != nil {
+		slog.ErrorContext(c.ReqCtx, "Some error occurred", "error", err)
+	} else {
+		status = http.StatusOK
+	}
+
+	c.AddHeaders(status, map[string]bool{"no_cache": true})
+	c.ServeResponse(utils.PrepareResponse(d, err, status))
+}
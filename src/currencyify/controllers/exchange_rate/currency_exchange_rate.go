@@ -2,14 +2,19 @@ package exchange_rate
 
 import (
 	"encoding/json"
-	"log"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"strings"
+	"time"
 
 	"currencyify/components/exchange_rate"
 	"currencyify/controllers"
 	"currencyify/utils"
 )
 
+const rateStreamHeartbeatInterval = 15 * time.Second
+
 type CurrencyExchangeRateController struct {
 	controllers.BaseController
 	Component exchange_rate.CurrencyExchangeRate
@@ -27,19 +32,116 @@ func (c *CurrencyExchangeRateController) GetCurrencyExchangeRate() {
 
 	form := c.Component.GetCurrencyExchangeRateForm()
 
-	if err = json.Unmarshal(c.GetRequestBody(), form); err != nil {
-		status = http.StatusInternalServerError
+	if c.Ctx.Input.IsGet() {
+		c.parseQuery(form)
+	} else if err = json.Unmarshal(c.GetRequestBody(), form); err != nil {
+		err = utils.NewMalformedRequestError(err)
+		status = c.ErrorStatus(err, nil)
+	}
+
+	if err != nil {
+		// do nothing
 	} else if d, err = c.Component.GetCurrencyExchangeRate(form); err != nil {
-		status = c.Component.GetCurrencyExchangeRateAppError().Status
+		status = c.ErrorStatus(err, c.Component.GetCurrencyExchangeRateAppError())
 	}
 
 	if err != nil {
-		log.Printf("Some error occurred: %v", err)
+		slog.ErrorContext(c.ReqCtx, "Some error occurred", "error", err)
 	} else {
 		status = http.StatusOK
 	}
 
-	c.Data["json"] = utils.PrepareResponse(d, err, status)
+	if err == nil && c.Ctx.Input.IsGet() {
+		if c.AddCacheHeaders(d, d.LastModified(), d.CacheTTL) {
+			c.AddHeaders(http.StatusNotModified, nil)
+			return
+		}
+
+		c.AddHeaders(status, nil)
+		c.ServeResponse(utils.PrepareResponse(d, err, status))
+		return
+	}
+
 	c.AddHeaders(status, map[string]bool{"no_cache": true})
-	_ = c.ServeJSON()
+	c.ServeResponse(utils.PrepareResponse(d, err, status))
+}
+
+func (c *CurrencyExchangeRateController) StreamCurrencyExchangeRate() {
+	var err error
+	var status int
+
+	// the rates are polled by the hub using its own connections
+	c.ReleaseRedisConn()
+
+	form := c.Component.GetRateStreamForm()
+	c.parseQuery(&form.CurrencyExchangeRateForm)
+
+	if c.GetString("threshold") != "" {
+		if form.Threshold, err = c.GetFloat("threshold"); err != nil {
+			errs := utils.ValidationErrors{}
+			errs.Add(utils.ErrCodeInvalidFormat, "threshold", c.GetString("threshold"), "`threshold` parameter should be a number")
+			err = errs
+			status = http.StatusBadRequest
+		}
+	}
+
+	var subscription *exchange_rate.RateSubscription
+	if err != nil {
+		// do nothing
+	} else if subscription, err = c.Component.SubscribeCurrencyExchangeRate(form); err != nil {
+		status = c.ErrorStatus(err, c.Component.GetCurrencyExchangeRateAppError())
+	}
+
+	if err != nil {
+		slog.ErrorContext(c.ReqCtx, "Some error occurred", "error", err)
+		c.AddHeaders(status, map[string]bool{"no_cache": true})
+		c.ServeResponse(utils.PrepareResponse(nil, err, status))
+		return
+	}
+	defer subscription.Close()
+
+	w := c.Ctx.ResponseWriter
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store, max-age=0")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	w.Flush()
+
+	heartbeat := time.NewTicker(rateStreamHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Ctx.Request.Context().Done():
+			return
+		case <-heartbeat.C:
+			// comment lines keep idle connections from being closed by proxies
+			if _, err = fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+		case d := <-subscription.Updates():
+			data, err := json.Marshal(d)
+			if err != nil {
+				slog.ErrorContext(c.ReqCtx, "error marshaling exchange rates", "error", err)
+				continue
+			}
+			if _, err = fmt.Fprintf(w, "event: rates\ndata: %s\n\n", data); err != nil {
+				return
+			}
+		}
+		w.Flush()
+	}
+}
+
+// parseQuery fills the currency exchange rate form from the query string, i.e. `?base=USD&symbols=INR,JPY&date=2024-01-15`.
+func (c *CurrencyExchangeRateController) parseQuery(form *exchange_rate.CurrencyExchangeRateForm) {
+	form.BaseCurrency = c.GetString("base")
+	form.Date = c.GetString("date")
+
+	for _, symbol := range strings.Split(c.GetString("symbols"), ",") {
+		if symbol = strings.TrimSpace(symbol); symbol != "" {
+			form.TargetCurrencies = append(form.TargetCurrencies, symbol)
+		}
+	}
 }
@@ -0,0 +1,206 @@
+package exchange_rate
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"currencyify/components"
+	"currencyify/components/exchange_rate"
+	"currencyify/constants"
+	"currencyify/utils"
+
+	beecontext "github.com/beego/beego/v2/server/web/context"
+	"github.com/stretchr/testify/assert"
+)
+
+type currencyExchangeRateAPIResponse struct {
+	Code   int                                         `json:"code"`
+	Data   *exchange_rate.CurrencyExchangeRateResponse `json:"data"`
+	Error  string                                      `json:"error"`
+	Errors []utils.ValidationError                     `json:"errors"`
+}
+
+func TestCurrencyExchangeRateController_GetCurrencyExchangeRate_query(t *testing.T) {
+	constants.CURRENCY_CODES_JSON_FILE_NAME = "../../currency_codes.json"
+
+	testCases := []struct {
+		name string
+
+		query string
+
+		wantStatus int
+		wantBase   string
+		wantRates  map[string]string
+		wantErrors []utils.ValidationError
+	}{
+		{
+			name:       "should get the latest rates of the symbols given in the query string",
+			query:      "base=USD&symbols=INR,JPY",
+			wantStatus: http.StatusOK,
+			wantBase:   "USD",
+			wantRates:  map[string]string{"INR": "82.771291", "JPY": "150.608807"},
+		},
+		{
+			name:       "should skip the blank symbols given in the query string",
+			query:      "base=USD&symbols=INR,%20,JPY,",
+			wantStatus: http.StatusOK,
+			wantBase:   "USD",
+			wantRates:  map[string]string{"INR": "82.771291", "JPY": "150.608807"},
+		},
+		{
+			name:       "should get the historical rates of the date given in the query string",
+			query:      "base=USD&symbols=INR,JPY&date=2024-01-15",
+			wantStatus: http.StatusOK,
+			wantBase:   "USD",
+			wantRates:  map[string]string{"INR": "83", "JPY": "147"},
+		},
+		{
+			name:       "should fail when the base currency isn't given",
+			query:      "symbols=INR",
+			wantStatus: http.StatusBadRequest,
+			wantErrors: []utils.ValidationError{
+				{Code: utils.ErrCodeRequired, Field: "base_currency"},
+			},
+		},
+		{
+			name:       "should fail when the symbols aren't given",
+			query:      "base=USD&symbols=",
+			wantStatus: http.StatusBadRequest,
+			wantErrors: []utils.ValidationError{
+				{Code: utils.ErrCodeRequired, Field: "target_currencies"},
+			},
+		},
+		{
+			name:       "should fail for the date which isn't in YYYY-MM-DD format",
+			query:      "base=USD&symbols=INR&date=15-01-2024",
+			wantStatus: http.StatusBadRequest,
+			wantErrors: []utils.ValidationError{
+				{Code: utils.ErrCodeInvalidFormat, Field: "date", Value: "15-01-2024"},
+			},
+		},
+	}
+
+	for _, tCase := range testCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			// Setup
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/exchange-rate/currency-exchange-rate?"+tCase.query, nil)
+			c := &CurrencyExchangeRateController{}
+			ctx := beecontext.NewContext()
+			ctx.Reset(rr, req)
+			c.Init(ctx, "CurrencyExchangeRateController", "GetCurrencyExchangeRate", c)
+			c.ReqCtx = context.WithValue(context.Background(), "x-mock-headers", map[string]string{"x-mock-api": "default"})
+			c.UpdateComponent(components.ComponentMap["CurrencyExchangeRate"](&components.BaseComponent{
+				ReqCtx:    c.ReqCtx,
+				RedisConn: utils.NewMockRedisConn(),
+			}))
+
+			// Run test
+			c.GetCurrencyExchangeRate()
+
+			// Assert
+			assert.Equal(t, tCase.wantStatus, rr.Code, "case: %v", tCase)
+			got := new(currencyExchangeRateAPIResponse)
+			if !assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), got), "case: %v", tCase) {
+				return
+			}
+			if tCase.wantRates != nil && assert.NotNil(t, got.Data, "case: %v", tCase) {
+				assert.Equal(t, tCase.wantBase, got.Data.BaseCurrency, "case: %v", tCase)
+				for currencyCode, wantRate := range tCase.wantRates {
+					assert.Equal(t, wantRate, got.Data.ExchangeRates[currencyCode].CurrencyExchangeRate, "case: %v", tCase)
+				}
+			}
+			assert.Len(t, got.Errors, len(tCase.wantErrors), "case: %v", tCase)
+			for i, wantErr := range tCase.wantErrors {
+				if i >= len(got.Errors) {
+					break
+				}
+				assert.Equal(t, wantErr.Code, got.Errors[i].Code, "case: %v", tCase)
+				assert.Equal(t, wantErr.Field, got.Errors[i].Field, "case: %v", tCase)
+				if wantErr.Value != nil {
+					assert.Equal(t, wantErr.Value, got.Errors[i].Value, "case: %v", tCase)
+				}
+			}
+		})
+	}
+}
+
+func TestCurrencyExchangeRateController_GetCurrencyExchangeRate_conditional(t *testing.T) {
+	constants.CURRENCY_CODES_JSON_FILE_NAME = "../../currency_codes.json"
+
+	testCases := []struct {
+		name string
+
+		headers func(etag string) map[string]string
+
+		wantStatus int
+	}{
+		{
+			name: "should serve the rates varying by the accepted content type",
+			headers: func(string) map[string]string {
+				return nil
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "should serve not modified varying by the accepted content type when the ETag matches",
+			headers: func(etag string) map[string]string {
+				return map[string]string{"If-None-Match": etag}
+			},
+			wantStatus: http.StatusNotModified,
+		},
+		{
+			name: "should serve not modified varying by the accepted content type since the last modified time",
+			headers: func(string) map[string]string {
+				return map[string]string{"If-Modified-Since": "Mon, 26 Feb 2024 12:04:00 GMT"}
+			},
+			wantStatus: http.StatusNotModified,
+		},
+	}
+
+	// getRates gets the rates with the given request headers, along with the status, the one set when the response
+	// isn't written, as the 304 response without body is only written by the router.
+	getRates := func(headers map[string]string) (*httptest.ResponseRecorder, int) {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/exchange-rate/currency-exchange-rate?base=USD&symbols=INR,JPY", nil)
+		for header, value := range headers {
+			req.Header.Set(header, value)
+		}
+		c := &CurrencyExchangeRateController{}
+		ctx := beecontext.NewContext()
+		ctx.Reset(rr, req)
+		c.Init(ctx, "CurrencyExchangeRateController", "GetCurrencyExchangeRate", c)
+		c.ReqCtx = context.WithValue(context.Background(), "x-mock-headers", map[string]string{"x-mock-api": "default"})
+		c.UpdateComponent(components.ComponentMap["CurrencyExchangeRate"](&components.BaseComponent{
+			ReqCtx:    c.ReqCtx,
+			RedisConn: utils.NewMockRedisConn(),
+		}))
+		c.GetCurrencyExchangeRate()
+
+		if ctx.Output.Status == 0 {
+			return rr, rr.Code
+		}
+		return rr, ctx.Output.Status
+	}
+
+	for _, tCase := range testCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			// Setup
+			rr, _ := getRates(nil)
+			etag := rr.Header().Get("ETag")
+
+			// Run test
+			rr, status := getRates(tCase.headers(etag))
+
+			// Assert
+			assert.Equal(t, tCase.wantStatus, status, "case: %v", tCase.name)
+			assert.Equal(t, []string{"Accept"}, rr.Header().Values("Vary"), "case: %v", tCase.name)
+			if tCase.wantStatus == http.StatusNotModified {
+				assert.Empty(t, rr.Body.String(), "case: %v", tCase.name)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package graphql
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"currencyify/components/graphql"
+	"currencyify/controllers"
+	"currencyify/utils"
+
+	graphqlgo "github.com/graph-gophers/graphql-go"
+	gqlerrors "github.com/graph-gophers/graphql-go/errors"
+)
+
+type GraphQLController struct {
+	controllers.BaseController
+	Component graphql.GraphQL
+}
+
+// UpdateComponent is used to update the component object.
+func (c *GraphQLController) UpdateComponent(component interface{}) {
+	c.Component, _ = component.(graphql.GraphQL)
+}
+
+func (c *GraphQLController) Query() {
+	var d *graphqlgo.Response
+	var err error
+	var status int
+
+	form := c.Component.GetGraphQLForm()
+
+	if err = json.Unmarshal(c.GetRequestBody(), form); err != nil {
+		err = utils.NewMalformedRequestError(err)
+		status = c.ErrorStatus(err, nil)
+		d = &graphqlgo.Response{Errors: []*gqlerrors.QueryError{gqlerrors.Errorf("%s", err)}}
+	} else if d, err = c.Component.Exec(form); err != nil {
+		status = c.ErrorStatus(err, c.Component.GetGraphQLAppError())
+	}
+
+	if err != nil {
+		slog.ErrorContext(c.ReqCtx, "Some error occurred", "error", err)
+	} else {
+		status = http.StatusOK
+	}
+
+	// GraphQL clients expect the GraphQL response as is, rather than wrapped in the `APIResponse` envelope
+	c.Data["json"] = d
+	c.AddHeaders(status, map[string]bool{"no_cache": true})
+	_ = c.ServeJSON()
+}
@@ -0,0 +1,31 @@
+package filters
+
+import (
+	"log/slog"
+	"time"
+
+	"currencyify/utils"
+
+	"github.com/beego/beego/v2/server/web"
+	"github.com/beego/beego/v2/server/web/context"
+)
+
+// AccessLog is the filter chain logging a line per served request of the API namespace, along with its request ID,
+// route pattern, status and latency. Being a chain, it wraps the filters too, so the requests rejected by them are
+// logged as well.
+func AccessLog(next web.FilterFunc) web.FilterFunc {
+	return func(ctx *context.Context) {
+		start := time.Now()
+		next(ctx)
+
+		slog.InfoContext(ctx.Request.Context(), "request served",
+			"method", ctx.Input.Method(),
+			"path", ctx.Input.URL(),
+			"route", routePattern(ctx),
+			"status", responseStatus(ctx),
+			"duration_ms", time.Since(start).Milliseconds(),
+			"client_ip", utils.ClientIP(ctx.Request),
+			"user_agent", ctx.Input.UserAgent(),
+		)
+	}
+}
@@ -0,0 +1,54 @@
+package filters
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"currencyify/constants"
+	"currencyify/logging"
+
+	"github.com/beego/beego/v2/server/web/context"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccessLog(t *testing.T) {
+	// Setup
+	path := "/" + constants.API_PATH + "/convert/currency-convert"
+	var buf bytes.Buffer
+	handler, _ := logging.NewHandler(&buf, logging.FormatJSON, "")
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(handler))
+	defer slog.SetDefault(defaultLogger)
+	findRoute = func(*context.Context) (string, bool) {
+		return path, true
+	}
+	defer func() {
+		findRoute = findRoutePattern
+	}()
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, path, nil)
+	req.Header.Set(logging.RequestIDHeader, "req-1")
+	ctx := context.NewContext()
+	ctx.Reset(rr, req)
+
+	// Run test
+	AccessLog(func(ctx *context.Context) {
+		RequestID(ctx)
+		abort(ctx, http.StatusTooManyRequests, errors.New("rate limit exceeded"))
+	})(ctx)
+
+	// Assert
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	got := make(map[string]interface{})
+	if assert.Len(t, lines, 2) && assert.NoError(t, json.Unmarshal(lines[1], &got)) {
+		assert.Equal(t, "request served", got["msg"])
+		assert.Equal(t, "req-1", got["request_id"])
+		assert.Equal(t, path, got["route"])
+		assert.Equal(t, float64(http.StatusTooManyRequests), got["status"])
+	}
+}
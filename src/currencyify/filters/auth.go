@@ -0,0 +1,121 @@
+package filters
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"currencyify/components/auth"
+	"currencyify/constants"
+	"currencyify/utils"
+
+	"github.com/beego/beego/v2/server/web"
+	"github.com/beego/beego/v2/server/web/context"
+)
+
+// PrincipalKey is the input data key holding the `auth.Principal` of the authenticated requests.
+const PrincipalKey = "principal"
+
+const apiKeyHeader = "X-API-Key"
+
+// publicPaths are the paths of the API namespace served without authentication.
+var publicPaths = map[string]bool{
+	"/healthcheck":               true,
+	"/livez":                     true,
+	"/readyz":                    true,
+	"/openapi.json":              true,
+	"/docs":                      true,
+	"/docs/swagger-ui-bundle.js": true,
+	"/docs/swagger-ui.css":       true,
+}
+
+// routeScopes maps the path prefixes of the API namespace to the scopes required to access them.
+var routeScopes = []struct {
+	prefix string
+	scopes []string
+}{
+	{prefix: "/convert", scopes: []string{auth.ScopeConvert}},
+	{prefix: "/exchange-rate", scopes: []string{auth.ScopeRates}},
+	{prefix: "/graphql", scopes: []string{auth.ScopeConvert, auth.ScopeRates}},
+}
+
+// authenticator authenticates the requests using the bearer tokens or the API keys.
+var authenticator = &auth.Authenticator{
+	RedisConn:   utils.Conn,
+	JWTVerifier: auth.DefaultJWTVerifier,
+}
+
+// Authenticate is the filter authenticating the requests of the API namespace using the bearer token passed in the
+// `Authorization` header or else the API key passed in the `X-API-Key` header, and authorizing them against the scopes
+// of the token or key. Public paths are served as is.
+// It aborts the request with 401 when the credentials are missing or invalid and with 403 when a required scope isn't
+// granted. Invalid credentials take a token of the client IP, the request being aborted with 429 once it is exhausted.
+func Authenticate(ctx *context.Context) {
+	if constants.AUTH_ENABLED != "true" {
+		return
+	}
+
+	path := strings.TrimPrefix(ctx.Input.URL(), fmt.Sprintf("/%v", constants.API_PATH))
+	if IsPublicPath(path) {
+		return
+	}
+
+	principal, err := authenticator.Authenticate(auth.Credentials{
+		Authorization: ctx.Input.Header("Authorization"),
+		APIKey:        ctx.Input.Header(apiKeyHeader),
+	})
+	if auth.IsUnauthenticated(err) {
+		if hasCredentials(ctx) && rateLimitFailedAuth(ctx, path) {
+			return
+		}
+		abort(ctx, http.StatusUnauthorized, err)
+		return
+	} else if err != nil {
+		abort(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	for _, scope := range RequiredScopes(path) {
+		if !principal.HasScope(scope) {
+			abort(ctx, http.StatusForbidden, fmt.Errorf("credentials don't have the `%s` scope", scope))
+			return
+		}
+	}
+
+	ctx.Input.SetData(PrincipalKey, principal)
+}
+
+// hasCredentials checks whether the request passes a bearer token or an API key.
+func hasCredentials(ctx *context.Context) bool {
+	return ctx.Input.Header("Authorization") != "" || ctx.Input.Header(apiKeyHeader) != ""
+}
+
+// IsPublicPath checks whether the given path of the API namespace is served without authentication.
+func IsPublicPath(path string) bool {
+	return publicPaths[path]
+}
+
+// RequiredScopes is used to get the scopes required to access the given path of the API namespace. Paths not listed
+// in `routeScopes` require the admin scope.
+// It returns the required scopes.
+func RequiredScopes(path string) []string {
+	for _, route := range routeScopes {
+		if path == route.prefix || strings.HasPrefix(path, route.prefix+"/") {
+			return route.scopes
+		}
+	}
+
+	return []string{auth.ScopeAdmin}
+}
+
+// abort serves the error response of the rejected request, stopping the execution of the filters and controllers.
+func abort(ctx *context.Context, status int, err error) {
+	slog.WarnContext(ctx.Request.Context(), "Request rejected", "status", status, "error", err)
+	if status == http.StatusUnauthorized {
+		ctx.Output.Header("WWW-Authenticate", fmt.Sprintf(`Bearer realm="currencyify", ApiKey header="%s"`, apiKeyHeader))
+	}
+	ctx.Output.Header("Cache-Control", "no-store, max-age=0")
+	ctx.Output.SetStatus(status)
+	_ = ctx.Output.JSON(utils.PrepareResponse(nil, err, status), web.BConfig.RunMode != web.PROD, false)
+}
@@ -0,0 +1,52 @@
+package filters
+
+import (
+	"fmt"
+	"net/http"
+
+	"currencyify/components/cors"
+
+	"github.com/beego/beego/v2/server/web/context"
+)
+
+// corsPolicy gets the CORS policy of the browser clients, nil when CORS isn't configured.
+var corsPolicy = cors.DefaultPolicy
+
+// CORS is the filter applying the CORS policy set by the `CORS` section of the app config to the requests of the API
+// namespace. It answers the preflight `OPTIONS` requests itself, before they are authenticated, and sets the CORS
+// headers of the responses to the allowed origins.
+// It aborts the preflight request with 403 when its origin, method or a header isn't allowed.
+func CORS(ctx *context.Context) {
+	policy := corsPolicy()
+	if policy == nil {
+		return
+	}
+
+	origin := ctx.Input.Header(cors.HeaderOrigin)
+	if ctx.Input.Method() == http.MethodOptions && origin != "" && ctx.Input.Header(cors.HeaderRequestMethod) != "" {
+		headers, err := policy.PreflightHeaders(origin, ctx.Input.Header(cors.HeaderRequestMethod), ctx.Input.Header(cors.HeaderRequestHeaders))
+		setCORSHeaders(ctx, headers)
+		if err != nil {
+			abort(ctx, http.StatusForbidden, fmt.Errorf("CORS preflight rejected: %w", err))
+			return
+		}
+
+		ctx.Output.SetStatus(http.StatusNoContent)
+		_ = ctx.Output.Body(nil)
+		return
+	}
+
+	setCORSHeaders(ctx, policy.Headers(origin))
+}
+
+// setCORSHeaders sets the given CORS headers of the response, adding to its `Vary` header, so the headers the response
+// varies by, i.e. `Accept`, added by the controllers are kept.
+func setCORSHeaders(ctx *context.Context, headers map[string]string) {
+	for header, value := range headers {
+		if header == "Vary" {
+			ctx.ResponseWriter.Header().Add(header, value)
+			continue
+		}
+		ctx.Output.Header(header, value)
+	}
+}
@@ -0,0 +1,135 @@
+package filters
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"currencyify/components/cors"
+	"currencyify/constants"
+
+	"github.com/beego/beego/v2/server/web/context"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCORS(t *testing.T) {
+	policy, _ := cors.NewPolicy(cors.Policy{
+		AllowOrigins:  []string{"https://*.example.com"},
+		AllowHeaders:  []string{"Content-Type", "X-API-Key"},
+		ExposeHeaders: []string{"ETag"},
+		MaxAge:        10 * time.Minute,
+	})
+
+	type vars struct {
+		configured bool
+		method     string
+		headers    map[string]string
+	}
+
+	testCases := []struct {
+		name string
+
+		vars vars
+
+		wantStatus  int
+		wantBody    string
+		wantHeaders map[string]string
+	}{
+		{
+			name: "should serve the request as is when CORS isn't configured",
+			vars: vars{
+				method:  http.MethodOptions,
+				headers: map[string]string{"Origin": "https://app.example.com", "Access-Control-Request-Method": "POST"},
+			},
+			wantHeaders: map[string]string{"Access-Control-Allow-Origin": "", "Vary": ""},
+		},
+		{
+			name: "should set the CORS headers of the request of the allowed origin",
+			vars: vars{
+				configured: true,
+				method:     http.MethodPost,
+				headers:    map[string]string{"Origin": "https://app.example.com"},
+			},
+			wantHeaders: map[string]string{"Access-Control-Allow-Origin": "https://app.example.com", "Access-Control-Expose-Headers": "ETag", "Vary": "Origin"},
+		},
+		{
+			name: "should not set the CORS headers of the request of the other origins",
+			vars: vars{
+				configured: true,
+				method:     http.MethodGet,
+				headers:    map[string]string{"Origin": "https://evil.com"},
+			},
+			wantHeaders: map[string]string{"Access-Control-Allow-Origin": "", "Vary": "Origin"},
+		},
+		{
+			name: "should answer the preflight request",
+			vars: vars{
+				configured: true,
+				method:     http.MethodOptions,
+				headers:    map[string]string{"Origin": "https://app.example.com", "Access-Control-Request-Method": "POST", "Access-Control-Request-Headers": "content-type,x-api-key"},
+			},
+			wantStatus: http.StatusNoContent,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin":  "https://app.example.com",
+				"Access-Control-Allow-Methods": "GET, POST, HEAD",
+				"Access-Control-Allow-Headers": "content-type, x-api-key",
+				"Access-Control-Max-Age":       "600",
+			},
+		},
+		{
+			name: "should reject the preflight request of the other origins",
+			vars: vars{
+				configured: true,
+				method:     http.MethodOptions,
+				headers:    map[string]string{"Origin": "https://evil.com", "Access-Control-Request-Method": "POST"},
+			},
+			wantStatus:  http.StatusForbidden,
+			wantBody:    "CORS preflight rejected: origin (https://evil.com) isn't allowed",
+			wantHeaders: map[string]string{"Access-Control-Allow-Origin": ""},
+		},
+		{
+			name: "should serve the OPTIONS request other than preflight as is",
+			vars: vars{
+				configured: true,
+				method:     http.MethodOptions,
+				headers:    map[string]string{"Origin": "https://app.example.com"},
+			},
+			wantHeaders: map[string]string{"Access-Control-Allow-Origin": "https://app.example.com", "Access-Control-Allow-Methods": ""},
+		},
+	}
+
+	for _, tCase := range testCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			// Setup
+			corsPolicy = func() *cors.Policy {
+				if !tCase.vars.configured {
+					return nil
+				}
+				return policy
+			}
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(tCase.vars.method, "/"+constants.API_PATH+"/convert/currency-convert", nil)
+			for header, value := range tCase.vars.headers {
+				req.Header.Set(header, value)
+			}
+			ctx := context.NewContext()
+			ctx.Reset(rr, req)
+
+			// Run test
+			CORS(ctx)
+
+			// Assert
+			if tCase.wantStatus != 0 {
+				assert.Equal(t, tCase.wantStatus, rr.Code, "case: %v", tCase)
+				assert.Contains(t, rr.Body.String(), tCase.wantBody, "case: %v", tCase)
+			} else {
+				assert.Empty(t, rr.Body.String(), "case: %v", tCase)
+			}
+			for header, want := range tCase.wantHeaders {
+				assert.Equal(t, want, rr.Header().Get(header), "case: %v, header: %v", tCase, header)
+			}
+		})
+	}
+	corsPolicy = cors.DefaultPolicy
+}
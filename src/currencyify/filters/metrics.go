@@ -0,0 +1,67 @@
+package filters
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"currencyify/metrics"
+
+	"github.com/beego/beego/v2/server/web"
+	"github.com/beego/beego/v2/server/web/context"
+)
+
+// unmatchedRoute labels the requests not matching any route, so the paths of their URLs don't blow up the metrics.
+const unmatchedRoute = "unmatched"
+
+// observeHTTPRequest observes the served HTTP request.
+var observeHTTPRequest = metrics.ObserveHTTPRequest
+
+// findRoute finds the pattern of the route matching the request, as the requests rejected by the filters aren't routed.
+var findRoute = findRoutePattern
+
+// findRoutePattern is used to find the pattern of the route matching the request in the routes of the app.
+// It returns the pattern and whether the route is found.
+func findRoutePattern(ctx *context.Context) (string, bool) {
+	if routerInfo, ok := web.BeeApp.Handlers.FindRouter(ctx); ok {
+		return routerInfo.GetPattern(), true
+	}
+
+	return "", false
+}
+
+// Metrics is the filter chain observing the count and latency of the requests of the API namespace, per route pattern,
+// method and status. Being a chain, it wraps the filters too, so the requests rejected by them are observed as well.
+func Metrics(next web.FilterFunc) web.FilterFunc {
+	return func(ctx *context.Context) {
+		start := time.Now()
+		next(ctx)
+
+		observeHTTPRequest(routePattern(ctx), ctx.Input.Method(), strconv.Itoa(responseStatus(ctx)), time.Since(start))
+	}
+}
+
+// responseStatus is used to get the status of the served response, 200 when it isn't set.
+func responseStatus(ctx *context.Context) int {
+	status := ctx.ResponseWriter.Status
+	if status == 0 {
+		status = ctx.Output.Status
+	}
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	return status
+}
+
+// routePattern is used to get the pattern of the route matching the request, `unmatched` when there is none.
+func routePattern(ctx *context.Context) string {
+	if pattern, ok := ctx.Input.GetData("RouterPattern").(string); ok && pattern != "" {
+		return pattern
+	}
+	if pattern, ok := findRoute(ctx); ok {
+		return pattern
+	}
+
+	return unmatchedRoute
+}
@@ -0,0 +1,97 @@
+package filters
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"currencyify/constants"
+	"currencyify/metrics"
+
+	"github.com/beego/beego/v2/server/web/context"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics(t *testing.T) {
+	path := "/" + constants.API_PATH + "/convert/currency-convert"
+
+	type vars struct {
+		routerPattern string
+		foundRoute    string
+		status        int
+	}
+
+	testCases := []struct {
+		name string
+
+		vars vars
+
+		wantRoute  string
+		wantStatus string
+	}{
+		{
+			name: "should observe the routed request by its route pattern",
+			vars: vars{
+				routerPattern: path,
+				status:        http.StatusBadRequest,
+			},
+			wantRoute:  path,
+			wantStatus: "400",
+		},
+		{
+			name: "should observe the request rejected before routing by the pattern of its route",
+			vars: vars{
+				foundRoute: path,
+				status:     http.StatusTooManyRequests,
+			},
+			wantRoute:  path,
+			wantStatus: "429",
+		},
+		{
+			name:       "should observe the request not matching any route as unmatched",
+			vars:       vars{status: http.StatusNotFound},
+			wantRoute:  "unmatched",
+			wantStatus: "404",
+		},
+		{
+			name:       "should observe the request without status written as ok",
+			vars:       vars{routerPattern: path},
+			wantRoute:  path,
+			wantStatus: "200",
+		},
+	}
+
+	for _, tCase := range testCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			// Setup
+			var gotRoute, gotMethod, gotStatus string
+			observeHTTPRequest = func(route, method, status string, _ time.Duration) {
+				gotRoute, gotMethod, gotStatus = route, method, status
+			}
+			findRoute = func(*context.Context) (string, bool) {
+				return tCase.vars.foundRoute, tCase.vars.foundRoute != ""
+			}
+			rr := httptest.NewRecorder()
+			ctx := context.NewContext()
+			ctx.Reset(rr, httptest.NewRequest(http.MethodPost, path, nil))
+
+			// Run test
+			Metrics(func(ctx *context.Context) {
+				if tCase.vars.routerPattern != "" {
+					ctx.Input.SetData("RouterPattern", tCase.vars.routerPattern)
+				}
+				if tCase.vars.status != 0 {
+					ctx.Output.SetStatus(tCase.vars.status)
+				}
+			})(ctx)
+
+			// Assert
+			assert.Equal(t, tCase.wantRoute, gotRoute, "case: %v", tCase)
+			assert.Equal(t, http.MethodPost, gotMethod, "case: %v", tCase)
+			assert.Equal(t, tCase.wantStatus, gotStatus, "case: %v", tCase)
+		})
+	}
+	observeHTTPRequest = metrics.ObserveHTTPRequest
+	findRoute = findRoutePattern
+}
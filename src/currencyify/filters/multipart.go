@@ -0,0 +1,16 @@
+package filters
+
+import (
+	"currencyify/utils"
+
+	"github.com/beego/beego/v2/server/web/context"
+)
+
+// StreamMultipart is the filter of the upload routes streaming their files, run before Beego parses the request body,
+// so the multipart body is read part by part by the controller instead of being buffered up to `MaxMemory` and spilled
+// to temp files.
+func StreamMultipart(ctx *context.Context) {
+	if ctx.Input.IsUpload() {
+		utils.SkipMultipartParsing(ctx.Request)
+	}
+}
@@ -0,0 +1,53 @@
+package filters
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"currencyify/constants"
+
+	"github.com/beego/beego/v2/server/web/context"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamMultipart(t *testing.T) {
+	testCases := []struct {
+		name string
+
+		contentType string
+		body        string
+
+		wantSkipped bool
+	}{
+		{
+			name:        "should leave the multipart body unread",
+			contentType: "multipart/form-data; boundary=ledger",
+			body:        "--ledger\r\nContent-Disposition: form-data; name=\"target_currency\"\r\n\r\nINR\r\n--ledger--\r\n",
+			wantSkipped: true,
+		},
+		{
+			name:        "should parse the other bodies",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "target_currency=INR",
+		},
+	}
+
+	for _, tCase := range testCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			// Setup
+			req := httptest.NewRequest(http.MethodPost, "/"+constants.API_PATH+"/convert/currency-convert/csv", strings.NewReader(tCase.body))
+			req.Header.Set("Content-Type", tCase.contentType)
+			ctx := context.NewContext()
+			ctx.Reset(httptest.NewRecorder(), req)
+
+			// Run test
+			StreamMultipart(ctx)
+
+			// Assert
+			assert.NoErrorf(t, ctx.Input.ParseFormOrMultiForm(1<<20), "case: %v", tCase)
+			assert.Equalf(t, tCase.wantSkipped, ctx.Request.FormValue("target_currency") == "", "case: %v", tCase)
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package filters
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"currencyify/components/auth"
+	"currencyify/components/ratelimit"
+	"currencyify/constants"
+	"currencyify/utils"
+
+	"github.com/beego/beego/v2/server/web/context"
+)
+
+// limiter gets the limiter of the requests, nil when rate limiting isn't configured.
+var limiter = ratelimit.DefaultLimiter
+
+// ipRateLimitedKey is the input data key set once the request is rate limited by IP, before authentication.
+const ipRateLimitedKey = "ip_rate_limited"
+
+// RateLimitByIP is the filter limiting the requests of the API namespace by client IP before they are authenticated, so
+// the requests rejected by the authentication are limited too. Requests without credentials, or all of them when auth
+// is disabled, take a token of their IP in the `anonymous` tier. Requests with credentials only check the IP, their
+// failed authentications taking its tokens, so once the IP exhausted its limit or quotas, its requests are rejected with
+// 429 before their credentials are checked.
+func RateLimitByIP(ctx *context.Context) {
+	l := limiter()
+	if l == nil {
+		return
+	}
+
+	path := strings.TrimPrefix(ctx.Input.URL(), fmt.Sprintf("/%v", constants.API_PATH))
+	if IsPublicPath(path) {
+		return
+	}
+
+	if constants.AUTH_ENABLED == "true" && hasCredentials(ctx) {
+		result, err := l.Peek(ipClient(ctx), ratelimit.TierAnonymous, path)
+		if err != nil {
+			slog.ErrorContext(ctx.Request.Context(), "Error limiting the request rate, serving it as is", "error", err)
+		} else if !result.Allowed {
+			ctx.Output.Header("Retry-After", ceilSeconds(result.RetryAfter))
+			abort(ctx, http.StatusTooManyRequests, errors.New(result.Reason))
+		}
+		return
+	}
+
+	ctx.Input.SetData(ipRateLimitedKey, true)
+	limitRequest(ctx, l, ipClient(ctx), ratelimit.TierAnonymous, path)
+}
+
+// RateLimit is the filter limiting the authenticated requests of the API namespace per client, being the API key or
+// bearer token subject, using the limits and quotas of the client tier. Requests already limited by `RateLimitByIP` are
+// served as is.
+// It sets the `X-RateLimit-*` headers of the rate limited routes, and aborts the request with 429 along with the
+// `Retry-After` header when the limit or a quota is exceeded. Requests are served as is when the limiter fails.
+func RateLimit(ctx *context.Context) {
+	l := limiter()
+	if l == nil {
+		return
+	}
+
+	path := strings.TrimPrefix(ctx.Input.URL(), fmt.Sprintf("/%v", constants.API_PATH))
+	if limited, _ := ctx.Input.GetData(ipRateLimitedKey).(bool); limited || IsPublicPath(path) {
+		return
+	}
+
+	client, tier := ipClient(ctx), ratelimit.TierAnonymous
+	if principal, ok := ctx.Input.GetData(PrincipalKey).(*auth.Principal); ok {
+		client, tier = principal.Client(), principal.Tier
+	}
+
+	limitRequest(ctx, l, client, tier, path)
+}
+
+// rateLimitFailedAuth takes a token of the client IP for the request whose credentials are rejected, so guessing them
+// is rate limited.
+// It returns whether the request is aborted with 429, the IP having exhausted its limit or quotas.
+func rateLimitFailedAuth(ctx *context.Context, path string) bool {
+	l := limiter()
+	if l == nil {
+		return false
+	}
+
+	result, err := l.Allow(ipClient(ctx), ratelimit.TierAnonymous, path)
+	if err != nil {
+		slog.ErrorContext(ctx.Request.Context(), "Error limiting the request rate, serving it as is", "error", err)
+		return false
+	} else if result.Allowed {
+		return false
+	}
+
+	ctx.Output.Header("Retry-After", ceilSeconds(result.RetryAfter))
+	abort(ctx, http.StatusTooManyRequests, errors.New(result.Reason))
+
+	return true
+}
+
+// limitRequest takes a token of the client for the request, setting the `X-RateLimit-*` headers, and aborts it with 429
+// when the limit or a quota is exceeded.
+func limitRequest(ctx *context.Context, l *ratelimit.Limiter, client, tier, path string) {
+	result, err := l.Allow(client, tier, path)
+	if err != nil {
+		slog.ErrorContext(ctx.Request.Context(), "Error limiting the request rate, serving it as is", "error", err)
+		return
+	}
+
+	if result.Limit > 0 {
+		ctx.Output.Header("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+		ctx.Output.Header("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		ctx.Output.Header("X-RateLimit-Reset", ceilSeconds(result.Reset))
+	}
+
+	if !result.Allowed {
+		ctx.Output.Header("Retry-After", ceilSeconds(result.RetryAfter))
+		abort(ctx, http.StatusTooManyRequests, errors.New(result.Reason))
+	}
+}
+
+// ipClient gets the client of the request identified by its IP.
+func ipClient(ctx *context.Context) string {
+	return "ip:" + utils.ClientIP(ctx.Request)
+}
+
+// ceilSeconds formats the given duration as whole seconds, rounded up.
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
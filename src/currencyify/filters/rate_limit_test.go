@@ -0,0 +1,264 @@
+package filters
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"currencyify/components/auth"
+	"currencyify/components/ratelimit"
+	"currencyify/constants"
+	"currencyify/utils"
+
+	"github.com/beego/beego/v2/server/web/context"
+	"github.com/gomodule/redigo/redis"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimit(t *testing.T) {
+	type vars struct {
+		configured bool
+		path       string
+		principal  *auth.Principal
+		requests   int
+	}
+
+	testCases := []struct {
+		name string
+
+		vars vars
+
+		wantStatus  int
+		wantHeaders map[string]string
+	}{
+		{
+			name: "should serve the request as is when rate limiting isn't configured",
+			vars: vars{
+				path:     "/convert/currency-convert",
+				requests: 3,
+			},
+			wantHeaders: map[string]string{"X-RateLimit-Limit": ""},
+		},
+		{
+			name: "should serve the public paths without limit",
+			vars: vars{
+				configured: true,
+				path:       "/healthcheck",
+				requests:   3,
+			},
+			wantHeaders: map[string]string{"X-RateLimit-Limit": ""},
+		},
+		{
+			name: "should set the rate limit headers of the anonymous client",
+			vars: vars{
+				configured: true,
+				path:       "/exchange-rate/currency-exchange-rate",
+				requests:   1,
+			},
+			wantHeaders: map[string]string{"X-RateLimit-Limit": "2", "X-RateLimit-Remaining": "1", "X-RateLimit-Reset": "30"},
+		},
+		{
+			name: "should reject the request when the limit is exceeded",
+			vars: vars{
+				configured: true,
+				path:       "/exchange-rate/currency-exchange-rate",
+				requests:   3,
+			},
+			wantStatus:  http.StatusTooManyRequests,
+			wantHeaders: map[string]string{"X-RateLimit-Remaining": "0", "Retry-After": "30"},
+		},
+		{
+			name: "should use the limits of the principal tier",
+			vars: vars{
+				configured: true,
+				path:       "/exchange-rate/currency-exchange-rate",
+				principal:  &auth.Principal{ID: "a1b2c3", Tier: "premium", Method: auth.MethodAPIKey},
+				requests:   3,
+			},
+			wantHeaders: map[string]string{"X-RateLimit-Limit": "10", "X-RateLimit-Remaining": "7"},
+		},
+	}
+
+	for _, tCase := range testCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			// Setup
+			conn := utils.NewMockRedisConn()
+			limiter = func() *ratelimit.Limiter {
+				if !tCase.vars.configured {
+					return nil
+				}
+				return ratelimit.NewLimiter(func() (redis.Conn, error) {
+					return conn, nil
+				}, ratelimit.Config{Limits: map[string]map[string]ratelimit.Limit{
+					ratelimit.TierDefault: {"": {Burst: 2, Period: time.Minute}},
+					"premium":             {"": {Burst: 10, Period: time.Minute}},
+				}})
+			}
+
+			// Run test
+			var rr *httptest.ResponseRecorder
+			for i := 0; i < tCase.vars.requests; i++ {
+				rr = httptest.NewRecorder()
+				req := httptest.NewRequest(http.MethodGet, "/"+constants.API_PATH+tCase.vars.path, nil)
+				ctx := context.NewContext()
+				ctx.Reset(rr, req)
+				if tCase.vars.principal != nil {
+					ctx.Input.SetData(PrincipalKey, tCase.vars.principal)
+				}
+				RateLimit(ctx)
+			}
+
+			// Assert
+			if tCase.wantStatus != 0 {
+				assert.Equal(t, tCase.wantStatus, rr.Code, "case: %v", tCase)
+				assert.Contains(t, rr.Body.String(), "rate limit of 2 requests per 1m0s exceeded", "case: %v", tCase)
+			} else {
+				assert.Empty(t, rr.Body.String(), "case: %v", tCase)
+			}
+			for header, want := range tCase.wantHeaders {
+				assert.Equal(t, want, rr.Header().Get(header), "case: %v, header: %v", tCase, header)
+			}
+		})
+	}
+	limiter = ratelimit.DefaultLimiter
+}
+
+func TestRateLimitByIP(t *testing.T) {
+	type request struct {
+		forwardedFor string
+		key          string
+	}
+
+	type vars struct {
+		enabled  string
+		requests []request
+	}
+
+	testCases := []struct {
+		name string
+
+		vars vars
+
+		wantStatus  int
+		wantBody    string
+		wantHeaders map[string]string
+	}{
+		{
+			name: "should limit the anonymous requests by the address of the connection",
+			vars: vars{
+				requests: []request{{}},
+			},
+			wantHeaders: map[string]string{"X-RateLimit-Limit": "2", "X-RateLimit-Remaining": "1"},
+		},
+		{
+			name: "should not give a fresh bucket to the clients rotating X-Forwarded-For",
+			vars: vars{
+				requests: []request{{forwardedFor: "198.51.100.1"}, {forwardedFor: "198.51.100.2"}, {forwardedFor: "198.51.100.3"}},
+			},
+			wantStatus: http.StatusTooManyRequests,
+			wantBody:   "rate limit of 2 requests per 1m0s exceeded",
+		},
+		{
+			name: "should only check the IP of the requests with credentials",
+			vars: vars{
+				enabled:  "true",
+				requests: []request{{key: "cfy_key"}, {key: "cfy_key"}, {key: "cfy_key"}},
+			},
+			wantHeaders: map[string]string{"X-RateLimit-Limit": ""},
+		},
+		{
+			name: "should reject the requests with credentials once the IP exhausted its limit",
+			vars: vars{
+				enabled:  "true",
+				requests: []request{{}, {}, {key: "cfy_key"}},
+			},
+			wantStatus:  http.StatusTooManyRequests,
+			wantBody:    "rate limit of 2 requests per 1m0s exceeded",
+			wantHeaders: map[string]string{"Retry-After": "30"},
+		},
+	}
+
+	for _, tCase := range testCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			// Setup
+			constants.AUTH_ENABLED = tCase.vars.enabled
+			conn := utils.NewMockRedisConn()
+			limiter = func() *ratelimit.Limiter {
+				return ratelimit.NewLimiter(func() (redis.Conn, error) {
+					return conn, nil
+				}, ratelimit.Config{Limits: map[string]map[string]ratelimit.Limit{
+					ratelimit.TierDefault: {"": {Burst: 2, Period: time.Minute}},
+				}})
+			}
+
+			// Run test
+			var rr *httptest.ResponseRecorder
+			for _, r := range tCase.vars.requests {
+				rr = httptest.NewRecorder()
+				req := httptest.NewRequest(http.MethodGet, "/"+constants.API_PATH+"/exchange-rate/currency-exchange-rate", nil)
+				if r.forwardedFor != "" {
+					req.Header.Set("X-Forwarded-For", r.forwardedFor)
+				}
+				if r.key != "" {
+					req.Header.Set(apiKeyHeader, r.key)
+				}
+				ctx := context.NewContext()
+				ctx.Reset(rr, req)
+				RateLimitByIP(ctx)
+			}
+
+			// Assert
+			if tCase.wantStatus != 0 {
+				assert.Equal(t, tCase.wantStatus, rr.Code, "case: %v", tCase)
+				assert.Contains(t, rr.Body.String(), tCase.wantBody, "case: %v", tCase)
+			} else {
+				assert.Empty(t, rr.Body.String(), "case: %v", tCase)
+			}
+			for header, want := range tCase.wantHeaders {
+				assert.Equal(t, want, rr.Header().Get(header), "case: %v, header: %v", tCase, header)
+			}
+		})
+	}
+	constants.AUTH_ENABLED = ""
+	limiter = ratelimit.DefaultLimiter
+}
+
+func TestAuthenticate_rateLimitsFailedAuth(t *testing.T) {
+	// Setup
+	constants.AUTH_ENABLED = "true"
+	conn := utils.NewMockRedisConn()
+	authenticator = &auth.Authenticator{
+		RedisConn: func() (redis.Conn, error) {
+			return conn, nil
+		},
+	}
+	limiter = func() *ratelimit.Limiter {
+		return ratelimit.NewLimiter(func() (redis.Conn, error) {
+			return conn, nil
+		}, ratelimit.Config{Limits: map[string]map[string]ratelimit.Limit{
+			ratelimit.TierDefault: {"": {Burst: 2, Period: time.Minute}},
+		}})
+	}
+
+	// Run test
+	var codes []int
+	for i := 0; i < 4; i++ {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/"+constants.API_PATH+"/exchange-rate/currency-exchange-rate", nil)
+		req.Header.Set(apiKeyHeader, "cfy_guessed")
+		ctx := context.NewContext()
+		ctx.Reset(rr, req)
+		RateLimitByIP(ctx)
+		if rr.Code == http.StatusOK && rr.Body.Len() == 0 {
+			Authenticate(ctx)
+		}
+		codes = append(codes, rr.Code)
+	}
+
+	// Assert
+	assert.Equal(t, []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests, http.StatusTooManyRequests}, codes)
+	constants.AUTH_ENABLED = ""
+	authenticator = &auth.Authenticator{RedisConn: utils.Conn, JWTVerifier: auth.DefaultJWTVerifier}
+	limiter = ratelimit.DefaultLimiter
+}
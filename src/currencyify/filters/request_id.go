@@ -0,0 +1,16 @@
+package filters
+
+import (
+	"currencyify/logging"
+
+	"github.com/beego/beego/v2/server/web/context"
+)
+
+// RequestID is the filter identifying the requests of the API namespace by their `X-Request-ID` header, or else a new
+// request ID when it is missing or invalid. The request ID is carried by the request context, so the log lines of the
+// request are correlated, and echoed in the `X-Request-ID` header of the response.
+func RequestID(ctx *context.Context) {
+	requestID := logging.PropagateRequestID(ctx.Input.Header(logging.RequestIDHeader))
+	ctx.Request = ctx.Request.WithContext(logging.NewContext(ctx.Request.Context(), requestID))
+	ctx.Output.Header(logging.RequestIDHeader, requestID)
+}
@@ -0,0 +1,61 @@
+package filters
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"currencyify/constants"
+	"currencyify/logging"
+
+	"github.com/beego/beego/v2/server/web/context"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestID(t *testing.T) {
+	testCases := []struct {
+		name string
+
+		requestID string
+
+		wantRequestID string
+	}{
+		{
+			name:          "should propagate the request ID given by the client",
+			requestID:     "4bf92f35-77b3-4da6-a3ce-929d0e0e4736",
+			wantRequestID: "4bf92f35-77b3-4da6-a3ce-929d0e0e4736",
+		},
+		{
+			name: "should generate the request ID when the client doesn't give one",
+		},
+		{
+			name:      "should generate the request ID when the given one is invalid",
+			requestID: "req 1\r\nX-Injected: true",
+		},
+	}
+
+	for _, tCase := range testCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			// Setup
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/"+constants.API_PATH+"/convert/currency-convert", nil)
+			if tCase.requestID != "" {
+				req.Header.Set(logging.RequestIDHeader, tCase.requestID)
+			}
+			ctx := context.NewContext()
+			ctx.Reset(rr, req)
+
+			// Run test
+			RequestID(ctx)
+
+			// Assert
+			got := rr.Header().Get(logging.RequestIDHeader)
+			if tCase.wantRequestID != "" {
+				assert.Equal(t, tCase.wantRequestID, got, "case: %v", tCase)
+			} else {
+				assert.Regexp(t, `^[0-9a-f]{32}$`, got, "case: %v", tCase)
+			}
+			assert.Equal(t, got, logging.RequestID(ctx.Request.Context()), "case: %v", tCase)
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package filters
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"currencyify/components/auth"
+	"currencyify/utils"
+
+	"github.com/beego/beego/v2/server/web/context"
+)
+
+// signatureVerifier verifies the signatures of the requests of the API keys having signing secrets.
+var signatureVerifier = auth.NewSignatureVerifier(utils.Conn)
+
+// VerifySignature is the filter verifying the signatures of the requests authenticated with the API keys having signing
+// secrets, passed in the `X-Signature`, `X-Signature-Timestamp` and `X-Signature-Nonce` headers. Requests of the other
+// clients are served as is.
+// It aborts the request with 401 when the signature is missing or invalid, the timestamp is skewed, the nonce is
+// replayed or the request is multipart, its body not being covered by the signature.
+func VerifySignature(ctx *context.Context) {
+	principal, ok := ctx.Input.GetData(PrincipalKey).(*auth.Principal)
+	if !ok || principal.SigningSecret == "" {
+		return
+	}
+
+	if mediaType, _, _ := mime.ParseMediaType(ctx.Input.Header("Content-Type")); strings.HasPrefix(mediaType, "multipart/") {
+		// multipart bodies aren't buffered, so their signature couldn't cover them
+		abort(ctx, http.StatusUnauthorized, fmt.Errorf("%w: multipart requests can't be signed", auth.ErrInvalidSignature))
+		return
+	}
+
+	err := signatureVerifier.Verify(principal, auth.SignedRequest{
+		Method:    ctx.Input.Method(),
+		URI:       ctx.Input.URI(),
+		Body:      ctx.Input.RequestBody,
+		Timestamp: ctx.Input.Header(auth.SignatureTimestampHeader),
+		Nonce:     ctx.Input.Header(auth.SignatureNonceHeader),
+		Signature: ctx.Input.Header(auth.SignatureHeader),
+	})
+	if errors.Is(err, auth.ErrInvalidSignature) {
+		abort(ctx, http.StatusUnauthorized, err)
+	} else if err != nil {
+		abort(ctx, http.StatusInternalServerError, err)
+	}
+}
@@ -0,0 +1,137 @@
+package filters
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"currencyify/components/auth"
+	"currencyify/constants"
+	"currencyify/utils"
+
+	"github.com/beego/beego/v2/server/web/context"
+	"github.com/gomodule/redigo/redis"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifySignature(t *testing.T) {
+	conn := utils.NewMockRedisConn()
+	signatureVerifier = auth.NewSignatureVerifier(func() (redis.Conn, error) {
+		return conn, nil
+	})
+	uri := "/" + constants.API_PATH + "/convert/currency-convert"
+	body := []byte(`{"from":"USD","to":"INR","amount":1}`)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	signed := &auth.Principal{ID: "a1b2c3", SigningSecret: "cfys_secret", Method: auth.MethodAPIKey}
+
+	type vars struct {
+		principal   *auth.Principal
+		contentType string
+		nonce       string
+		signature   string
+	}
+
+	testCases := []struct {
+		name string
+
+		vars vars
+
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name: "should serve the request of the principal without signing secret as is",
+			vars: vars{
+				principal: &auth.Principal{ID: "d4e5f6", Method: auth.MethodAPIKey},
+			},
+		},
+		{
+			name: "should serve the request as is when auth is disabled",
+			vars: vars{},
+		},
+		{
+			name: "should serve the signed request",
+			vars: vars{
+				principal: signed,
+				nonce:     "n1",
+				signature: auth.Sign(signed.SigningSecret, auth.SignedRequest{Method: http.MethodPost, URI: uri, Body: body, Timestamp: timestamp, Nonce: "n1"}),
+			},
+		},
+		{
+			name: "should reject the unsigned request",
+			vars: vars{
+				principal: signed,
+			},
+			wantStatus: http.StatusUnauthorized,
+			wantBody:   "headers are required",
+		},
+		{
+			name: "should reject the request having invalid signature",
+			vars: vars{
+				principal: signed,
+				nonce:     "n2",
+				signature: auth.Sign("cfys_other", auth.SignedRequest{Method: http.MethodPost, URI: uri, Body: body, Timestamp: timestamp, Nonce: "n2"}),
+			},
+			wantStatus: http.StatusUnauthorized,
+			wantBody:   "signature mismatch",
+		},
+		{
+			name: "should reject the replayed request",
+			vars: vars{
+				principal: signed,
+				nonce:     "n1",
+				signature: auth.Sign(signed.SigningSecret, auth.SignedRequest{Method: http.MethodPost, URI: uri, Body: body, Timestamp: timestamp, Nonce: "n1"}),
+			},
+			wantStatus: http.StatusUnauthorized,
+			wantBody:   "nonce is already used",
+		},
+		{
+			name: "should reject the signed multipart request",
+			vars: vars{
+				principal:   signed,
+				contentType: "multipart/form-data; boundary=x",
+				nonce:       "n3",
+				signature:   auth.Sign(signed.SigningSecret, auth.SignedRequest{Method: http.MethodPost, URI: uri, Timestamp: timestamp, Nonce: "n3"}),
+			},
+			wantStatus: http.StatusUnauthorized,
+			wantBody:   "multipart requests can't be signed",
+		},
+	}
+
+	for _, tCase := range testCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			// Setup
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, uri, bytes.NewReader(body))
+			if tCase.vars.contentType != "" {
+				req.Header.Set("Content-Type", tCase.vars.contentType)
+			}
+			if tCase.vars.nonce != "" {
+				req.Header.Set(auth.SignatureTimestampHeader, timestamp)
+				req.Header.Set(auth.SignatureNonceHeader, tCase.vars.nonce)
+				req.Header.Set(auth.SignatureHeader, tCase.vars.signature)
+			}
+			ctx := context.NewContext()
+			ctx.Reset(rr, req)
+			ctx.Input.RequestBody = body
+			if tCase.vars.principal != nil {
+				ctx.Input.SetData(PrincipalKey, tCase.vars.principal)
+			}
+
+			// Run test
+			VerifySignature(ctx)
+
+			// Assert
+			if tCase.wantStatus != 0 {
+				assert.Equal(t, tCase.wantStatus, rr.Code, "case: %v", tCase)
+				assert.Contains(t, rr.Body.String(), tCase.wantBody, "case: %v", tCase)
+			} else {
+				assert.Empty(t, rr.Body.String(), "case: %v", tCase)
+			}
+		})
+	}
+	signatureVerifier = auth.NewSignatureVerifier(utils.Conn)
+}
@@ -0,0 +1,130 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"currencyify/constants"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Formats of the log lines.
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// RequestIDHeader is the header of the ID the clients give to their requests, echoed in the responses.
+const RequestIDHeader = "X-Request-ID"
+
+// requestIDPattern matches the request IDs given by the clients which are propagated, others are replaced by a new ID.
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+type requestIDKey struct{}
+
+// Init is used to set up the default logger, also used by the `log` package, writing the `LOG_LEVEL` (`debug`,
+// `info`, `warn` or `error`, `info` by default) lines to stderr in the `LOG_FORMAT` format (`text` or `json`, `text`
+// by default). Lines logged with the request context carry its request ID and trace ID.
+// It returns error for the invalid config.
+func Init() error {
+	handler, err := NewHandler(os.Stderr, constants.LOG_FORMAT, constants.LOG_LEVEL)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(slog.New(handler))
+
+	return nil
+}
+
+// NewHandler is used to create a new handler writing the lines of the given level, or above, to the given writer in
+// the given format, adding the request ID and trace ID of the context of the lines.
+// It returns the handler and error for the unknown format or level.
+func NewHandler(w io.Writer, format, level string) (slog.Handler, error) {
+	var lvl slog.Level
+	if level != "" {
+		if err := lvl.UnmarshalText([]byte(level)); err != nil {
+			return nil, fmt.Errorf("invalid log level (%s)", level)
+		}
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl}
+	switch strings.ToLower(format) {
+	case "", FormatText:
+		return contextHandler{slog.NewTextHandler(w, opts)}, nil
+	case FormatJSON:
+		return contextHandler{slog.NewJSONHandler(w, opts)}, nil
+	default:
+		return nil, fmt.Errorf("invalid log format (%s)", format)
+	}
+}
+
+// NewContext is used to get the context carrying the given request ID, for the lines logged with it.
+func NewContext(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestID is used to get the request ID carried by the context, empty when there is none.
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+
+	return requestID
+}
+
+// NewRequestID is used to generate a random request ID, for the requests not passing their own.
+// It returns the request ID.
+func NewRequestID() string {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+
+	return hex.EncodeToString(id)
+}
+
+// PropagateRequestID is used to get the ID of the request, the given one when it is valid, or else a new one.
+func PropagateRequestID(requestID string) string {
+	if requestIDPattern.MatchString(requestID) {
+		return requestID
+	}
+
+	return NewRequestID()
+}
+
+// contextHandler adds the request ID and trace ID of the context to the lines.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if requestID := RequestID(ctx); requestID != "" {
+		r.AddAttrs(slog.String("request_id", requestID))
+	}
+	if ctx != nil {
+		if spanCtx := trace.SpanContextFromContext(ctx); spanCtx.HasTraceID() {
+			r.AddAttrs(slog.String("trace_id", spanCtx.TraceID().String()))
+		}
+	}
+
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
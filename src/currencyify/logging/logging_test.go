@@ -0,0 +1,126 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestNewHandler(t *testing.T) {
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	tracedCtx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID}))
+
+	type vars struct {
+		format string
+		level  string
+		ctx    context.Context
+	}
+
+	testCases := []struct {
+		name string
+
+		vars vars
+
+		want   map[string]interface{}
+		hasErr bool
+		err    string
+	}{
+		{
+			name: "should log the JSON line along with the request ID and trace ID of the context",
+			vars: vars{
+				format: FormatJSON,
+				ctx:    NewContext(tracedCtx, "req-1"),
+			},
+			want: map[string]interface{}{
+				"level":      "WARN",
+				"msg":        "vendor API call budget exhausted",
+				"component":  "convert",
+				"currency":   "INR",
+				"request_id": "req-1",
+				"trace_id":   "4bf92f3577b34da6a3ce929d0e0e4736",
+			},
+		},
+		{
+			name: "should log the JSON line without request ID outside of the requests",
+			vars: vars{
+				format: FormatJSON,
+				ctx:    context.Background(),
+			},
+			want: map[string]interface{}{
+				"level":     "WARN",
+				"msg":       "vendor API call budget exhausted",
+				"component": "convert",
+				"currency":  "INR",
+			},
+		},
+		{
+			name: "should not log the lines below the level",
+			vars: vars{
+				format: FormatText,
+				level:  "error",
+				ctx:    NewContext(context.Background(), "req-1"),
+			},
+		},
+		{
+			name: "should fail for the unknown format",
+			vars: vars{
+				format: "xml",
+			},
+			hasErr: true,
+			err:    "invalid log format (xml)",
+		},
+		{
+			name: "should fail for the unknown level",
+			vars: vars{
+				level: "verbose",
+			},
+			hasErr: true,
+			err:    "invalid log level (verbose)",
+		},
+	}
+
+	for _, tCase := range testCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			// Setup
+			var buf bytes.Buffer
+
+			// Run test
+			handler, err := NewHandler(&buf, tCase.vars.format, tCase.vars.level)
+
+			// Assert
+			if tCase.hasErr {
+				if assert.Errorf(t, err, "case: %v", tCase) {
+					assert.EqualErrorf(t, err, tCase.err, "case: %v", tCase)
+				}
+				return
+			}
+			assert.NoErrorf(t, err, "case: %v", tCase)
+
+			slog.New(handler).With("component", "convert").WarnContext(tCase.vars.ctx, "vendor API call budget exhausted", "currency", "INR")
+			if tCase.want == nil {
+				assert.Empty(t, buf.String(), "case: %v", tCase)
+				return
+			}
+			got := make(map[string]interface{})
+			if assert.NoError(t, json.Unmarshal(buf.Bytes(), &got), "case: %v", tCase) {
+				delete(got, "time")
+				assert.Equal(t, tCase.want, got, "case: %v", tCase)
+			}
+		})
+	}
+}
+
+func TestPropagateRequestID(t *testing.T) {
+	assert.Equal(t, "4bf92f35-77b3-4da6", PropagateRequestID("4bf92f35-77b3-4da6"))
+	assert.Regexp(t, `^[0-9a-f]{32}$`, PropagateRequestID(""))
+	assert.Regexp(t, `^[0-9a-f]{32}$`, PropagateRequestID("req 1\r\n"))
+	assert.Regexp(t, `^[0-9a-f]{32}$`, NewRequestID())
+	assert.NotEqual(t, NewRequestID(), NewRequestID())
+	assert.Equal(t, "", RequestID(context.Background()))
+}
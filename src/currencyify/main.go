@@ -1,10 +1,23 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
 
+	"currencyify/cli"
+	"currencyify/components/audit"
+	"currencyify/components/registry"
 	"currencyify/constants"
+	"currencyify/logging"
 	"currencyify/routers"
+	"currencyify/rpc"
+	"currencyify/tracing"
+	"currencyify/utils"
 
 	_ "github.com/beego/beego/v2/core/config/yaml"
 	"github.com/beego/beego/v2/server/web"
@@ -12,8 +25,16 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		if err := cli.Run(os.Args[1:], os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	// Generated using http://patorjk.com/software/taag/#p=display&f=Graffiti
-	log.Printf(`
+	if constants.LOG_FORMAT != logging.FormatJSON {
+		fmt.Fprintln(os.Stderr, `
                                                                .__   _____        
   ____   __ __ _______ _______   ____    ____    ____  ___.__.|__|_/ ____\___.__.
 _/ ___\ |  |  \\_  __ \\_  __ \_/ __ \  /    \ _/ ___\<   |  ||  |\   __\<   |  |
@@ -21,7 +42,34 @@ _/ ___\ |  |  \\_  __ \\_  __ \_/ __ \  /    \ _/ ___\<   |  ||  |\   __\<   |
  \___  >|____/  |__|    |__|    \___  >|___|  / \___  >/ ____||__| |__|   / ____|
      \/                             \/      \/      \/ \/                 \/      
 	`)
-	web.BConfig.Log.AccessLogs = true
+	}
+	shutdownTracing, err := tracing.Init()
+	if err != nil {
+		log.Fatal("Error initializing tracing: ", err)
+	}
+	// the gRPC server is started once tracing is initialized, so its first RPCs are traced too
+	go func() {
+		if err := rpc.Run(constants.GRPC_PORT); err != nil {
+			log.Fatal("Error running gRPC server: ", err)
+		}
+	}()
+	go func() {
+		// web.Run doesn't return, so the queued audit records and spans are flushed on the shutdown signals
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+		<-signals
+
+		if err := audit.Close(); err != nil {
+			slog.Error("Error closing audit log", "error", err)
+		}
+		if err := shutdownTracing(context.Background()); err != nil {
+			slog.Error("Error shutting down tracing", "error", err)
+		}
+		os.Exit(0)
+	}()
+
+	// requests are logged by the access log filter, along with their request ID
+	web.BConfig.Log.AccessLogs = false
 	web.Run()
 }
 
@@ -39,6 +87,19 @@ func init() {
 		log.Fatal("Error loading env variables: ", err)
 	}
 	constants.InitConstantsVars()
+	if err := logging.Init(); err != nil {
+		log.Fatal("Error initializing logging: ", err)
+	}
+	if err := utils.Init(); err != nil {
+		log.Fatal("Error initializing HTTP client: ", err)
+	}
+	// Load the currency registry once, it is only read by the requests
+	if _, err := registry.DefaultRegistry(); err != nil {
+		log.Fatal("Error loading currency registry: ", err)
+	}
+	if err := audit.Init(); err != nil {
+		log.Fatal("Error initializing audit log: ", err)
+	}
 
 	// Init routes
 	routers.InitRoutes()
@@ -0,0 +1,212 @@
+package metrics
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "currencyify"
+
+// Kinds of the cached rates, told by their cache keys.
+const (
+	CacheKindLatest     = "latest"
+	CacheKindHistorical = "historical"
+	CacheKindStale      = "stale"
+)
+
+// Outcomes of the vendor API calls.
+const (
+	VendorOutcomeSuccess         = "success"
+	VendorOutcomeError           = "error"
+	VendorOutcomeTimeout         = "timeout"
+	VendorOutcomeBudgetExhausted = "budget_exhausted"
+)
+
+// defaultRateBase is the base of the rates cached under the sole currency code, i.e. `INR`, by the convert endpoints.
+const defaultRateBase = "USD"
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "HTTP requests served, per route, method and status.",
+	}, []string{"route", "method", "status"})
+
+	httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of the HTTP requests, per route, method and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+
+	cacheLookups = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "cache",
+		Name:      "lookups_total",
+		Help:      "Lookups of the cached rates, per kind of rate and result (hit or miss).",
+	}, []string{"kind", "result"})
+
+	vendorRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "vendor",
+		Name:      "requests_total",
+		Help:      "Vendor API calls, per provider, API and outcome.",
+	}, []string{"provider", "api", "outcome"})
+
+	vendorRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "vendor",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of the vendor API calls made, per provider and API.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"provider", "api"})
+
+	rateAges = &rateAgeCollector{
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "cache", "freshest_rate_age_seconds"),
+			"Age of the freshest rate cached by this instance, per base currency.",
+			[]string{"base"}, nil,
+		),
+		updatedAt: make(map[string]time.Time),
+		now:       time.Now,
+	}
+
+	registerRedisPoolOnce sync.Once
+)
+
+// Handler is used to get the handler serving the metrics in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// ObserveHTTPRequest is used to count the HTTP request served and observe its latency.
+func ObserveHTTPRequest(route, method, status string, duration time.Duration) {
+	httpRequests.WithLabelValues(route, method, status).Inc()
+	httpRequestDuration.WithLabelValues(route, method, status).Observe(duration.Seconds())
+}
+
+// ObserveCacheLookup is used to count the lookup of the rate cached under the given key.
+func ObserveCacheLookup(key string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+
+	kind, _ := parseRateKey(key)
+	cacheLookups.WithLabelValues(kind, result).Inc()
+}
+
+// ObserveCachedRate is used to record the update time of the rate cached under the given key, so the age of the
+// freshest rate of its base can be reported. Historical and stale rates aren't recorded.
+func ObserveCachedRate(key string, updatedAt time.Time) {
+	if kind, base := parseRateKey(key); kind == CacheKindLatest && !updatedAt.IsZero() {
+		rateAges.observe(base, updatedAt)
+	}
+}
+
+// ObserveVendorRequest is used to count the call of the vendor API and observe its latency. Calls not made, as the call
+// budget is exhausted, aren't observed.
+func ObserveVendorRequest(provider, api, outcome string, duration time.Duration) {
+	vendorRequests.WithLabelValues(provider, api, outcome).Inc()
+	if outcome != VendorOutcomeBudgetExhausted {
+		vendorRequestDuration.WithLabelValues(provider, api).Observe(duration.Seconds())
+	}
+}
+
+// RegisterRedisPool is used to report the connections of the given redis pool. Only the first pool is reported.
+func RegisterRedisPool(pool *redis.Pool) {
+	registerRedisPoolOnce.Do(func() {
+		prometheus.MustRegister(
+			prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "redis_pool",
+				Name:      "active_connections",
+				Help:      "Connections of the redis pool, idle or in use.",
+			}, func() float64 {
+				return float64(pool.Stats().ActiveCount)
+			}),
+			prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "redis_pool",
+				Name:      "idle_connections",
+				Help:      "Idle connections of the redis pool.",
+			}, func() float64 {
+				return float64(pool.Stats().IdleCount)
+			}),
+			prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+				Namespace: namespace,
+				Subsystem: "redis_pool",
+				Name:      "max_active_connections",
+				Help:      "Max connections of the redis pool, 0 when unlimited.",
+			}, func() float64 {
+				return float64(pool.MaxActive)
+			}),
+		)
+	})
+}
+
+// parseRateKey is used to tell the kind and base currency of the rate cached under the given key. Keys are
+// `stale:<key>` for the stale copies, `<base>-<currency>` for the rates of the exchange-rate endpoints, `<currency>`
+// for the USD based rates of the convert endpoints, and suffixed with `@<date>` for the historical rates.
+// It returns the kind and base currency.
+func parseRateKey(key string) (string, string) {
+	kind := CacheKindLatest
+	if strings.HasPrefix(key, "stale:") {
+		kind, key = CacheKindStale, strings.TrimPrefix(key, "stale:")
+	} else if strings.Contains(key, "@") {
+		kind = CacheKindHistorical
+	}
+
+	base := defaultRateBase
+	if currencies, _, _ := strings.Cut(key, "@"); strings.Contains(currencies, "-") {
+		base, _, _ = strings.Cut(currencies, "-")
+	}
+
+	return kind, base
+}
+
+// rateAgeCollector reports the age of the freshest rate cached per base currency, as of the scrape.
+type rateAgeCollector struct {
+	desc *prometheus.Desc
+
+	mu        sync.Mutex
+	updatedAt map[string]time.Time
+	now       func() time.Time
+}
+
+func (c *rateAgeCollector) observe(base string, updatedAt time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if updatedAt.After(c.updatedAt[base]) {
+		c.updatedAt[base] = updatedAt
+	}
+}
+
+// Describe sends the descriptor of the rate age metric.
+func (c *rateAgeCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+// Collect sends the age of the freshest rate of each base currency.
+func (c *rateAgeCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	for base, updatedAt := range c.updatedAt {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, now.Sub(updatedAt).Seconds(), base)
+	}
+}
+
+func init() {
+	prometheus.MustRegister(httpRequests, httpRequestDuration, cacheLookups, vendorRequests, vendorRequestDuration, rateAges)
+}
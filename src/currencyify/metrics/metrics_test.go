@@ -0,0 +1,115 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRateKey(t *testing.T) {
+	testCases := []struct {
+		name string
+
+		key string
+
+		wantKind string
+		wantBase string
+	}{
+		{
+			name:     "should parse the USD based rate of the convert endpoints",
+			key:      "INR",
+			wantKind: CacheKindLatest,
+			wantBase: "USD",
+		},
+		{
+			name:     "should parse the rate of the exchange-rate endpoints",
+			key:      "EUR-INR",
+			wantKind: CacheKindLatest,
+			wantBase: "EUR",
+		},
+		{
+			name:     "should parse the historical rate",
+			key:      "EUR-INR@2024-01-15",
+			wantKind: CacheKindHistorical,
+			wantBase: "EUR",
+		},
+		{
+			name:     "should parse the stale copy of the rate",
+			key:      "stale:INR@2024-01-15",
+			wantKind: CacheKindStale,
+			wantBase: "USD",
+		},
+	}
+
+	for _, tCase := range testCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			// Run test
+			kind, base := parseRateKey(tCase.key)
+
+			// Assert
+			assert.Equal(t, tCase.wantKind, kind, "case: %v", tCase)
+			assert.Equal(t, tCase.wantBase, base, "case: %v", tCase)
+		})
+	}
+}
+
+func TestObserveCacheLookup(t *testing.T) {
+	// Setup
+	hits := testutil.ToFloat64(cacheLookups.WithLabelValues(CacheKindLatest, "hit"))
+	misses := testutil.ToFloat64(cacheLookups.WithLabelValues(CacheKindStale, "miss"))
+
+	// Run test
+	ObserveCacheLookup("USD-INR", true)
+	ObserveCacheLookup("INR", true)
+	ObserveCacheLookup("stale:INR", false)
+
+	// Assert
+	assert.Equal(t, hits+2, testutil.ToFloat64(cacheLookups.WithLabelValues(CacheKindLatest, "hit")))
+	assert.Equal(t, misses+1, testutil.ToFloat64(cacheLookups.WithLabelValues(CacheKindStale, "miss")))
+}
+
+func TestObserveVendorRequest(t *testing.T) {
+	// Setup
+	provider := "api.fxratesapi.com"
+	calls := testutil.ToFloat64(vendorRequests.WithLabelValues(provider, "GetLatestCurrencyRate", VendorOutcomeSuccess))
+	exhausted := testutil.ToFloat64(vendorRequests.WithLabelValues(provider, "GetLatestCurrencyRate", VendorOutcomeBudgetExhausted))
+	observed := testutil.CollectAndCount(vendorRequestDuration)
+
+	// Run test
+	ObserveVendorRequest(provider, "GetLatestCurrencyRate", VendorOutcomeSuccess, 100*time.Millisecond)
+	ObserveVendorRequest(provider, "GetLatestCurrencyRate", VendorOutcomeBudgetExhausted, 0)
+
+	// Assert
+	assert.Equal(t, calls+1, testutil.ToFloat64(vendorRequests.WithLabelValues(provider, "GetLatestCurrencyRate", VendorOutcomeSuccess)))
+	assert.Equal(t, exhausted+1, testutil.ToFloat64(vendorRequests.WithLabelValues(provider, "GetLatestCurrencyRate", VendorOutcomeBudgetExhausted)))
+	assert.LessOrEqual(t, testutil.CollectAndCount(vendorRequestDuration), observed+1, "calls not made shouldn't be timed")
+}
+
+func TestRateAgeCollector(t *testing.T) {
+	// Setup
+	now := time.Date(2026, time.March, 31, 12, 0, 0, 0, time.UTC)
+	collector := &rateAgeCollector{desc: rateAges.desc, updatedAt: make(map[string]time.Time), now: func() time.Time { return now }}
+	rateAges, collector = collector, rateAges
+	defer func() {
+		rateAges = collector
+	}()
+
+	// Run test
+	ObserveCachedRate("INR", now.Add(-time.Hour))
+	ObserveCachedRate("JPY", now.Add(-time.Minute))
+	ObserveCachedRate("EUR-INR", now.Add(-30*time.Second))
+	ObserveCachedRate("EUR-JPY@2024-01-15", now.Add(-time.Second))
+	ObserveCachedRate("stale:GBP-INR", now.Add(-time.Second))
+
+	// Assert
+	want := `
+# HELP currencyify_cache_freshest_rate_age_seconds Age of the freshest rate cached by this instance, per base currency.
+# TYPE currencyify_cache_freshest_rate_age_seconds gauge
+currencyify_cache_freshest_rate_age_seconds{base="EUR"} 30
+currencyify_cache_freshest_rate_age_seconds{base="USD"} 60
+`
+	assert.NoError(t, testutil.CollectAndCompare(rateAges, strings.NewReader(want)))
+}
@@ -0,0 +1,459 @@
+package openapi
+
+import (
+	_ "embed"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"currencyify/components/admin"
+	"currencyify/components/convert"
+	"currencyify/components/exchange_rate"
+	"currencyify/components/graphql"
+	"currencyify/constants"
+	"currencyify/filters"
+	"currencyify/utils"
+)
+
+//go:embed swagger_ui.html
+var SwaggerUI []byte
+
+// SwaggerUIBundle and SwaggerUICSS are the swagger-ui assets loaded by the Swagger UI page, vendored in `swagger-ui` so
+// the page doesn't load scripts from a CDN.
+//
+//go:embed swagger-ui/swagger-ui-bundle.js
+var SwaggerUIBundle []byte
+
+//go:embed swagger-ui/swagger-ui.css
+var SwaggerUICSS []byte
+
+type Document struct {
+	OpenAPI    string                          `json:"openapi"`
+	Info       Info                            `json:"info"`
+	Servers    []Server                        `json:"servers"`
+	Paths      map[string]map[string]Operation `json:"paths"`
+	Components Components                      `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Version     string `json:"version"`
+}
+
+type Server struct {
+	URL string `json:"url"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Name         string `json:"name,omitempty"`
+	In           string `json:"in,omitempty"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	Description string                `json:"description,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]Response   `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Headers     map[string]Header    `json:"headers,omitempty"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type Header struct {
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+}
+
+// Route describes an endpoint registered in routers, along with the forms and responses of its controller action.
+type Route struct {
+	Path        string
+	Method      string
+	OperationID string
+	Summary     string
+	Tag         string
+
+	// QueryParams are the query string params accepted by the route, i.e. by the GET variants.
+	QueryParams []Parameter
+	// Form is the request body form. Forms having `form` tags are documented as multipart uploads.
+	Form interface{}
+	// FileField is the name of the uploaded file field of multipart forms.
+	FileField string
+	// Data is the response data wrapped in the `APIResponse` envelope. Nil when ResponseContentType is set.
+	Data interface{}
+	// ResponseContentType is the content type of the routes not responding with the `APIResponse` envelope.
+	ResponseContentType string
+}
+
+var Routes = []Route{
+	{
+		Path:                "/healthcheck",
+		Method:              http.MethodGet,
+		OperationID:         "healthcheck",
+		Summary:             "Checks whether the service is alive",
+		Tag:                 "health",
+		ResponseContentType: "text/plain",
+	},
+	{
+		Path:                "/livez",
+		Method:              http.MethodGet,
+		OperationID:         "livez",
+		Summary:             "Checks whether the process is alive, without checking its dependencies",
+		Tag:                 "health",
+		ResponseContentType: "text/plain",
+	},
+	{
+		Path:                "/readyz",
+		Method:              http.MethodGet,
+		OperationID:         "readyz",
+		Summary:             "Checks whether redis, the currency registry and the rates are ready, with 503 and the breakdown when they aren't",
+		Tag:                 "health",
+		ResponseContentType: "application/json",
+	},
+	{
+		Path:        "/convert/currency-convert",
+		Method:      http.MethodGet,
+		OperationID: "convertCurrencyQuery",
+		Summary:     "Converts the given amount from source currency to target currency",
+		Tag:         "convert",
+		QueryParams: []Parameter{
+			{Name: "from", In: "query", Required: true, Description: "Source currency code", Schema: &Schema{Type: "string"}},
+			{Name: "to", In: "query", Required: true, Description: "Target currency code", Schema: &Schema{Type: "string"}},
+			{Name: "amount", In: "query", Description: "Amount to convert", Schema: &Schema{Type: "number", Format: "double"}},
+			{Name: "target_amount", In: "query", Description: "Amount to receive, for reverse conversion", Schema: &Schema{Type: "number", Format: "double"}},
+		},
+		Data: convert.CurrencyConverterResponse{},
+	},
+	{
+		Path:        "/convert/currency-convert",
+		Method:      http.MethodPost,
+		OperationID: "convertCurrency",
+		Summary:     "Converts the given amount from source currency to target currency",
+		Tag:         "convert",
+		Form:        convert.CurrencyConverterForm{},
+		Data:        convert.CurrencyConverterResponse{},
+	},
+	{
+		Path:        "/convert/currency-convert/batch",
+		Method:      http.MethodPost,
+		OperationID: "convertCurrencies",
+		Summary:     "Converts multiple amounts in one call, reporting per-item errors",
+		Tag:         "convert",
+		Form:        convert.CurrencyBatchConverterForm{},
+		Data:        convert.CurrencyBatchConverterResponse{},
+	},
+	{
+		Path:                "/convert/currency-convert/csv",
+		Method:              http.MethodPost,
+		OperationID:         "convertCSV",
+		Summary:             "Streams back the uploaded CSV ledger with converted amounts appended",
+		Tag:                 "convert",
+		Form:                convert.CurrencyCSVConverterForm{},
+		FileField:           "file",
+		ResponseContentType: "text/csv",
+	},
+	{
+		Path:        "/convert/portfolio-valuation",
+		Method:      http.MethodPost,
+		OperationID: "valuePortfolio",
+		Summary:     "Values the given holdings in the reporting currency using one rate snapshot",
+		Tag:         "convert",
+		Form:        convert.PortfolioValuationForm{},
+		Data:        convert.PortfolioValuationResponse{},
+	},
+	{
+		Path:        "/exchange-rate/currency-exchange-rate",
+		Method:      http.MethodGet,
+		OperationID: "getCurrencyExchangeRateQuery",
+		Summary:     "Fetches the exchange rates of the given currencies against the base currency",
+		Tag:         "exchange-rate",
+		QueryParams: []Parameter{
+			{Name: "base", In: "query", Required: true, Description: "Base currency code", Schema: &Schema{Type: "string"}},
+			{Name: "symbols", In: "query", Required: true, Description: "Comma separated target currency codes", Schema: &Schema{Type: "string"}},
+			{Name: "date", In: "query", Description: "Day of the historical rates, in YYYY-MM-DD format", Schema: &Schema{Type: "string", Format: "date"}},
+		},
+		Data: exchange_rate.CurrencyExchangeRateResponse{},
+	},
+	{
+		Path:        "/exchange-rate/currency-exchange-rate",
+		Method:      http.MethodPost,
+		OperationID: "getCurrencyExchangeRate",
+		Summary:     "Fetches the exchange rates of the given currencies against the base currency",
+		Tag:         "exchange-rate",
+		Form:        exchange_rate.CurrencyExchangeRateForm{},
+		Data:        exchange_rate.CurrencyExchangeRateResponse{},
+	},
+	{
+		Path:        "/exchange-rate/currency-exchange-rate/stream",
+		Method:      http.MethodGet,
+		OperationID: "streamCurrencyExchangeRate",
+		Summary:     "Streams the exchange rates as Server-Sent Events, pushing the ones which change beyond the threshold",
+		Tag:         "exchange-rate",
+		QueryParams: []Parameter{
+			{Name: "base", In: "query", Required: true, Description: "Base currency code", Schema: &Schema{Type: "string"}},
+			{Name: "symbols", In: "query", Required: true, Description: "Comma separated target currency codes", Schema: &Schema{Type: "string"}},
+			{Name: "threshold", In: "query", Description: "Minimum change of a rate, in percentage, to push it again", Schema: &Schema{Type: "number", Format: "double"}},
+		},
+		ResponseContentType: "text/event-stream",
+	},
+	{
+		Path:                "/graphql",
+		Method:              http.MethodPost,
+		OperationID:         "graphql",
+		Summary:             "Executes the GraphQL query over currencies, rates and conversions, batching its rate lookups",
+		Tag:                 "graphql",
+		Form:                graphql.GraphQLForm{},
+		ResponseContentType: "application/json",
+	},
+	{
+		Path:        "/admin/vendor-usage",
+		Method:      http.MethodGet,
+		OperationID: "getVendorUsage",
+		Summary:     "Gets the calls made to each vendor API provider in the current minute, day and month, along with their budgets",
+		Tag:         "admin",
+		Data:        admin.VendorUsageResponse{},
+	},
+	{
+		Path:                "/openapi.json",
+		Method:              http.MethodGet,
+		OperationID:         "getOpenAPI",
+		Summary:             "Serves this OpenAPI document",
+		Tag:                 "docs",
+		ResponseContentType: "application/json",
+	},
+	{
+		Path:                "/docs",
+		Method:              http.MethodGet,
+		OperationID:         "getDocs",
+		Summary:             "Serves the Swagger UI page of this OpenAPI document",
+		Tag:                 "docs",
+		ResponseContentType: "text/html",
+	},
+	{
+		Path:                "/docs/swagger-ui-bundle.js",
+		Method:              http.MethodGet,
+		OperationID:         "getDocsScript",
+		Summary:             "Serves the vendored swagger-ui script of the Swagger UI page",
+		Tag:                 "docs",
+		ResponseContentType: "text/javascript",
+	},
+	{
+		Path:                "/docs/swagger-ui.css",
+		Method:              http.MethodGet,
+		OperationID:         "getDocsStylesheet",
+		Summary:             "Serves the vendored swagger-ui stylesheet of the Swagger UI page",
+		Tag:                 "docs",
+		ResponseContentType: "text/css",
+	},
+}
+
+// Generate is used to generate the OpenAPI 3 document of the routes, building schemas from the forms and responses.
+// It returns the OpenAPI document.
+func Generate() *Document {
+	doc := &Document{
+		OpenAPI: "3.0.3",
+		Info: Info{
+			Title:       "currencyify",
+			Description: "Converts currencies and serves currency exchange rates.",
+			Version:     "1.0",
+		},
+		Servers: []Server{{URL: fmt.Sprintf("/%v", constants.API_PATH)}},
+		Paths:   make(map[string]map[string]Operation),
+		Components: Components{
+			Schemas: make(map[string]*Schema),
+			SecuritySchemes: map[string]SecurityScheme{
+				"ApiKeyAuth": {Type: "apiKey", Name: "X-API-Key", In: "header", Description: "API key issued with `currencyify apikey create`, required when `AUTH_ENABLED` is `true`"},
+				"BearerAuth": {Type: "http", Scheme: "bearer", BearerFormat: "JWT", Description: "Token issued by the identity provider configured with the `JWT_*` env vars, instead of API key"},
+			},
+		},
+	}
+
+	for _, route := range Routes {
+		op := Operation{
+			OperationID: route.OperationID,
+			Summary:     route.Summary,
+			Tags:        []string{route.Tag},
+			Parameters:  route.QueryParams,
+			Responses:   make(map[string]Response),
+		}
+
+		if route.Form != nil {
+			if route.FileField != "" {
+				schema := doc.schemaOf(reflect.TypeOf(route.Form), "form", false)
+				schema.Properties[route.FileField] = &Schema{Type: "string", Format: "binary"}
+				op.RequestBody = &RequestBody{Required: true, Content: map[string]MediaType{"multipart/form-data": {Schema: schema}}}
+			} else {
+				op.RequestBody = &RequestBody{Required: true, Content: map[string]MediaType{"application/json": {Schema: doc.schemaOf(reflect.TypeOf(route.Form), "json", true)}}}
+			}
+		}
+
+		if route.ResponseContentType != "" {
+			op.Responses["200"] = Response{Description: "OK", Content: map[string]MediaType{route.ResponseContentType: {Schema: &Schema{Type: "string"}}}}
+		} else {
+			op.Responses["200"] = Response{Description: "OK", Content: map[string]MediaType{"application/json": {Schema: doc.envelopeSchema(doc.schemaOf(reflect.TypeOf(route.Data), "json", true))}}}
+		}
+		if route.Form != nil || route.QueryParams != nil {
+			op.Responses["400"] = Response{Description: "Invalid input params", Content: map[string]MediaType{"application/json": {Schema: doc.envelopeSchema(&Schema{Nullable: true})}}}
+			op.Responses["404"] = Response{Description: "Exchange rate not found at vendor API", Content: map[string]MediaType{"application/json": {Schema: doc.envelopeSchema(&Schema{Nullable: true})}}}
+			op.Responses["500"] = Response{Description: "Internal error", Content: map[string]MediaType{"application/json": {Schema: doc.envelopeSchema(&Schema{Nullable: true})}}}
+			op.Responses["502"] = Response{Description: "Vendor API failed", Content: map[string]MediaType{"application/json": {Schema: doc.envelopeSchema(&Schema{Nullable: true})}}}
+			op.Responses["503"] = Response{Description: "Vendor API call budget exhausted, and the rates aren't in cache", Content: map[string]MediaType{"application/json": {Schema: doc.envelopeSchema(&Schema{Nullable: true})}}}
+			op.Responses["504"] = Response{Description: "Vendor API timed out", Content: map[string]MediaType{"application/json": {Schema: doc.envelopeSchema(&Schema{Nullable: true})}}}
+		}
+		if route.Form != nil && route.FileField == "" {
+			op.Responses["422"] = Response{Description: "Request body having values of the wrong type", Content: map[string]MediaType{"application/json": {Schema: doc.envelopeSchema(&Schema{Nullable: true})}}}
+		}
+
+		if !filters.IsPublicPath(route.Path) {
+			scopes := filters.RequiredScopes(route.Path)
+			op.Description = fmt.Sprintf("Requires an API key or bearer token with scopes: `%s`. Requests of the API keys having signing secrets require the `X-Signature`, `X-Signature-Timestamp` and `X-Signature-Nonce` headers.", strings.Join(scopes, "`, `"))
+			op.Security = []map[string][]string{{"ApiKeyAuth": {}}, {"BearerAuth": {}}}
+			op.Responses["401"] = Response{Description: "Credentials or request signature missing or invalid", Content: map[string]MediaType{"application/json": {Schema: doc.envelopeSchema(&Schema{Nullable: true})}}}
+			op.Responses["403"] = Response{Description: "Credentials lacking the required scope", Content: map[string]MediaType{"application/json": {Schema: doc.envelopeSchema(&Schema{Nullable: true})}}}
+			op.Responses["429"] = Response{
+				Description: "Rate limit or daily/monthly quota of the client tier exceeded",
+				Headers: map[string]Header{
+					"Retry-After":           {Description: "Seconds till the request can be retried", Schema: &Schema{Type: "integer"}},
+					"X-RateLimit-Limit":     {Description: "Burst of the rate limit", Schema: &Schema{Type: "integer"}},
+					"X-RateLimit-Remaining": {Description: "Requests remaining in the burst", Schema: &Schema{Type: "integer"}},
+					"X-RateLimit-Reset":     {Description: "Seconds till the burst is fully available again", Schema: &Schema{Type: "integer"}},
+				},
+				Content: map[string]MediaType{"application/json": {Schema: doc.envelopeSchema(&Schema{Nullable: true})}},
+			}
+		}
+
+		if _, ok := doc.Paths[route.Path]; !ok {
+			doc.Paths[route.Path] = make(map[string]Operation)
+		}
+		doc.Paths[route.Path][strings.ToLower(route.Method)] = op
+	}
+
+	return doc
+}
+
+// envelopeSchema wraps the given data schema in the `APIResponse` envelope.
+func (doc *Document) envelopeSchema(data *Schema) *Schema {
+	return &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"code":   {Type: "integer"},
+			"data":   data,
+			"error":  {Type: "string"},
+			"errors": doc.schemaOf(reflect.TypeOf([]utils.ValidationError{}), "json", true),
+		},
+	}
+}
+
+// schemaOf builds the schema of the given type using the given struct tag for property names. Named structs are added
+// to the components of the document and referenced, when ref is true.
+// It returns the schema.
+func (doc *Document) schemaOf(t reflect.Type, tagName string, ref bool) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == reflect.TypeOf(time.Time{}):
+		return &Schema{Type: "string", Format: "date-time"}
+	case t.Kind() == reflect.String:
+		return &Schema{Type: "string"}
+	case t.Kind() == reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		return &Schema{Type: "array", Items: doc.schemaOf(t.Elem(), tagName, ref)}
+	case t.Kind() == reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: doc.schemaOf(t.Elem(), tagName, ref)}
+	case t.Kind() == reflect.Struct:
+		if !ref || t.Name() == "" {
+			return doc.structSchema(t, tagName, ref)
+		}
+
+		if _, ok := doc.Components.Schemas[t.Name()]; !ok {
+			// reserve the name first, so recursive types don't loop forever
+			doc.Components.Schemas[t.Name()] = &Schema{}
+			*doc.Components.Schemas[t.Name()] = *doc.structSchema(t, tagName, ref)
+		}
+
+		return &Schema{Ref: fmt.Sprintf("#/components/schemas/%s", t.Name())}
+	default:
+		return &Schema{}
+	}
+}
+
+func (doc *Document) structSchema(t reflect.Type, tagName string, ref bool) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name := strings.Split(field.Tag.Get(tagName), ",")[0]
+		if name == "-" {
+			continue
+		}
+
+		if field.Anonymous && name == "" {
+			for propName, prop := range doc.structSchema(field.Type, tagName, ref).Properties {
+				schema.Properties[propName] = prop
+			}
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+		schema.Properties[name] = doc.schemaOf(field.Type, tagName, ref)
+	}
+
+	return schema
+}
@@ -0,0 +1,133 @@
+package openapi_test
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"currencyify/components/admin"
+	"currencyify/components/convert"
+	"currencyify/components/exchange_rate"
+	"currencyify/components/graphql"
+	"currencyify/constants"
+	"currencyify/openapi"
+	"currencyify/routers"
+
+	"github.com/beego/beego/v2/server/web"
+	"github.com/stretchr/testify/assert"
+)
+
+var update = flag.Bool("update", false, "update the committed openapi.json with the generated document")
+
+func TestGenerate_MatchesRegisteredRoutes(t *testing.T) {
+	routers.InitRoutes()
+
+	// every pattern is listed under each router method along with the actions of all its methods, i.e. `map[GET:GET POST:POST]`
+	registered := make([]string, 0)
+	for method, data := range web.PrintTree()["Data"].(web.M) {
+		for _, route := range *data.(*[][]string) {
+			if !strings.Contains(route[1], method+":") && !strings.Contains(route[1], "*:") {
+				continue
+			}
+			path := strings.TrimSuffix(strings.TrimPrefix(route[0], fmt.Sprintf("/%v", constants.API_PATH)), "/")
+			registered = append(registered, fmt.Sprintf("%s %s", method, path))
+		}
+	}
+
+	documented := make([]string, 0)
+	for path, item := range openapi.Generate().Paths {
+		for method := range item {
+			documented = append(documented, fmt.Sprintf("%s %s", strings.ToUpper(method), path))
+		}
+	}
+
+	sort.Strings(registered)
+	sort.Strings(documented)
+	assert.Equal(t, registered, documented, "routes registered in routers should match the routes of the OpenAPI document")
+}
+
+func TestGenerate_MatchesComponentSchemas(t *testing.T) {
+	// component methods called by the controller action of each route taking params or responding with data
+	actions := map[string]struct {
+		component interface{}
+		method    string
+	}{
+		"GET /convert/currency-convert":                    {(*convert.CurrencyConverter)(nil), "ConvertCurrency"},
+		"POST /convert/currency-convert":                   {(*convert.CurrencyConverter)(nil), "ConvertCurrency"},
+		"POST /convert/currency-convert/batch":             {(*convert.CurrencyConverter)(nil), "ConvertCurrencies"},
+		"POST /convert/currency-convert/csv":               {(*convert.CurrencyConverter)(nil), "ConvertCSV"},
+		"POST /convert/portfolio-valuation":                {(*convert.PortfolioValuator)(nil), "ValuePortfolio"},
+		"GET /exchange-rate/currency-exchange-rate":        {(*exchange_rate.CurrencyExchangeRate)(nil), "GetCurrencyExchangeRate"},
+		"POST /exchange-rate/currency-exchange-rate":       {(*exchange_rate.CurrencyExchangeRate)(nil), "GetCurrencyExchangeRate"},
+		"GET /exchange-rate/currency-exchange-rate/stream": {(*exchange_rate.CurrencyExchangeRate)(nil), "SubscribeCurrencyExchangeRate"},
+		"POST /graphql":           {(*graphql.GraphQL)(nil), "Exec"},
+		"GET /admin/vendor-usage": {(*admin.VendorUsage)(nil), "GetVendorUsage"},
+	}
+
+	got := openapi.Generate()
+	routes := openapi.Routes
+	defer func() { openapi.Routes = routes }()
+
+	for _, route := range routes {
+		name := fmt.Sprintf("%s %s", route.Method, route.Path)
+		action, ok := actions[name]
+		if !ok {
+			assert.True(t, route.Form == nil && route.Data == nil && route.QueryParams == nil, "route %s taking params or responding with data should be mapped to its component method", name)
+			continue
+		}
+
+		t.Run(name, func(t *testing.T) {
+			// Setup
+			method, ok := reflect.TypeOf(action.component).Elem().MethodByName(action.method)
+			if !assert.True(t, ok, "case: %v", name) {
+				return
+			}
+			want := route
+			want.Form, want.Data = nil, nil
+			if route.Method != http.MethodGet && method.Type.NumIn() > 0 {
+				// the request body is decoded into the form taken by the component, the GET variants parse it from the query
+				want.Form = reflect.New(method.Type.In(0).Elem()).Elem().Interface()
+			}
+			if route.ResponseContentType == "" && method.Type.NumOut() > 1 {
+				want.Data = reflect.New(method.Type.Out(0).Elem()).Elem().Interface()
+			}
+			openapi.Routes = []openapi.Route{want}
+
+			// Run test
+			wantDoc := openapi.Generate()
+
+			// Assert
+			wantOp := wantDoc.Paths[route.Path][strings.ToLower(route.Method)]
+			gotOp := got.Paths[route.Path][strings.ToLower(route.Method)]
+			assert.Equal(t, wantOp.RequestBody, gotOp.RequestBody, "request body schema of %s should match the form of %s", name, action.method)
+			assert.Equal(t, wantOp.Responses["200"], gotOp.Responses["200"], "response schema of %s should match the response of %s", name, action.method)
+			for schemaName, schema := range wantDoc.Components.Schemas {
+				assert.Equal(t, schema, got.Components.Schemas[schemaName], "schema %s should match its struct, case: %v", schemaName, name)
+			}
+		})
+	}
+}
+
+func TestGenerate_MatchesCommittedDocument(t *testing.T) {
+	got, err := json.MarshalIndent(openapi.Generate(), "", "  ")
+	if !assert.NoError(t, err) {
+		return
+	}
+	got = append(got, '\n')
+
+	if *update {
+		assert.NoError(t, os.WriteFile("openapi.json", got, 0644))
+		return
+	}
+
+	want, err := os.ReadFile("openapi.json")
+	if assert.NoError(t, err) {
+		assert.Equal(t, string(want), string(got), "openapi.json is out of date, run `go test ./openapi -update` to regenerate it")
+	}
+}
@@ -7,10 +7,46 @@ import (
 
 func init() {
 
+	beego.GlobalControllerRouter["currencyify/controllers/admin:VendorUsageController"] = append(beego.GlobalControllerRouter["currencyify/controllers/admin:VendorUsageController"],
+		beego.ControllerComments{
+			Method:           "GetVendorUsage",
+			Router:           `/`,
+			AllowHTTPMethods: []string{"get"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
 	beego.GlobalControllerRouter["currencyify/controllers/convert:CurrencyConvertController"] = append(beego.GlobalControllerRouter["currencyify/controllers/convert:CurrencyConvertController"],
 		beego.ControllerComments{
 			Method:           "ConvertCurrency",
 			Router:           `/`,
+			AllowHTTPMethods: []string{"get", "post"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["currencyify/controllers/convert:CurrencyConvertController"] = append(beego.GlobalControllerRouter["currencyify/controllers/convert:CurrencyConvertController"],
+		beego.ControllerComments{
+			Method:           "ConvertCurrencies",
+			Router:           `/batch`,
+			AllowHTTPMethods: []string{"post"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["currencyify/controllers/convert:CurrencyConvertController"] = append(beego.GlobalControllerRouter["currencyify/controllers/convert:CurrencyConvertController"],
+		beego.ControllerComments{
+			Method:           "ConvertCSV",
+			Router:           `/csv`,
+			AllowHTTPMethods: []string{"post"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["currencyify/controllers/convert:PortfolioValuationController"] = append(beego.GlobalControllerRouter["currencyify/controllers/convert:PortfolioValuationController"],
+		beego.ControllerComments{
+			Method:           "ValuePortfolio",
+			Router:           `/`,
 			AllowHTTPMethods: []string{"post"},
 			MethodParams:     param.Make(),
 			Filters:          nil,
@@ -20,6 +56,24 @@ func init() {
 		beego.ControllerComments{
 			Method:           "GetCurrencyExchangeRate",
 			Router:           `/`,
+			AllowHTTPMethods: []string{"get", "post"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["currencyify/controllers/exchange_rate:CurrencyExchangeRateController"] = append(beego.GlobalControllerRouter["currencyify/controllers/exchange_rate:CurrencyExchangeRateController"],
+		beego.ControllerComments{
+			Method:           "StreamCurrencyExchangeRate",
+			Router:           `/stream`,
+			AllowHTTPMethods: []string{"get"},
+			MethodParams:     param.Make(),
+			Filters:          nil,
+			Params:           nil})
+
+	beego.GlobalControllerRouter["currencyify/controllers/graphql:GraphQLController"] = append(beego.GlobalControllerRouter["currencyify/controllers/graphql:GraphQLController"],
+		beego.ControllerComments{
+			Method:           "Query",
+			Router:           `/`,
 			AllowHTTPMethods: []string{"post"},
 			MethodParams:     param.Make(),
 			Filters:          nil,
@@ -2,21 +2,62 @@ package routers
 
 import (
 	"fmt"
+	"net/http"
 
+	"currencyify/components/health"
 	"currencyify/constants"
+	"currencyify/controllers/admin"
 	"currencyify/controllers/convert"
 	"currencyify/controllers/exchange_rate"
+	"currencyify/controllers/graphql"
+	"currencyify/filters"
+	"currencyify/metrics"
+	"currencyify/openapi"
+	"currencyify/utils"
 
 	"github.com/beego/beego/v2/server/web"
 	"github.com/beego/beego/v2/server/web/context"
 )
 
+// readinessChecker checks the dependencies of the service for the readiness probes.
+var readinessChecker = health.NewChecker()
+
 func InitRoutes() {
 	ns := web.NewNamespace(fmt.Sprintf("/%v", constants.API_PATH),
+		web.NSBefore(filters.RequestID, filters.CORS, filters.RateLimitByIP, filters.Authenticate, filters.VerifySignature, filters.RateLimit),
+
 		web.NSGet("/healthcheck", func(ctx *context.Context) {
 			_ = ctx.Output.Body([]byte("i am alive"))
 		}),
 
+		web.NSGet("/livez", func(ctx *context.Context) {
+			ctx.Output.Header("Cache-Control", "no-store, max-age=0")
+			_ = ctx.Output.Body([]byte("ok"))
+		}),
+
+		web.NSGet("/readyz", func(ctx *context.Context) {
+			report := readinessChecker.Ready()
+			status := http.StatusOK
+			if report.Status != health.StatusReady {
+				status = http.StatusServiceUnavailable
+			}
+			ctx.Output.Header("Cache-Control", "no-store, max-age=0")
+			ctx.Output.SetStatus(status)
+			_ = ctx.Output.JSON(report, web.BConfig.RunMode != web.PROD, false)
+		}),
+
+		web.NSGet("/openapi.json", func(ctx *context.Context) {
+			_ = ctx.Output.JSON(openapi.Generate(), true, false)
+		}),
+
+		web.NSGet("/docs", func(ctx *context.Context) {
+			ctx.Output.Header("Content-Type", "text/html; charset=utf-8")
+			_ = ctx.Output.Body(openapi.SwaggerUI)
+		}),
+
+		web.NSGet("/docs/swagger-ui-bundle.js", serveAsset(openapi.SwaggerUIBundle, "text/javascript; charset=utf-8")),
+		web.NSGet("/docs/swagger-ui.css", serveAsset(openapi.SwaggerUICSS, "text/css; charset=utf-8")),
+
 		web.NSNamespace("/convert",
 			web.NSNamespace(
 				"/currency-convert",
@@ -24,6 +65,12 @@ func InitRoutes() {
 					&convert.CurrencyConvertController{},
 				),
 			),
+			web.NSNamespace(
+				"/portfolio-valuation",
+				web.NSInclude(
+					&convert.PortfolioValuationController{},
+				),
+			),
 		),
 
 		web.NSNamespace("/exchange-rate",
@@ -34,7 +81,41 @@ func InitRoutes() {
 				),
 			),
 		),
+
+		web.NSNamespace("/graphql",
+			web.NSInclude(
+				&graphql.GraphQLController{},
+			),
+		),
+
+		web.NSNamespace("/admin",
+			web.NSNamespace(
+				"/vendor-usage",
+				web.NSInclude(
+					&admin.VendorUsageController{},
+				),
+			),
+		),
 	)
 
 	web.AddNamespace(ns)
+
+	// the CSV ledgers are streamed by the controller, so Beego must not parse them before routing
+	web.InsertFilter(fmt.Sprintf("/%v/convert/currency-convert/csv", constants.API_PATH), web.BeforeStatic, filters.StreamMultipart)
+
+	web.InsertFilterChain(fmt.Sprintf("/%v/*", constants.API_PATH), filters.Metrics)
+	web.InsertFilterChain(fmt.Sprintf("/%v/*", constants.API_PATH), filters.AccessLog)
+
+	metrics.RegisterRedisPool(utils.Pool())
+	web.Handler("/metrics", metrics.Handler())
+}
+
+// serveAsset is used to serve the given embedded static asset of the docs, letting the clients cache it for a day.
+// It returns the handler of the asset.
+func serveAsset(body []byte, contentType string) web.HandleFunc {
+	return func(ctx *context.Context) {
+		ctx.Output.Header("Content-Type", contentType)
+		ctx.Output.Header("Cache-Control", "public, max-age=86400")
+		_ = ctx.Output.Body(body)
+	}
 }
@@ -0,0 +1,107 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"currencyify/components/auth"
+	"currencyify/constants"
+	"currencyify/rpc/pb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	apiKeyMetadata             = "x-api-key"
+	requestIDMetadata          = "x-request-id"
+	signatureMetadata          = "x-signature"
+	signatureTimestampMetadata = "x-signature-timestamp"
+	signatureNonceMetadata     = "x-signature-nonce"
+)
+
+// methodScopes maps the RPCs to the scopes required to call them. Other RPCs, i.e. health and reflection, are public.
+var methodScopes = map[string][]string{
+	pb.Currencyify_Convert_FullMethodName:          {auth.ScopeConvert},
+	pb.Currencyify_GetExchangeRates_FullMethodName: {auth.ScopeRates},
+	pb.Currencyify_ListCurrencies_FullMethodName:   {auth.ScopeRates},
+}
+
+// authUnaryInterceptor authenticates the RPCs using the bearer token passed in the `authorization` metadata or else the
+// API key passed in the `x-api-key` metadata, verifies the signatures of the RPCs of the API keys having signing
+// secrets, and authorizes them against their scopes, same as the HTTP API.
+func (s *Server) authUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	requiredScopes, ok := methodScopes[info.FullMethod]
+	if constants.AUTH_ENABLED != "true" || !ok {
+		return handler(ctx, req)
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	creds := auth.Credentials{
+		Authorization: firstMetadata(md, "authorization"),
+		APIKey:        firstMetadata(md, apiKeyMetadata),
+	}
+
+	authenticator := &auth.Authenticator{RedisConn: s.RedisConn, JWTVerifier: s.JWTVerifier}
+	principal, err := authenticator.Authenticate(creds)
+	if auth.IsUnauthenticated(err) {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	} else if err != nil {
+		slog.ErrorContext(ctx, "Some error occurred", "error", err)
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	if principal.SigningSecret != "" {
+		if err := s.verifySignature(principal, md, info.FullMethod, req); errors.Is(err, auth.ErrInvalidSignature) {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		} else if err != nil {
+			slog.ErrorContext(ctx, "Some error occurred", "error", err)
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+	}
+
+	for _, scope := range requiredScopes {
+		if !principal.HasScope(scope) {
+			return nil, status.Errorf(codes.PermissionDenied, "credentials don't have the `%s` scope", scope)
+		}
+	}
+
+	return handler(auth.NewContext(ctx, principal), req)
+}
+
+// verifySignature is used to verify the signature of the RPC of the principal, passed in the `x-signature`,
+// `x-signature-timestamp` and `x-signature-nonce` metadata. The RPC is signed as a `POST` of the full method name, the
+// body being the deterministic protobuf encoding of the request.
+// It returns error wrapping `auth.ErrInvalidSignature` when the RPC fails verification.
+func (s *Server) verifySignature(principal *auth.Principal, md metadata.MD, fullMethod string, req interface{}) error {
+	var body []byte
+	if msg, ok := req.(proto.Message); ok {
+		var err error
+		if body, err = (proto.MarshalOptions{Deterministic: true}).Marshal(msg); err != nil {
+			return err
+		}
+	}
+
+	return auth.NewSignatureVerifier(s.RedisConn).Verify(principal, auth.SignedRequest{
+		Method:    http.MethodPost,
+		URI:       fullMethod,
+		Body:      body,
+		Timestamp: firstMetadata(md, signatureTimestampMetadata),
+		Nonce:     firstMetadata(md, signatureNonceMetadata),
+		Signature: firstMetadata(md, signatureMetadata),
+	})
+}
+
+// firstMetadata gets the first value of the given metadata key, empty when it isn't passed.
+func firstMetadata(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+
+	return ""
+}
@@ -0,0 +1,83 @@
+package rpc
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"strconv"
+	"time"
+
+	"currencyify/components/auth"
+	"currencyify/components/ratelimit"
+	"currencyify/rpc/pb"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// methodPaths maps the RPCs to the route paths of the same API over HTTP, so the route limits of the tiers apply to
+// both. Other RPCs, i.e. health and reflection, aren't rate limited.
+var methodPaths = map[string]string{
+	pb.Currencyify_Convert_FullMethodName:          "/convert/currency-convert",
+	pb.Currencyify_GetExchangeRates_FullMethodName: "/exchange-rate/currency-exchange-rate",
+	pb.Currencyify_ListCurrencies_FullMethodName:   "/exchange-rate/currencies",
+}
+
+// rateLimitUnaryInterceptor limits the RPCs per client, being the authenticated principal or else the peer IP in the
+// `anonymous` tier, using the limits and quotas of the client tier, same as the HTTP API.
+// It sets the `x-ratelimit-*` header metadata of the rate limited RPCs, and fails the RPC with resource exhausted,
+// along with the `retry-after` header metadata and the `RetryInfo` detail, when the limit or a quota is exceeded. RPCs
+// are served as is when the limiter fails.
+func (s *Server) rateLimitUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	path, ok := methodPaths[info.FullMethod]
+	if !ok || s.Limiter == nil {
+		return handler(ctx, req)
+	}
+	l := s.Limiter()
+	if l == nil {
+		return handler(ctx, req)
+	}
+
+	client, tier := peerClient(ctx), ratelimit.TierAnonymous
+	if principal, ok := auth.FromContext(ctx); ok {
+		client, tier = principal.Client(), principal.Tier
+	}
+
+	result, err := l.Allow(client, tier, path)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error limiting the RPC rate, serving it as is", "error", err)
+		return handler(ctx, req)
+	}
+
+	md := metadata.MD{}
+	if result.Limit > 0 {
+		md.Set("x-ratelimit-limit", strconv.Itoa(result.Limit))
+		md.Set("x-ratelimit-remaining", strconv.Itoa(result.Remaining))
+		md.Set("x-ratelimit-reset", ceilSeconds(result.Reset))
+	}
+	if !result.Allowed {
+		md.Set("retry-after", ceilSeconds(result.RetryAfter))
+	}
+	if md.Len() > 0 {
+		_ = grpc.SetHeader(ctx, md)
+	}
+
+	if !result.Allowed {
+		st := status.New(codes.ResourceExhausted, result.Reason)
+		if detailed, detailsErr := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(result.RetryAfter)}); detailsErr == nil {
+			st = detailed
+		}
+		return nil, st.Err()
+	}
+
+	return handler(ctx, req)
+}
+
+// ceilSeconds formats the given duration as whole seconds, rounded up.
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,257 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+
+	"currencyify/components"
+	"currencyify/components/audit"
+	"currencyify/components/auth"
+	"currencyify/components/convert"
+	"currencyify/components/exchange_rate"
+	"currencyify/components/ratelimit"
+	"currencyify/logging"
+	"currencyify/rpc/pb"
+	"currencyify/utils"
+
+	"github.com/gomodule/redigo/redis"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+)
+
+type Server struct {
+	pb.UnimplementedCurrencyifyServer
+
+	// RedisConn opens the redis connection passed to the components for caching.
+	RedisConn func() (redis.Conn, error)
+	// JWTVerifier gets the verifier of the bearer tokens, nil when bearer tokens aren't accepted.
+	JWTVerifier func() *auth.JWTVerifier
+	// Limiter gets the limiter of the RPCs, nil when rate limiting isn't configured.
+	Limiter func() *ratelimit.Limiter
+}
+
+// NewServer is used to create a new gRPC server backed by the components.
+// It returns the server instance.
+func NewServer() *Server {
+	return &Server{RedisConn: utils.Conn, JWTVerifier: auth.DefaultJWTVerifier, Limiter: ratelimit.DefaultLimiter}
+}
+
+// Run starts the gRPC server on the given port, along with the standard health and reflection services. RPCs of the
+// currencyify service are authenticated with API keys and rate limited, same as the HTTP API.
+// It blocks till the server stops and returns error, right away when the port isn't a valid port number, so an unset
+// `GRPC_PORT` fails the startup instead of listening on a random port.
+func Run(port string) error {
+	if p, err := strconv.Atoi(port); err != nil || p < 1 || p > 65535 {
+		return fmt.Errorf("invalid GRPC_PORT (%s), it should be a port number between 1 and 65535", port)
+	}
+
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%s", port))
+	if err != nil {
+		return err
+	}
+
+	server := NewServer()
+	s := grpc.NewServer(grpc.ChainUnaryInterceptor(requestIDUnaryInterceptor, server.authUnaryInterceptor, server.rateLimitUnaryInterceptor))
+	pb.RegisterCurrencyifyServer(s, server)
+
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("", grpc_health_v1.HealthCheckResponse_SERVING)
+	healthServer.SetServingStatus(pb.Currencyify_ServiceDesc.ServiceName, grpc_health_v1.HealthCheckResponse_SERVING)
+	grpc_health_v1.RegisterHealthServer(s, healthServer)
+
+	reflection.Register(s)
+
+	slog.Info("gRPC server listening", "address", lis.Addr().String())
+
+	return s.Serve(lis)
+}
+
+// Convert converts the given amount from source currency to target currency using the currency converter component.
+func (s *Server) Convert(ctx context.Context, req *pb.ConvertRequest) (*pb.ConvertResponse, error) {
+	base, err := s.initBaseComponent(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer closeRedisConn(base.RedisConn)
+
+	component, _ := components.ComponentMap["CurrencyConvert"](base).(convert.CurrencyConverter)
+	form := component.GetCurrencyConverterForm()
+	form.SourceCurrency = req.GetSourceCurrency()
+	form.TargetCurrency = req.GetTargetCurrency()
+	form.Amount = req.GetAmount()
+	form.TargetAmount = req.GetTargetAmount()
+
+	d, err := component.ConvertCurrency(form)
+	if err != nil {
+		return nil, toStatusError(ctx, component.GetCurrencyConverterAppError(), err)
+	}
+
+	resp, _ := d.ToProto().(*pb.ConvertResponse)
+
+	return resp, nil
+}
+
+// GetExchangeRates fetches the exchange rates of the target currencies using the currency exchange rate component.
+func (s *Server) GetExchangeRates(ctx context.Context, req *pb.GetExchangeRatesRequest) (*pb.GetExchangeRatesResponse, error) {
+	base, err := s.initBaseComponent(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer closeRedisConn(base.RedisConn)
+
+	component, _ := components.ComponentMap["CurrencyExchangeRate"](base).(exchange_rate.CurrencyExchangeRate)
+	form := component.GetCurrencyExchangeRateForm()
+	form.BaseCurrency = req.GetBaseCurrency()
+	form.TargetCurrencies = req.GetTargetCurrencies()
+
+	d, err := component.GetCurrencyExchangeRate(form)
+	if err != nil {
+		return nil, toStatusError(ctx, component.GetCurrencyExchangeRateAppError(), err)
+	}
+
+	resp, _ := d.ToProto().(*pb.GetExchangeRatesResponse)
+
+	return resp, nil
+}
+
+// ListCurrencies lists the supported currencies using the currency exchange rate component.
+func (s *Server) ListCurrencies(ctx context.Context, _ *pb.ListCurrenciesRequest) (*pb.ListCurrenciesResponse, error) {
+	base := &components.BaseComponent{
+		ReqCtx:   ctx,
+		AppError: new(utils.AppError),
+	}
+
+	component, _ := components.ComponentMap["CurrencyExchangeRate"](base).(exchange_rate.CurrencyExchangeRate)
+	currencyCodes, err := component.ListCurrencies()
+	if err != nil {
+		return nil, toStatusError(ctx, component.GetCurrencyExchangeRateAppError(), err)
+	}
+
+	return &pb.ListCurrenciesResponse{Currencies: currencyCodes}, nil
+}
+
+// initBaseComponent initializes the base of the component whose methods needs to be called, same as the controllers do.
+// It returns the base component and error.
+func (s *Server) initBaseComponent(ctx context.Context) (*components.BaseComponent, error) {
+	conn, err := s.RedisConn()
+	if err != nil {
+		slog.ErrorContext(ctx, "Some error occurred", "error", err)
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &components.BaseComponent{
+		ReqCtx:    audit.NewContext(ctx, auditRequest(ctx)),
+		AppError:  new(utils.AppError),
+		RedisConn: conn,
+	}, nil
+}
+
+// requestIDUnaryInterceptor identifies the RPCs by the `x-request-id` metadata, or else a new request ID, carried by
+// their context for the log lines and echoed in the `x-request-id` header metadata of the response.
+func requestIDUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	var requestID string
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get(requestIDMetadata); len(values) > 0 {
+		requestID = values[0]
+	}
+	requestID = logging.PropagateRequestID(requestID)
+	_ = grpc.SetHeader(ctx, metadata.Pairs(requestIDMetadata, requestID))
+
+	return handler(logging.NewContext(ctx, requestID), req)
+}
+
+// auditRequest identifies the RPC in the audit records, by the request ID of its context, or else a new request ID,
+// and by the authenticated principal, or else the peer address.
+// It returns the audit request.
+func auditRequest(ctx context.Context) audit.Request {
+	req := audit.Request{ID: logging.RequestID(ctx)}
+	if req.ID == "" {
+		req.ID = logging.NewRequestID()
+	}
+
+	if principal, ok := auth.FromContext(ctx); ok {
+		req.Client, req.ClientName = principal.Client(), principal.Name
+	} else if _, ok := peer.FromContext(ctx); ok {
+		req.Client = peerClient(ctx)
+	}
+
+	return req
+}
+
+// peerClient gets the client of the RPC identified by the IP of its peer.
+func peerClient(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return "ip:"
+	}
+
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		host = p.Addr.String()
+	}
+
+	return "ip:" + host
+}
+
+func closeRedisConn(conn redis.Conn) {
+	if conn == nil {
+		return
+	}
+	if err := conn.Close(); err != nil {
+		slog.Error("error closing redis connection", "error", err)
+	}
+}
+
+// toStatusError converts the error of the component to the gRPC status error, mapping its HTTP status to gRPC code.
+// Validation errors are attached to the status as `BadRequest` field violations.
+// It returns the status error.
+func toStatusError(ctx context.Context, appError *utils.AppError, err error) error {
+	slog.ErrorContext(ctx, "Some error occurred", "error", err)
+
+	httpStatus := http.StatusInternalServerError
+	if appError != nil && appError.Status != 0 {
+		httpStatus = appError.Status
+	}
+
+	code := codes.Internal
+	switch utils.HTTPStatus(err, httpStatus) {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		code = codes.InvalidArgument
+	case http.StatusNotFound:
+		code = codes.NotFound
+	case http.StatusBadGateway, http.StatusServiceUnavailable:
+		code = codes.Unavailable
+	case http.StatusGatewayTimeout:
+		code = codes.DeadlineExceeded
+	}
+
+	st := status.New(code, err.Error())
+
+	var validationErrors utils.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		badRequest := new(errdetails.BadRequest)
+		for _, e := range validationErrors {
+			badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       e.Field,
+				Description: fmt.Sprintf("%s: %s", e.Code, e.Message),
+			})
+		}
+		if detailed, detailsErr := st.WithDetails(badRequest); detailsErr == nil {
+			st = detailed
+		}
+	}
+
+	return st.Err()
+}
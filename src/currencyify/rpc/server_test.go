@@ -0,0 +1,562 @@
+package rpc
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"currencyify/components/auth"
+	"currencyify/components/ratelimit"
+	"currencyify/constants"
+	"currencyify/logging"
+	"currencyify/rpc/pb"
+	"currencyify/utils"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func newTestServer() *Server {
+	return &Server{
+		RedisConn: func() (redis.Conn, error) {
+			return nil, nil
+		},
+	}
+}
+
+func TestServer_Convert(t *testing.T) {
+	constants.CURRENCY_CODES_JSON_FILE_NAME = "../currency_codes.json"
+
+	type vars struct {
+		req *pb.ConvertRequest
+
+		headers map[string]string
+	}
+
+	testCases := []struct {
+		name string
+
+		vars vars
+
+		want   *pb.ConvertResponse
+		hasErr bool
+		code   codes.Code
+	}{
+		{
+			name: "should success to convert the given amount from source currency to target currency",
+			vars: vars{
+				req: &pb.ConvertRequest{SourceCurrency: "USD", TargetCurrency: "INR", Amount: 100},
+				headers: map[string]string{
+					"x-mock-api": "default",
+				},
+			},
+			want: &pb.ConvertResponse{SourceCurrency: "USD", TargetCurrency: "INR", Amount: 100, ConvertedAmount: 8277.1291},
+		},
+		{
+			name: "should fail with invalid argument when input params are invalid",
+			vars: vars{
+				req: &pb.ConvertRequest{SourceCurrency: "USD", Amount: 100},
+			},
+			hasErr: true,
+			code:   codes.InvalidArgument,
+		},
+		{
+			name: "should fail with unavailable when vendor API fails",
+			vars: vars{
+				req: &pb.ConvertRequest{SourceCurrency: "USD", TargetCurrency: "INR", Amount: 100},
+				headers: map[string]string{
+					"x-mock-api": "error_response",
+				},
+			},
+			hasErr: true,
+			code:   codes.Unavailable,
+		},
+		{
+			name: "should fail with deadline exceeded when vendor API times out",
+			vars: vars{
+				req: &pb.ConvertRequest{SourceCurrency: "USD", TargetCurrency: "INR", Amount: 100},
+				headers: map[string]string{
+					"x-mock-api": "timeout",
+				},
+			},
+			hasErr: true,
+			code:   codes.DeadlineExceeded,
+		},
+	}
+
+	for _, tCase := range testCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			// Setup
+			ctx := context.WithValue(context.Background(), "x-mock-headers", tCase.vars.headers)
+
+			// Run test
+			got, err := newTestServer().Convert(ctx, tCase.vars.req)
+
+			// Assert
+			if tCase.hasErr {
+				assert.Equalf(t, tCase.code, status.Code(err), "case: %v", tCase)
+			} else {
+				assert.NoErrorf(t, err, "case: %v", tCase)
+				assert.Truef(t, proto.Equal(tCase.want, got), "case: %v, got: %v", tCase, got)
+			}
+		})
+	}
+}
+
+func TestServer_GetExchangeRates(t *testing.T) {
+	constants.CURRENCY_CODES_JSON_FILE_NAME = "../currency_codes.json"
+
+	type vars struct {
+		req *pb.GetExchangeRatesRequest
+
+		headers map[string]string
+	}
+
+	lastUpdateTime := &timestamppb.Timestamp{Seconds: 1708949040}
+
+	testCases := []struct {
+		name string
+
+		vars vars
+
+		want   *pb.GetExchangeRatesResponse
+		hasErr bool
+		code   codes.Code
+	}{
+		{
+			name: "should success to fetch the currency exchange rates of the given currency codes",
+			vars: vars{
+				req: &pb.GetExchangeRatesRequest{BaseCurrency: "USD", TargetCurrencies: []string{"INR"}},
+				headers: map[string]string{
+					"x-mock-api": "default",
+				},
+			},
+			want: &pb.GetExchangeRatesResponse{
+				BaseCurrency: "USD",
+				ExchangeRates: map[string]*pb.ExchangeRate{
+					"INR": {CurrencyExchangeRate: "82.771291", Bid: "82.771291", Ask: "82.771291", Mid: "82.771291", LastUpdateTime: lastUpdateTime},
+					"JPY": {CurrencyExchangeRate: "150.608807", Bid: "150.608807", Ask: "150.608807", Mid: "150.608807", LastUpdateTime: lastUpdateTime},
+				},
+			},
+		},
+		{
+			name: "should fail with invalid argument when input params are invalid",
+			vars: vars{
+				req: &pb.GetExchangeRatesRequest{BaseCurrency: "USD"},
+			},
+			hasErr: true,
+			code:   codes.InvalidArgument,
+		},
+	}
+
+	for _, tCase := range testCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			// Setup
+			ctx := context.WithValue(context.Background(), "x-mock-headers", tCase.vars.headers)
+
+			// Run test
+			got, err := newTestServer().GetExchangeRates(ctx, tCase.vars.req)
+
+			// Assert
+			if tCase.hasErr {
+				assert.Equalf(t, tCase.code, status.Code(err), "case: %v", tCase)
+			} else {
+				assert.NoErrorf(t, err, "case: %v", tCase)
+				assert.Truef(t, proto.Equal(tCase.want, got), "case: %v, got: %v", tCase, got)
+			}
+		})
+	}
+}
+
+func TestServer_ListCurrencies(t *testing.T) {
+	constants.CURRENCY_CODES_JSON_FILE_NAME = "../currency_codes.json"
+
+	// Run test
+	got, err := newTestServer().ListCurrencies(context.Background(), &pb.ListCurrenciesRequest{})
+
+	// Assert
+	if assert.NoError(t, err) {
+		assert.Contains(t, got.GetCurrencies(), "USD")
+		assert.Contains(t, got.GetCurrencies(), "INR")
+		assert.IsIncreasing(t, got.GetCurrencies())
+	}
+}
+
+func TestServer_Convert_FieldViolations(t *testing.T) {
+	constants.CURRENCY_CODES_JSON_FILE_NAME = "../currency_codes.json"
+
+	// Run test
+	_, err := newTestServer().Convert(context.Background(), &pb.ConvertRequest{SourceCurrency: "USD", TargetCurrency: "India"})
+
+	// Assert
+	st, _ := status.FromError(err)
+	assert.Equal(t, codes.InvalidArgument, st.Code())
+	if assert.Len(t, st.Details(), 1) {
+		badRequest, ok := st.Details()[0].(*errdetails.BadRequest)
+		if assert.True(t, ok) && assert.Len(t, badRequest.FieldViolations, 2) {
+			assert.Equal(t, "target_currency", badRequest.FieldViolations[0].Field)
+			assert.Contains(t, badRequest.FieldViolations[0].Description, "unknown_currency: ")
+			assert.Equal(t, "amount", badRequest.FieldViolations[1].Field)
+		}
+	}
+}
+
+func TestServer_authUnaryInterceptor(t *testing.T) {
+	conn := utils.NewMockRedisConn()
+	ratesKey, _, _ := auth.CreateAPIKey(conn, "rates client", []string{auth.ScopeRates}, "")
+	signedKey, signedAPIKey, _ := auth.CreateAPIKey(conn, "signed client", []string{auth.ScopeRates}, "")
+	signingSecret, _ := auth.RotateSigningSecret(conn, signedAPIKey.ID)
+	req := &pb.GetExchangeRatesRequest{BaseCurrency: "USD", TargetCurrencies: []string{"INR"}}
+	body, _ := proto.MarshalOptions{Deterministic: true}.Marshal(req)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	sign := func(secret string, body []byte, nonce string) []string {
+		return []string{
+			"x-signature-timestamp", timestamp,
+			"x-signature-nonce", nonce,
+			"x-signature", auth.Sign(secret, auth.SignedRequest{Method: http.MethodPost, URI: pb.Currencyify_GetExchangeRates_FullMethodName, Body: body, Timestamp: timestamp, Nonce: nonce}),
+		}
+	}
+	s := &Server{
+		RedisConn: func() (redis.Conn, error) {
+			return conn, nil
+		},
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+
+	type vars struct {
+		enabled  string
+		method   string
+		key      string
+		metadata []string
+	}
+
+	testCases := []struct {
+		name string
+
+		vars vars
+
+		hasErr bool
+		code   codes.Code
+	}{
+		{
+			name: "should call the RPC as is when auth is disabled",
+			vars: vars{
+				method: pb.Currencyify_Convert_FullMethodName,
+			},
+		},
+		{
+			name: "should call the public RPCs without API key",
+			vars: vars{
+				enabled: "true",
+				method:  "/grpc.health.v1.Health/Check",
+			},
+		},
+		{
+			name: "should fail with unauthenticated without API key",
+			vars: vars{
+				enabled: "true",
+				method:  pb.Currencyify_GetExchangeRates_FullMethodName,
+			},
+			hasErr: true,
+			code:   codes.Unauthenticated,
+		},
+		{
+			name: "should fail with permission denied when the API key doesn't have the required scope",
+			vars: vars{
+				enabled: "true",
+				method:  pb.Currencyify_Convert_FullMethodName,
+				key:     ratesKey,
+			},
+			hasErr: true,
+			code:   codes.PermissionDenied,
+		},
+		{
+			name: "should call the RPC when the API key has the required scope",
+			vars: vars{
+				enabled: "true",
+				method:  pb.Currencyify_GetExchangeRates_FullMethodName,
+				key:     ratesKey,
+			},
+		},
+		{
+			name: "should fail with unauthenticated when the API key having a signing secret doesn't sign the RPC",
+			vars: vars{
+				enabled: "true",
+				method:  pb.Currencyify_GetExchangeRates_FullMethodName,
+				key:     signedKey,
+			},
+			hasErr: true,
+			code:   codes.Unauthenticated,
+		},
+		{
+			name: "should fail with unauthenticated when the signature doesn't cover the request",
+			vars: vars{
+				enabled:  "true",
+				method:   pb.Currencyify_GetExchangeRates_FullMethodName,
+				key:      signedKey,
+				metadata: sign(signingSecret, nil, "n1"),
+			},
+			hasErr: true,
+			code:   codes.Unauthenticated,
+		},
+		{
+			name: "should call the RPC when it is signed by the API key",
+			vars: vars{
+				enabled:  "true",
+				method:   pb.Currencyify_GetExchangeRates_FullMethodName,
+				key:      signedKey,
+				metadata: sign(signingSecret, body, "n2"),
+			},
+		},
+		{
+			name: "should fail with unauthenticated when the signed RPC is replayed",
+			vars: vars{
+				enabled:  "true",
+				method:   pb.Currencyify_GetExchangeRates_FullMethodName,
+				key:      signedKey,
+				metadata: sign(signingSecret, body, "n2"),
+			},
+			hasErr: true,
+			code:   codes.Unauthenticated,
+		},
+	}
+
+	for _, tCase := range testCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			// Setup
+			constants.AUTH_ENABLED = tCase.vars.enabled
+			ctx := context.Background()
+			if tCase.vars.key != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(append([]string{"x-api-key", tCase.vars.key}, tCase.vars.metadata...)...))
+			}
+
+			// Run test
+			got, err := s.authUnaryInterceptor(ctx, req, &grpc.UnaryServerInfo{FullMethod: tCase.vars.method}, handler)
+
+			// Assert
+			if tCase.hasErr {
+				assert.Equalf(t, tCase.code, status.Code(err), "case: %v", tCase)
+			} else {
+				assert.NoErrorf(t, err, "case: %v", tCase)
+				assert.Equal(t, "ok", got, "case: %v", tCase)
+			}
+		})
+	}
+	constants.AUTH_ENABLED = ""
+}
+
+func TestServer_rateLimitUnaryInterceptor(t *testing.T) {
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+
+	type vars struct {
+		configured bool
+		method     string
+		principal  *auth.Principal
+		requests   int
+	}
+
+	testCases := []struct {
+		name string
+
+		vars vars
+
+		hasErr         bool
+		wantRetryDelay time.Duration
+	}{
+		{
+			name: "should call the RPC as is when rate limiting isn't configured",
+			vars: vars{
+				method:   pb.Currencyify_Convert_FullMethodName,
+				requests: 3,
+			},
+		},
+		{
+			name: "should call the public RPCs without limit",
+			vars: vars{
+				configured: true,
+				method:     "/grpc.health.v1.Health/Check",
+				requests:   3,
+			},
+		},
+		{
+			name: "should call the RPC of the anonymous client within the limit",
+			vars: vars{
+				configured: true,
+				method:     pb.Currencyify_GetExchangeRates_FullMethodName,
+				requests:   2,
+			},
+		},
+		{
+			name: "should fail with resource exhausted along with the retry delay when the limit is exceeded",
+			vars: vars{
+				configured: true,
+				method:     pb.Currencyify_GetExchangeRates_FullMethodName,
+				requests:   3,
+			},
+			hasErr:         true,
+			wantRetryDelay: 30 * time.Second,
+		},
+		{
+			name: "should use the limit of the HTTP route of the RPC",
+			vars: vars{
+				configured: true,
+				method:     pb.Currencyify_Convert_FullMethodName,
+				requests:   2,
+			},
+			hasErr:         true,
+			wantRetryDelay: time.Minute,
+		},
+		{
+			name: "should use the limits of the principal tier",
+			vars: vars{
+				configured: true,
+				method:     pb.Currencyify_GetExchangeRates_FullMethodName,
+				principal:  &auth.Principal{ID: "a1b2c3", Tier: "premium", Method: auth.MethodAPIKey},
+				requests:   3,
+			},
+		},
+	}
+
+	for _, tCase := range testCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			// Setup
+			conn := utils.NewMockRedisConn()
+			s := &Server{
+				Limiter: func() *ratelimit.Limiter {
+					if !tCase.vars.configured {
+						return nil
+					}
+					return ratelimit.NewLimiter(func() (redis.Conn, error) {
+						return conn, nil
+					}, ratelimit.Config{Limits: map[string]map[string]ratelimit.Limit{
+						ratelimit.TierDefault: {"": {Burst: 2, Period: time.Minute}, "/convert": {Burst: 1, Period: time.Minute}},
+						"premium":             {"": {Burst: 10, Period: time.Minute}},
+					}})
+				},
+			}
+			ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("198.51.100.1"), Port: 50000}})
+			if tCase.vars.principal != nil {
+				ctx = auth.NewContext(ctx, tCase.vars.principal)
+			}
+
+			// Run test
+			var got interface{}
+			var err error
+			for i := 0; i < tCase.vars.requests; i++ {
+				got, err = s.rateLimitUnaryInterceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: tCase.vars.method}, handler)
+			}
+
+			// Assert
+			if tCase.hasErr {
+				assert.Equalf(t, codes.ResourceExhausted, status.Code(err), "case: %v", tCase)
+				details := status.Convert(err).Details()
+				if assert.Lenf(t, details, 1, "case: %v", tCase) {
+					retryInfo, _ := details[0].(*errdetails.RetryInfo)
+					if assert.NotNilf(t, retryInfo, "case: %v", tCase) {
+						assert.InDelta(t, tCase.wantRetryDelay.Seconds(), retryInfo.GetRetryDelay().AsDuration().Seconds(), 1, "case: %v", tCase)
+					}
+				}
+			} else {
+				assert.NoErrorf(t, err, "case: %v", tCase)
+				assert.Equal(t, "ok", got, "case: %v", tCase)
+			}
+		})
+	}
+}
+
+func TestRequestIDUnaryInterceptor(t *testing.T) {
+	testCases := []struct {
+		name string
+
+		requestID string
+
+		wantRequestID string
+	}{
+		{
+			name:          "should propagate the request ID given by the client",
+			requestID:     "req-1",
+			wantRequestID: "req-1",
+		},
+		{
+			name: "should generate the request ID when the client doesn't give one",
+		},
+	}
+
+	for _, tCase := range testCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			// Setup
+			ctx := context.Background()
+			if tCase.requestID != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("x-request-id", tCase.requestID))
+			}
+			var got string
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				got = logging.RequestID(ctx)
+				return auditRequest(ctx).ID, nil
+			}
+
+			// Run test
+			auditID, err := requestIDUnaryInterceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: pb.Currencyify_Convert_FullMethodName}, handler)
+
+			// Assert
+			assert.NoErrorf(t, err, "case: %v", tCase)
+			if tCase.wantRequestID != "" {
+				assert.Equal(t, tCase.wantRequestID, got, "case: %v", tCase)
+			} else {
+				assert.Regexp(t, `^[0-9a-f]{32}$`, got, "case: %v", tCase)
+			}
+			assert.Equal(t, got, auditID, "case: %v", tCase)
+		})
+	}
+}
+
+func TestRun_invalidPort(t *testing.T) {
+	testCases := []struct {
+		name string
+
+		port string
+	}{
+		{
+			name: "should fail when the port isn't set",
+		},
+		{
+			name: "should fail for the port which isn't a number",
+			port: "grpc",
+		},
+		{
+			name: "should fail for the port 0, which listens on a random port",
+			port: "0",
+		},
+		{
+			name: "should fail for the port out of range",
+			port: "65536",
+		},
+	}
+
+	for _, tCase := range testCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			// Run test
+			err := Run(tCase.port)
+
+			// Assert
+			if assert.Errorf(t, err, "case: %v", tCase) {
+				assert.EqualErrorf(t, err, "invalid GRPC_PORT ("+tCase.port+"), it should be a port number between 1 and 65535", "case: %v", tCase)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"currencyify/constants"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Exporters of the spans.
+const (
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+)
+
+const (
+	serviceName = "currencyify"
+	tracerName  = "currencyify"
+)
+
+// defaultOTLPEndpoint is the OTLP/HTTP traces endpoint of the collector, by default.
+const defaultOTLPEndpoint = "http://localhost:4318/v1/traces"
+
+// Init is used to set up the tracing of the requests, exporting the spans with the `TRACING_EXPORTER` exporter: `otlp`
+// to post them to the `TRACING_OTLP_ENDPOINT` collector, or `stdout` to print them. The W3C trace context of the
+// incoming requests is propagated to the vendor API calls even when the spans aren't exported.
+// It returns the func flushing the pending spans and stopping the exporter, and error for the invalid config.
+func Init() (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch constants.TRACING_EXPORTER {
+	case "":
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+		endpoint := constants.TRACING_OTLP_ENDPOINT
+		if endpoint == "" {
+			endpoint = defaultOTLPEndpoint
+		}
+		if exporter, err = otlptracehttp.New(context.Background(), otlptracehttp.WithEndpointURL(endpoint)); err != nil {
+			return nil, err
+		}
+	case ExporterStdout:
+		if exporter, err = stdouttrace.New(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter (%s)", constants.TRACING_EXPORTER)
+	}
+
+	ratio := 1.0
+	if constants.TRACING_SAMPLE_RATIO != "" {
+		if ratio, err = strconv.ParseFloat(constants.TRACING_SAMPLE_RATIO, 64); err != nil || ratio < 0 || ratio > 1 {
+			return nil, fmt.Errorf("invalid tracing sample ratio (%s)", constants.TRACING_SAMPLE_RATIO)
+		}
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(attribute.String("service.name", serviceName)))
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// Start is used to start a span as the child of the span of the given context, the background context when nil.
+// It returns the context carrying the span, and the span which should be ended.
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return otel.Tracer(tracerName).Start(ctx, name, opts...)
+}
+
+// End is used to end the span, marking it failed with the given error if any.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Extract is used to get the context continuing the trace of the incoming request, given by its `traceparent` header.
+func Extract(ctx context.Context, header http.Header) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(header))
+}
+
+// Inject is used to set the `traceparent` header of the outgoing request, continuing the trace of the given context.
+// Only the trace context is injected, so the baggage sent by the clients isn't forwarded to the vendor APIs.
+func Inject(ctx context.Context, header http.Header) {
+	propagation.TraceContext{}.Inject(ctx, propagation.HeaderCarrier(header))
+}
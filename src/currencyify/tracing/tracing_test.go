@@ -0,0 +1,160 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"currencyify/constants"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func TestInit(t *testing.T) {
+	type vars struct {
+		exporter    string
+		sampleRatio string
+	}
+
+	testCases := []struct {
+		name string
+
+		vars vars
+
+		wantRecording bool
+		hasErr        bool
+		err           string
+	}{
+		{
+			name: "should not export the spans when the exporter isn't set",
+		},
+		{
+			name:          "should export the spans to stdout",
+			vars:          vars{exporter: ExporterStdout},
+			wantRecording: true,
+		},
+		{
+			name:          "should export the spans to the OTLP collector",
+			vars:          vars{exporter: ExporterOTLP, sampleRatio: "1"},
+			wantRecording: true,
+		},
+		{
+			name:   "should fail for the unknown exporter",
+			vars:   vars{exporter: "jaeger"},
+			hasErr: true,
+			err:    "unknown tracing exporter (jaeger)",
+		},
+		{
+			name:   "should fail for the invalid sample ratio",
+			vars:   vars{exporter: ExporterOTLP, sampleRatio: "2"},
+			hasErr: true,
+			err:    "invalid tracing sample ratio (2)",
+		},
+	}
+
+	defaultProvider := otel.GetTracerProvider()
+	for _, tCase := range testCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			// Setup
+			constants.TRACING_EXPORTER = tCase.vars.exporter
+			constants.TRACING_SAMPLE_RATIO = tCase.vars.sampleRatio
+			otel.SetTracerProvider(noop.NewTracerProvider())
+
+			// Run test
+			shutdown, err := Init()
+
+			// Assert
+			if tCase.hasErr {
+				if assert.Errorf(t, err, "case: %v", tCase) {
+					assert.EqualErrorf(t, err, tCase.err, "case: %v", tCase)
+				}
+				return
+			}
+			assert.NoErrorf(t, err, "case: %v", tCase)
+			_, span := Start(context.Background(), "test")
+			assert.Equal(t, tCase.wantRecording, span.IsRecording(), "case: %v", tCase)
+			span.End()
+			assert.NoError(t, shutdown(context.Background()), "case: %v", tCase)
+		})
+	}
+	constants.TRACING_EXPORTER, constants.TRACING_SAMPLE_RATIO = "", ""
+	otel.SetTracerProvider(defaultProvider)
+}
+
+func TestInit_otlpEndpoint(t *testing.T) {
+	// Setup
+	requests := make(chan *http.Request, 1)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests <- r
+	}))
+	defer collector.Close()
+	constants.TRACING_EXPORTER = ExporterOTLP
+	constants.TRACING_OTLP_ENDPOINT = collector.URL + "/v1/traces"
+	defaultProvider := otel.GetTracerProvider()
+
+	// Run test
+	shutdown, err := Init()
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, span := Start(context.Background(), "test")
+	span.End()
+
+	// Assert
+	assert.NoError(t, shutdown(context.Background()))
+	select {
+	case r := <-requests:
+		assert.Equal(t, "/v1/traces", r.URL.Path)
+		assert.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"))
+	default:
+		assert.Fail(t, "spans should be posted to the OTLP endpoint")
+	}
+	constants.TRACING_EXPORTER, constants.TRACING_OTLP_ENDPOINT = "", ""
+	otel.SetTracerProvider(defaultProvider)
+}
+
+func TestEnd(t *testing.T) {
+	// Setup
+	recorder := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+	_, ok := tracer.Start(context.Background(), "ok")
+	_, failed := tracer.Start(context.Background(), "failed")
+
+	// Run test
+	End(ok, nil)
+	End(failed, errors.New("rate not found"))
+
+	// Assert
+	spans := recorder.Ended()
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+	assert.Equal(t, sdktrace.Status{Code: codes.Error, Description: "rate not found"}, spans[1].Status())
+	if assert.Len(t, spans[1].Events(), 1) {
+		assert.Equal(t, "exception", spans[1].Events()[0].Name)
+	}
+}
+
+func TestInject(t *testing.T) {
+	// Setup
+	_, err := Init()
+	assert.NoError(t, err)
+	incoming := http.Header{
+		"Traceparent": []string{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		"Baggage":     []string{"user.id=42,session=abc"},
+	}
+
+	// Run test
+	ctx := Extract(context.Background(), incoming)
+	outgoing := http.Header{}
+	Inject(ctx, outgoing)
+
+	// Assert
+	assert.Equal(t, incoming.Get("Traceparent"), outgoing.Get("Traceparent"), "trace should be continued even when spans aren't exported")
+	assert.Empty(t, outgoing.Get("Baggage"), "baggage of the clients should not be forwarded to the vendor APIs")
+}
@@ -0,0 +1,114 @@
+package utils
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"currencyify/constants"
+)
+
+var (
+	defaultTrustedProxies     []*net.IPNet
+	defaultTrustedProxiesOnce sync.Once
+)
+
+// trustedProxies gets the networks of the proxies whose `X-Forwarded-For` header is trusted.
+var trustedProxies = DefaultTrustedProxies
+
+// DefaultTrustedProxies is used to get the networks of the proxies set by `TRUSTED_PROXIES`, parsed on first use.
+// It returns the networks, none when the env var isn't set or is invalid.
+func DefaultTrustedProxies() []*net.IPNet {
+	defaultTrustedProxiesOnce.Do(func() {
+		proxies, err := ParseTrustedProxies(constants.TRUSTED_PROXIES)
+		if err != nil {
+			slog.Warn("X-Forwarded-For is ignored, error parsing TRUSTED_PROXIES", "error", err)
+			return
+		}
+
+		defaultTrustedProxies = proxies
+	})
+
+	return defaultTrustedProxies
+}
+
+// ParseTrustedProxies is used to parse the comma separated IPs or CIDR networks of the trusted proxies, i.e.
+// `10.0.0.0/8,192.168.1.10`.
+// It returns the networks and error.
+func ParseTrustedProxies(value string) ([]*net.IPNet, error) {
+	var proxies []*net.IPNet
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+
+		if !strings.Contains(item, "/") {
+			ip := net.ParseIP(item)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy (%s), it should be an IP or a CIDR network", item)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			proxies = append(proxies, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(item)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy (%s), it should be an IP or a CIDR network", item)
+		}
+		proxies = append(proxies, network)
+	}
+
+	return proxies, nil
+}
+
+// ClientIP is used to get the IP of the client of the request, being the address of the connection unless it comes
+// from a trusted proxy, in which case the `X-Forwarded-For` header is walked from its last entry, skipping the trusted
+// proxies. Entries set by the client itself, before the trusted proxies, are never used.
+// It returns the client IP.
+func ClientIP(r *http.Request) string {
+	ip := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+
+	proxies := trustedProxies()
+	if !isTrustedProxy(ip, proxies) {
+		return ip
+	}
+
+	forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for index := len(forwarded) - 1; index >= 0; index-- {
+		hop := strings.TrimSpace(forwarded[index])
+		if net.ParseIP(hop) == nil {
+			break
+		}
+		ip = hop
+		if !isTrustedProxy(hop, proxies) {
+			break
+		}
+	}
+
+	return ip
+}
+
+// isTrustedProxy checks whether the given IP is in the networks of the trusted proxies.
+func isTrustedProxy(ip string, proxies []*net.IPNet) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, network := range proxies {
+		if network.Contains(parsed) {
+			return true
+		}
+	}
+
+	return false
+}
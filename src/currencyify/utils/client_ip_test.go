@@ -0,0 +1,132 @@
+package utils
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTrustedProxies(t *testing.T) {
+	testCases := []struct {
+		name string
+
+		value string
+
+		want   []string
+		hasErr bool
+	}{
+		{
+			name:  "should parse the IPs and CIDR networks",
+			value: "10.0.0.0/8, 192.168.1.10,::1",
+			want:  []string{"10.0.0.0/8", "192.168.1.10/32", "::1/128"},
+		},
+		{
+			name:  "should parse no proxies from the empty value",
+			value: "",
+		},
+		{
+			name:   "should fail for the invalid proxy",
+			value:  "10.0.0.0/8,proxy",
+			hasErr: true,
+		},
+	}
+
+	for _, tCase := range testCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			// Run test
+			got, err := ParseTrustedProxies(tCase.value)
+
+			// Assert
+			if tCase.hasErr {
+				assert.Errorf(t, err, "case: %v", tCase)
+				return
+			}
+			assert.NoErrorf(t, err, "case: %v", tCase)
+			var networks []string
+			for _, network := range got {
+				networks = append(networks, network.String())
+			}
+			assert.Equal(t, tCase.want, networks, "case: %v", tCase)
+		})
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	proxies, _ := ParseTrustedProxies("10.0.0.0/8")
+
+	type vars struct {
+		remoteAddr string
+		forwarded  []string
+	}
+
+	testCases := []struct {
+		name string
+
+		vars vars
+
+		want string
+	}{
+		{
+			name: "should use the address of the connection",
+			vars: vars{
+				remoteAddr: "203.0.113.7:52311",
+			},
+			want: "203.0.113.7",
+		},
+		{
+			name: "should ignore X-Forwarded-For sent by an untrusted client",
+			vars: vars{
+				remoteAddr: "203.0.113.7:52311",
+				forwarded:  []string{"198.51.100.1"},
+			},
+			want: "203.0.113.7",
+		},
+		{
+			name: "should use the entry added by the trusted proxy",
+			vars: vars{
+				remoteAddr: "10.0.0.2:52311",
+				forwarded:  []string{"198.51.100.1, 203.0.113.7"},
+			},
+			want: "203.0.113.7",
+		},
+		{
+			name: "should skip the chained trusted proxies",
+			vars: vars{
+				remoteAddr: "10.0.0.2:52311",
+				forwarded:  []string{"198.51.100.1, 203.0.113.7", "10.1.2.3"},
+			},
+			want: "203.0.113.7",
+		},
+		{
+			name: "should use the proxy when X-Forwarded-For is missing",
+			vars: vars{
+				remoteAddr: "10.0.0.2:52311",
+			},
+			want: "10.0.0.2",
+		},
+	}
+
+	for _, tCase := range testCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			// Setup
+			trustedProxies = func() []*net.IPNet {
+				return proxies
+			}
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tCase.vars.remoteAddr
+			for _, forwarded := range tCase.vars.forwarded {
+				req.Header.Add("X-Forwarded-For", forwarded)
+			}
+
+			// Run test
+			got := ClientIP(req)
+
+			// Assert
+			assert.Equal(t, tCase.want, got, "case: %v", tCase)
+		})
+	}
+	trustedProxies = DefaultTrustedProxies
+}
@@ -0,0 +1,227 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"currencyify/rpc/pb"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+const (
+	MIMEJSON     = "application/json"
+	MIMEXML      = "application/xml"
+	MIMECSV      = "text/csv"
+	MIMEProtobuf = "application/x-protobuf"
+)
+
+// acceptedMediaTypes maps the media types accepted in the `Accept` header to the content types served for them.
+var acceptedMediaTypes = map[string]string{
+	"application/json":       MIMEJSON,
+	"application/xml":        MIMEXML,
+	"text/xml":               MIMEXML,
+	"text/csv":               MIMECSV,
+	"application/x-protobuf": MIMEProtobuf,
+	"application/protobuf":   MIMEProtobuf,
+	"application/*":          MIMEJSON,
+	"*/*":                    MIMEJSON,
+}
+
+var xmlNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9._-]*$`)
+
+// CSVMarshaler is implemented by the response data which can be served as a CSV table.
+type CSVMarshaler interface {
+	MarshalCSV() [][]string
+}
+
+// ProtoConverter is implemented by the response data which has a protobuf message defined in `rpc/pb`.
+type ProtoConverter interface {
+	ToProto() proto.Message
+}
+
+// NegotiateContentType picks the content type of the response from the `Accept` header, preferring the media types
+// with higher quality and then the ones listed first. JSON is picked when none of the accepted media types is served.
+// It returns the content type.
+func NegotiateContentType(accept string) string {
+	contentType, bestQuality := MIMEJSON, 0.0
+	for _, mediaRange := range strings.Split(accept, ",") {
+		params := strings.Split(mediaRange, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(params[0]))
+
+		quality := 1.0
+		for _, param := range params[1:] {
+			if key, value, ok := strings.Cut(strings.TrimSpace(param), "="); ok && key == "q" {
+				quality, _ = strconv.ParseFloat(value, 64)
+			}
+		}
+
+		if t, ok := acceptedMediaTypes[mediaType]; ok && quality > bestQuality {
+			contentType, bestQuality = t, quality
+		}
+	}
+
+	return contentType
+}
+
+// MarshalXML encodes the API response as XML, with the same element names and order as the JSON fields. Array items
+// are encoded as `item` elements, and map keys which aren't valid element names as `entry` elements with `key` attribute.
+// It returns the XML bytes and error.
+func MarshalXML(resp APIResponse) ([]byte, error) {
+	jsonBytes, err := json.Marshal(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(jsonBytes))
+	dec.UseNumber()
+
+	buf := bytes.NewBufferString(xml.Header)
+	enc := xml.NewEncoder(buf)
+	if err = writeXMLElement(dec, enc, xml.StartElement{Name: xml.Name{Local: "response"}}); err != nil {
+		return nil, err
+	}
+	if err = enc.Flush(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// writeXMLElement reads the next JSON value from the decoder and writes it as the given XML element.
+func writeXMLElement(dec *json.Decoder, enc *xml.Encoder, start xml.StartElement) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	if err = enc.EncodeToken(start); err != nil {
+		return err
+	}
+
+	switch token {
+	case json.Delim('{'):
+		for dec.More() {
+			keyToken, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			key, _ := keyToken.(string)
+
+			child := xml.StartElement{Name: xml.Name{Local: key}}
+			if !xmlNameRegexp.MatchString(key) {
+				child = xml.StartElement{Name: xml.Name{Local: "entry"}, Attr: []xml.Attr{{Name: xml.Name{Local: "key"}, Value: key}}}
+			}
+			if err = writeXMLElement(dec, enc, child); err != nil {
+				return err
+			}
+		}
+		if _, err = dec.Token(); err != nil {
+			return err
+		}
+	case json.Delim('['):
+		for dec.More() {
+			if err = writeXMLElement(dec, enc, xml.StartElement{Name: xml.Name{Local: "item"}}); err != nil {
+				return err
+			}
+		}
+		if _, err = dec.Token(); err != nil {
+			return err
+		}
+	case nil:
+		// null is encoded as empty element
+	default:
+		if err = enc.EncodeToken(xml.CharData(fmt.Sprintf("%v", token))); err != nil {
+			return err
+		}
+	}
+
+	return enc.EncodeToken(start.End())
+}
+
+// MarshalCSV encodes the API response as CSV, when its data is a table. Validation errors are encoded as a
+// `code,field,value,message` table and other errors as a `code,error` table.
+// It returns the CSV bytes, whether the response can be encoded as CSV and error.
+func MarshalCSV(resp APIResponse) ([]byte, bool, error) {
+	var records [][]string
+	if len(resp.Errors) > 0 {
+		records = [][]string{{"code", "field", "value", "message"}}
+		for _, e := range resp.Errors {
+			records = append(records, []string{e.Code, e.Field, e.ValueString(), e.Message})
+		}
+	} else if resp.Error != "" {
+		records = [][]string{{"code", "error"}, {strconv.Itoa(resp.Code), resp.Error}}
+	} else if data, ok := resp.Data.(CSVMarshaler); ok {
+		records = data.MarshalCSV()
+	} else {
+		return nil, false, nil
+	}
+
+	buf := new(bytes.Buffer)
+	if err := writeCSV(buf, records); err != nil {
+		return nil, true, err
+	}
+
+	return buf.Bytes(), true, nil
+}
+
+func writeCSV(w io.Writer, records [][]string) error {
+	writer := csv.NewWriter(w)
+	if err := writer.WriteAll(records); err != nil {
+		return err
+	}
+
+	return writer.Error()
+}
+
+// MarshalProto encodes the API response as the `APIResponse` protobuf message, when its data has a protobuf message.
+// It returns the protobuf bytes, whether the response can be encoded as protobuf and error.
+func MarshalProto(resp APIResponse) ([]byte, bool, error) {
+	msg := &pb.APIResponse{
+		Code:  int32(resp.Code),
+		Error: resp.Error,
+	}
+	for _, e := range resp.Errors {
+		msg.Errors = append(msg.Errors, &pb.ValidationError{Code: e.Code, Field: e.Field, Value: e.ValueString(), Message: e.Message})
+	}
+
+	if resp.Error == "" && resp.Data != nil {
+		data, ok := resp.Data.(ProtoConverter)
+		if !ok {
+			return nil, false, nil
+		}
+
+		var err error
+		if msg.Data, err = anypb.New(data.ToProto()); err != nil {
+			return nil, true, err
+		}
+	}
+
+	protoBytes, err := proto.Marshal(msg)
+	if err != nil {
+		return nil, true, err
+	}
+
+	return protoBytes, true, nil
+}
+
+// SortedKeys is used to get the keys of the given map in sorted order, i.e. to encode maps deterministically.
+// It returns the sorted keys.
+func SortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,171 @@
+package utils
+
+import (
+	"errors"
+	"testing"
+
+	"currencyify/rpc/pb"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+)
+
+type testTable struct {
+	Rows [][]string `json:"rows"`
+}
+
+func (t *testTable) MarshalCSV() [][]string {
+	return t.Rows
+}
+
+type testConvertResponse struct {
+	SourceCurrency string `json:"source_currency"`
+}
+
+func (r *testConvertResponse) ToProto() proto.Message {
+	return &pb.ConvertResponse{SourceCurrency: r.SourceCurrency}
+}
+
+func TestNegotiateContentType(t *testing.T) {
+	testCases := []struct {
+		name string
+
+		accept string
+
+		want string
+	}{
+		{
+			name:   "should fall back to JSON when accept header is empty",
+			accept: "",
+			want:   MIMEJSON,
+		},
+		{
+			name:   "should pick XML for text/xml",
+			accept: "text/xml",
+			want:   MIMEXML,
+		},
+		{
+			name:   "should pick the media type having the highest quality",
+			accept: "application/xml;q=0.5, text/csv;q=0.9, */*;q=0.1",
+			want:   MIMECSV,
+		},
+		{
+			name:   "should pick the media type listed first among the same quality",
+			accept: "application/x-protobuf, application/json",
+			want:   MIMEProtobuf,
+		},
+		{
+			name:   "should fall back to JSON when none of the media types is served",
+			accept: "text/html, image/png",
+			want:   MIMEJSON,
+		},
+	}
+
+	for _, tCase := range testCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			// Run test
+			got := NegotiateContentType(tCase.accept)
+
+			// Assert
+			assert.Equal(t, tCase.want, got, "case: %v", tCase)
+		})
+	}
+}
+
+func TestMarshalXML(t *testing.T) {
+	testCases := []struct {
+		name string
+
+		resp APIResponse
+
+		want string
+	}{
+		{
+			name: "should success to encode the response data in JSON fields order",
+			resp: PrepareResponse(map[string]interface{}{"base_currency": "USD", "exchange_rates": map[string]interface{}{"INR": map[string]interface{}{"bid": "82.7"}}}, nil, 200),
+			want: `<?xml version="1.0" encoding="UTF-8"?>` + "\n" + `<response><code>200</code><data><base_currency>USD</base_currency><exchange_rates><INR><bid>82.7</bid></INR></exchange_rates></data><error></error></response>`,
+		},
+		{
+			name: "should success to encode arrays as items and invalid element names as entries",
+			resp: PrepareResponse(map[string]interface{}{"items": []interface{}{1.5, "a"}, "1x": true}, nil, 200),
+			want: `<?xml version="1.0" encoding="UTF-8"?>` + "\n" + `<response><code>200</code><data><entry key="1x">true</entry><items><item>1.5</item><item>a</item></items></data><error></error></response>`,
+		},
+		{
+			name: "should success to encode the error response",
+			resp: PrepareResponse(nil, errors.New("some <error>"), 400),
+			want: `<?xml version="1.0" encoding="UTF-8"?>` + "\n" + `<response><code>400</code><data></data><error>some &lt;error&gt;</error></response>`,
+		},
+	}
+
+	for _, tCase := range testCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			// Run test
+			got, err := MarshalXML(tCase.resp)
+
+			// Assert
+			assert.NoErrorf(t, err, "case: %v", tCase)
+			assert.Equal(t, tCase.want, string(got), "case: %v", tCase)
+		})
+	}
+}
+
+func TestMarshalCSV(t *testing.T) {
+	testCases := []struct {
+		name string
+
+		resp APIResponse
+
+		want   string
+		wantOk bool
+	}{
+		{
+			name:   "should success to encode the tabular data",
+			resp:   PrepareResponse(&testTable{Rows: [][]string{{"currency", "rate"}, {"INR", "82.7"}}}, nil, 200),
+			want:   "currency,rate\nINR,82.7\n",
+			wantOk: true,
+		},
+		{
+			name:   "should success to encode the error response",
+			resp:   PrepareResponse(nil, errors.New("some error"), 400),
+			want:   "code,error\n400,some error\n",
+			wantOk: true,
+		},
+		{
+			name:   "should not encode the non-tabular data",
+			resp:   PrepareResponse(map[string]string{"a": "b"}, nil, 200),
+			wantOk: false,
+		},
+	}
+
+	for _, tCase := range testCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			// Run test
+			got, ok, err := MarshalCSV(tCase.resp)
+
+			// Assert
+			assert.NoErrorf(t, err, "case: %v", tCase)
+			assert.Equal(t, tCase.wantOk, ok, "case: %v", tCase)
+			assert.Equal(t, tCase.want, string(got), "case: %v", tCase)
+		})
+	}
+}
+
+func TestMarshalProto(t *testing.T) {
+	// Run test
+	got, ok, err := MarshalProto(PrepareResponse(&testConvertResponse{SourceCurrency: "USD"}, nil, 200))
+
+	// Assert
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	msg := new(pb.APIResponse)
+	assert.NoError(t, proto.Unmarshal(got, msg))
+	assert.Equal(t, int32(200), msg.Code)
+	data := new(pb.ConvertResponse)
+	assert.NoError(t, msg.Data.UnmarshalTo(data))
+	assert.Equal(t, "USD", data.SourceCurrency)
+
+	_, ok, err = MarshalProto(PrepareResponse(map[string]string{"a": "b"}, nil, 200))
+	assert.NoError(t, err)
+	assert.False(t, ok, "data without protobuf message should not be encoded")
+}
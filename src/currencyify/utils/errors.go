@@ -0,0 +1,107 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// ErrVendorTimeout is wrapped by the errors of the vendor API calls which didn't complete in time.
+var ErrVendorTimeout = errors.New("vendor API timed out")
+
+// MalformedRequestError is returned when the request body can't be decoded into the form.
+type MalformedRequestError struct {
+	Err error
+}
+
+// VendorError is returned when the vendor API can't be reached or responds with an error status.
+type VendorError struct {
+	StatusCode int
+	Message    string
+}
+
+// RateNotFoundError is returned when the vendor API doesn't have the exchange rate of the requested currency.
+type RateNotFoundError struct {
+	Currency string
+}
+
+// NewMalformedRequestError is used to wrap the error occurred while decoding the request body.
+// It returns the malformed request error.
+func NewMalformedRequestError(err error) *MalformedRequestError {
+	return &MalformedRequestError{Err: err}
+}
+
+func (e *MalformedRequestError) Error() string {
+	return fmt.Sprintf("malformed request body: %v", e.Err)
+}
+
+func (e *MalformedRequestError) Unwrap() error {
+	return e.Err
+}
+
+// Status is used to get the HTTP status of the malformed request. Well-formed JSON having values of the wrong type is
+// unprocessable, anything else is a bad request.
+// It returns the HTTP status.
+func (e *MalformedRequestError) Status() int {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(e.Err, &typeErr) {
+		return http.StatusUnprocessableEntity
+	}
+
+	return http.StatusBadRequest
+}
+
+func (e *VendorError) Error() string {
+	return e.Message
+}
+
+func (e *RateNotFoundError) Error() string {
+	return fmt.Sprintf("exchange rate not found for: %s", e.Currency)
+}
+
+// newVendorRequestError is used to type the error occurred while calling the vendor API, wrapping `ErrVendorTimeout`
+// when the call timed out.
+// It returns the typed error.
+func newVendorRequestError(err error) error {
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return fmt.Errorf("%w: %v", ErrVendorTimeout, err)
+	}
+
+	return &VendorError{Message: err.Error()}
+}
+
+// HTTPStatus maps the typed errors to their HTTP status, i.e. 400/422 for bad input, 404 for the currencies unknown
+// to vendor API, 502 for the vendor API failures, 503 when the vendor API call budget is exhausted and 504 for the
+// vendor API timeouts.
+// It returns the mapped status, or the given status when the error isn't typed.
+func HTTPStatus(err error, status int) int {
+	var malformedErr *MalformedRequestError
+	var validationErrors ValidationErrors
+	var rateNotFoundErr *RateNotFoundError
+	var vendorErr *VendorError
+
+	switch {
+	case err == nil:
+		return status
+	case errors.As(err, &malformedErr):
+		return malformedErr.Status()
+	case errors.As(err, &validationErrors):
+		return http.StatusBadRequest
+	case errors.As(err, &rateNotFoundErr):
+		return http.StatusNotFound
+	case errors.Is(err, ErrVendorTimeout):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, ErrVendorBudgetExhausted):
+		return http.StatusServiceUnavailable
+	case errors.As(err, &vendorErr):
+		return http.StatusBadGateway
+	case status == 0:
+		return http.StatusInternalServerError
+	}
+
+	return status
+}
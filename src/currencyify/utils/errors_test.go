@@ -0,0 +1,144 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPStatus(t *testing.T) {
+	typeErr := json.Unmarshal([]byte(`{"amount":"ten"}`), &struct {
+		Amount float64 `json:"amount"`
+	}{})
+	validationErrors := ValidationErrors{}
+	validationErrors.Add(ErrCodeRequired, "source_currency", nil, "`source_currency` parameter is required")
+
+	testCases := []struct {
+		name string
+
+		err    error
+		status int
+
+		want int
+	}{
+		{
+			name:   "should map the malformed JSON to bad request",
+			err:    NewMalformedRequestError(json.Unmarshal([]byte(`{`), &struct{}{})),
+			status: http.StatusInternalServerError,
+			want:   http.StatusBadRequest,
+		},
+		{
+			name:   "should map the JSON values of the wrong type to unprocessable entity",
+			err:    NewMalformedRequestError(typeErr),
+			status: http.StatusInternalServerError,
+			want:   http.StatusUnprocessableEntity,
+		},
+		{
+			name:   "should map the validation errors to bad request",
+			err:    validationErrors,
+			status: http.StatusInternalServerError,
+			want:   http.StatusBadRequest,
+		},
+		{
+			name:   "should map the unknown currency of vendor API to not found",
+			err:    &RateNotFoundError{Currency: "XYZ"},
+			status: http.StatusInternalServerError,
+			want:   http.StatusNotFound,
+		},
+		{
+			name:   "should map the vendor API timeout to gateway timeout",
+			err:    fmt.Errorf("%w: context deadline exceeded", ErrVendorTimeout),
+			status: http.StatusInternalServerError,
+			want:   http.StatusGatewayTimeout,
+		},
+		{
+			name:   "should map the vendor API error to bad gateway",
+			err:    fmt.Errorf("fetching rates: %w", &VendorError{StatusCode: http.StatusServiceUnavailable, Message: "unavailable"}),
+			status: http.StatusInternalServerError,
+			want:   http.StatusBadGateway,
+		},
+		{
+			name:   "should map the exhausted vendor API call budget to service unavailable",
+			err:    fmt.Errorf("%w: 1000 calls per day to api.fxratesapi.com", ErrVendorBudgetExhausted),
+			status: http.StatusInternalServerError,
+			want:   http.StatusServiceUnavailable,
+		},
+		{
+			name:   "should keep the given status of the untyped error",
+			err:    errors.New("some error"),
+			status: http.StatusConflict,
+			want:   http.StatusConflict,
+		},
+		{
+			name: "should fall back to internal server error when status isn't given",
+			err:  errors.New("some error"),
+			want: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tCase := range testCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			// Run test
+			got := HTTPStatus(tCase.err, tCase.status)
+
+			// Assert
+			assert.Equal(t, tCase.want, got, "case: %v", tCase)
+		})
+	}
+}
+
+func TestGetExternalAPIResponse_Errors(t *testing.T) {
+	testCases := []struct {
+		name string
+
+		mockType string
+
+		want int
+	}{
+		{
+			name:     "should report the vendor API client error as bad gateway",
+			mockType: "error_response",
+			want:     http.StatusBadGateway,
+		},
+		{
+			name:     "should report the vendor API server error as bad gateway",
+			mockType: "vendor_error",
+			want:     http.StatusBadGateway,
+		},
+		{
+			name:     "should report the vendor API timeout as gateway timeout",
+			mockType: "timeout",
+			want:     http.StatusGatewayTimeout,
+		},
+		{
+			name:     "should report the exhausted vendor API call budget as service unavailable",
+			mockType: "budget_exhausted",
+			want:     http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tCase := range testCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			// Setup
+			req := ExternalRequest{
+				Name:    "GetLatestCurrencyRate",
+				Type:    http.MethodGet,
+				Headers: map[string]string{"Content-Type": "application/json"},
+			}
+			reqCtx := context.WithValue(context.Background(), "x-mock-headers", map[string]string{"x-mock-api": tCase.mockType})
+
+			// Run test
+			_, err := GetExternalAPIResponse(req, reqCtx)
+
+			// Assert
+			if assert.Errorf(t, err, "case: %v", tCase) {
+				assert.Equal(t, tCase.want, HTTPStatus(err, http.StatusInternalServerError), "case: %v", tCase)
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"strings"
 )
 
@@ -24,11 +25,24 @@ func (r *ExternalRequest) DoMock() (*http.Response, error) {
 	case "error_response":
 		rr.WriteHeader(400)
 		_, _ = rr.WriteString(`{"errors":"some error"}`)
+	case "vendor_error":
+		rr.WriteHeader(503)
+		_, _ = rr.WriteString(`{"errors":"service unavailable"}`)
+	case "timeout":
+		err = fmt.Errorf("Get %q: %w", r.URL, os.ErrDeadlineExceeded)
+	case "budget_exhausted":
+		return nil, fmt.Errorf("%w: 1000 calls per day to %s", ErrVendorBudgetExhausted, VendorProvider(r.URL))
+	case "bid_ask":
+		rr.WriteHeader(200)
+		_, _ = rr.WriteString(`{"success":true,"terms":"https://fxratesapi.com/legal/terms-conditions","privacy":"https://fxratesapi.com/legal/privacy-policy","timestamp":1708949040,"date":"2024-02-26T12:04:00.000Z","base":"USD","rates":{"INR":82.771291,"JPY":150.608807},"bid":{"INR":82.7,"JPY":150.5},"ask":{"INR":82.8,"JPY":150.7}}`)
 	default:
 		switch r.Name {
 		case "GetLatestCurrencyRate":
 			rr.WriteHeader(200)
-			_, _ = rr.WriteString(`{"success":true,"terms":"https://fxratesapi.com/legal/terms-conditions","privacy":"https://fxratesapi.com/legal/privacy-policy","timestamp":1708949040,"date":"2024-02-26T12:04:00.000Z","base":"USD","rates":{"INR":82.771291,"JPY":150.608807,"USD":1}}`)
+			_, _ = rr.WriteString(`{"success":true,"terms":"https://fxratesapi.com/legal/terms-conditions","privacy":"https://fxratesapi.com/legal/privacy-policy","timestamp":1708949040,"date":"2024-02-26T12:04:00.000Z","base":"USD","rates":{"INR":82.771291,"JPY":150.608807,"EUR":0.92,"USD":1}}`)
+		case "GetHistoricalCurrencyRate":
+			rr.WriteHeader(200)
+			_, _ = rr.WriteString(`{"success":true,"terms":"https://fxratesapi.com/legal/terms-conditions","privacy":"https://fxratesapi.com/legal/privacy-policy","timestamp":1705363140,"date":"2024-01-15T23:59:00.000Z","base":"USD","rates":{"INR":83,"JPY":147,"EUR":0.9,"USD":1}}`)
 		case "GetLatestCurrencyExchangeRates":
 			rr.WriteHeader(200)
 			_, _ = rr.WriteString(`{"success":true,"terms":"https://fxratesapi.com/legal/terms-conditions","privacy":"https://fxratesapi.com/legal/privacy-policy","timestamp":1708949040,"date":"2024-02-26T12:04:00.000Z","base":"USD","rates":{"INR":82.771291,"JPY":150.608807}}`)
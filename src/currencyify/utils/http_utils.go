@@ -6,18 +6,26 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
-	"os"
 	"strings"
 	"time"
 
+	"currencyify/constants"
+	"currencyify/metrics"
+	"currencyify/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/net/http2"
 )
 
-var transport *http2.Transport
+// defaultHTTPClientTimeout is the timeout of the vendor API calls, by default.
+const defaultHTTPClientTimeout = 30 * time.Second
+
+var transport = &http2.Transport{}
 
-var httpClient *http.Client
+// httpClient is the client of the vendor API calls, rebuilt by Init once the env vars are loaded.
+var httpClient = &http.Client{Transport: transport, Timeout: defaultHTTPClientTimeout}
 
 type ExternalRequest struct {
 	Name    string                 `json:"name"`
@@ -70,17 +78,27 @@ func GetAPIResponse(reqCtx context.Context, name, url, method string, body map[s
 	return resp.Data, nil
 }
 
-// GetExternalAPIResponse calls external api and adds the api response to the request context.
+// GetExternalAPIResponse calls external api and adds the api response to the request context. Failures of the call are
+// returned as `VendorError`, wrapping `ErrVendorTimeout` instead when the call timed out, and `ErrVendorBudgetExhausted`
+// is returned when the call isn't made as the call budget of the vendor API is exhausted.
+// The calls are observed by their provider, API name and outcome.
 // It returns the updated context and error.
-func GetExternalAPIResponse(req ExternalRequest, reqCtx context.Context) (context.Context, error) {
+func GetExternalAPIResponse(req ExternalRequest, reqCtx context.Context) (rCtx context.Context, err error) {
 	req.ReqCtx = reqCtx
 
-	if resp, err := req.Do(); err != nil {
+	start := time.Now()
+	defer func() {
+		metrics.ObserveVendorRequest(VendorProvider(req.URL), req.Name, vendorOutcome(err), time.Since(start))
+	}()
+
+	if resp, err := req.Do(); errors.Is(err, ErrVendorBudgetExhausted) {
 		return reqCtx, err
+	} else if err != nil {
+		return reqCtx, newVendorRequestError(err)
 	} else if re, err := ParseAsJSON(resp); err != nil {
-		return reqCtx, err
+		return reqCtx, &VendorError{StatusCode: resp.StatusCode, Message: err.Error()}
 	} else if re.StatusCode < 200 || re.StatusCode > 299 {
-		return reqCtx, errors.New(fmt.Sprintf("%v", re.Response["errors"]))
+		return reqCtx, &VendorError{StatusCode: re.StatusCode, Message: fmt.Sprintf("%v", re.Response["errors"])}
 	} else {
 		apiResp := APIResponse{
 			Code: re.StatusCode,
@@ -92,19 +110,54 @@ func GetExternalAPIResponse(req ExternalRequest, reqCtx context.Context) (contex
 	}
 }
 
-// Do method execute the ExternalRequest.
-func (r *ExternalRequest) Do() (*http.Response, error) {
+// vendorOutcome is used to tell the outcome of the vendor API call from its error.
+func vendorOutcome(err error) string {
+	switch {
+	case err == nil:
+		return metrics.VendorOutcomeSuccess
+	case errors.Is(err, ErrVendorBudgetExhausted):
+		return metrics.VendorOutcomeBudgetExhausted
+	case errors.Is(err, ErrVendorTimeout):
+		return metrics.VendorOutcomeTimeout
+	default:
+		return metrics.VendorOutcomeError
+	}
+}
+
+// Do method execute the ExternalRequest, counting the call against the vendor API call budgets. The call is traced as
+// the client span of the request trace, which is continued by the vendor API through the `traceparent` header.
+func (r *ExternalRequest) Do() (resp *http.Response, err error) {
 	r.GetMockHeadersFromContext()
+
+	ctx, span := tracing.Start(r.ReqCtx, "vendor "+r.Name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		attribute.String("vendor.provider", VendorProvider(r.URL)),
+		attribute.String("vendor.api", r.Name),
+		attribute.String("http.method", http.MethodGet),
+	))
+	defer func() {
+		spanErr := err
+		if resp != nil {
+			span.SetAttributes(attribute.Int("http.status_code", resp.StatusCode))
+			if spanErr == nil && resp.StatusCode >= http.StatusBadRequest {
+				spanErr = fmt.Errorf("vendor API responded with status %d", resp.StatusCode)
+			}
+		}
+		tracing.End(span, spanErr)
+	}()
+
 	if _, ok := r.Headers["x-mock-api"]; ok {
 		return r.DoMock()
 	}
 
-	var resp *http.Response
+	if err := reserveVendorCall(r.URL); err != nil {
+		return nil, err
+	}
+
 	body, err := r.getRequestBody()
 	if err != nil {
 		return nil, err
 	}
-	req, err := http.NewRequest(r.Type, r.URL, body)
+	req, err := http.NewRequestWithContext(ctx, r.Type, r.URL, body)
 	if err != nil {
 		return nil, err
 	}
@@ -119,10 +172,21 @@ func (r *ExternalRequest) Do() (*http.Response, error) {
 	}
 	req.URL.RawQuery = q.Encode()
 
-	resp, err = httpClient.Get(req.URL.String())
+	// The vendor APIs are queried by the URL alone, carrying only the trace context. The call is canceled along with the
+	// request context.
+	getReq, err := http.NewRequestWithContext(ctx, http.MethodGet, req.URL.String(), nil)
 	if err != nil {
 		return nil, err
 	}
+	tracing.Inject(ctx, getReq.Header)
+
+	resp, err = httpClient.Do(getReq)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+		recordVendorSuccess(r.URL)
+	}
 
 	return resp, err
 }
@@ -189,34 +253,20 @@ func ParseAsJSON(response *http.Response) (*ExternalJSONResponse, error) {
 	return extResp, nil
 }
 
-// updateDurationFromEnv fetches given var from env and sets it to passed duration variable.
-func updateDurationFromEnv(enVar string, rVar *time.Duration) {
-	if val := os.Getenv(enVar); val != "" {
-		if d, err := time.ParseDuration(val); err == nil {
-			*rVar = d
-		} else {
-			log.Printf("Error parsing duration %v: %v", enVar, err)
+// Init is used to build the client of the vendor API calls, timing out after `HTTP_CLIENT_TIMEOUT`, 30s by default.
+// It should be called once the constants are initialized from the env vars.
+// It returns error if `HTTP_CLIENT_TIMEOUT` is invalid.
+func Init() error {
+	timeout := defaultHTTPClientTimeout
+	if constants.HTTP_CLIENT_TIMEOUT != "" {
+		d, err := time.ParseDuration(constants.HTTP_CLIENT_TIMEOUT)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("invalid HTTP_CLIENT_TIMEOUT (%s), it should be a positive duration, i.e. `30s`", constants.HTTP_CLIENT_TIMEOUT)
 		}
+		timeout = d
 	}
-}
 
-func init() {
-	//var idleConnTimeout = 30 * time.Second
-	//var respHeaderTimeout = 5 * time.Second
-	//var maxIdleConnections = 100
-	//
-	//updateDurationFromEnv("HTTP_IDLE_CONN_TIMEOUT", &idleConnTimeout)
-	//updateDurationFromEnv("HTTP_RESPONSE_HEADER_TIMEOUT", &respHeaderTimeout)
-	//
-	//if val := os.Getenv("HTTP_MAX_IDLE_CONNS"); val != "" {
-	//	if i, err := strconv.Atoi(val); err == nil {
-	//		maxIdleConnections = i
-	//	} else {
-	//		log.Printf("Error parsing integer HTTP_MAX_IDLE_CONNS: %v", err)
-	//	}
-	//}
-
-	transport = &http2.Transport{}
-
-	httpClient = &http.Client{Transport: transport}
+	httpClient = &http.Client{Transport: transport, Timeout: timeout}
+
+	return nil
 }
@@ -0,0 +1,106 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"currencyify/constants"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInit(t *testing.T) {
+	testCases := []struct {
+		name string
+
+		timeout string
+
+		want   time.Duration
+		hasErr bool
+	}{
+		{
+			name: "should build the client timing out after 30s by default",
+			want: 30 * time.Second,
+		},
+		{
+			name:    "should build the client timing out after the configured timeout",
+			timeout: "5s",
+			want:    5 * time.Second,
+		},
+		{
+			name:    "should fail for the invalid timeout",
+			timeout: "5 seconds",
+			hasErr:  true,
+		},
+		{
+			name:    "should fail for the non positive timeout",
+			timeout: "0s",
+			hasErr:  true,
+		},
+	}
+
+	for _, tCase := range testCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			// Setup
+			constants.HTTP_CLIENT_TIMEOUT = tCase.timeout
+			httpClient.Timeout = time.Minute
+
+			// Run test
+			err := Init()
+
+			// Assert
+			if tCase.hasErr {
+				assert.Errorf(t, err, "case: %v", tCase)
+				assert.Equalf(t, time.Minute, httpClient.Timeout, "case: %v", tCase)
+				return
+			}
+			assert.NoErrorf(t, err, "case: %v", tCase)
+			assert.Equalf(t, tCase.want, httpClient.Timeout, "case: %v", tCase)
+		})
+	}
+	constants.HTTP_CLIENT_TIMEOUT = ""
+	_ = Init()
+}
+
+func TestExternalRequest_Do_canceled(t *testing.T) {
+	// Setup
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer server.Close()
+	client := httpClient
+	httpClient = server.Client()
+	defer func() {
+		httpClient = client
+	}()
+	vendorUsageConn = func() (redis.Conn, error) {
+		return nil, errors.New("redis unavailable")
+	}
+	defer func() {
+		vendorUsageConn = Conn
+	}()
+	reqCtx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req := ExternalRequest{
+		Name:    "GetLatestCurrencyRate",
+		Type:    http.MethodGet,
+		URL:     server.URL,
+		Headers: map[string]string{"Content-Type": "application/json"},
+	}
+
+	// Run test
+	start := time.Now()
+	_, err := GetExternalAPIResponse(req, reqCtx)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrVendorTimeout, "the vendor API call should end with the request context")
+	assert.Less(t, time.Since(start), time.Second)
+}
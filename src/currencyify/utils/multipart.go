@@ -0,0 +1,28 @@
+package utils
+
+import (
+	"mime/multipart"
+	"net/http"
+)
+
+// streamedMultipartForm marks the requests whose multipart body is left unread, for their handlers to stream its parts.
+var streamedMultipartForm = new(multipart.Form)
+
+// SkipMultipartParsing is used to keep `ParseMultipartForm` from reading the multipart body of the given request into
+// memory or temp files, as it returns early for the requests already holding a multipart form.
+func SkipMultipartParsing(r *http.Request) {
+	if r.MultipartForm == nil {
+		r.MultipartForm = streamedMultipartForm
+	}
+}
+
+// MultipartReader is used to get the reader of the parts of the multipart body of the given request, streaming the
+// body left unread by `SkipMultipartParsing`.
+// It returns the reader and error if the request isn't multipart or its body was already parsed.
+func MultipartReader(r *http.Request) (*multipart.Reader, error) {
+	if r.MultipartForm == streamedMultipartForm {
+		r.MultipartForm = nil
+	}
+
+	return r.MultipartReader()
+}
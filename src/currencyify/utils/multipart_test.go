@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMultipartReader(t *testing.T) {
+	body := new(bytes.Buffer)
+	writer := multipart.NewWriter(body)
+	_ = writer.WriteField("target_currency", "INR")
+	part, _ := writer.CreateFormFile("file", "ledger.csv")
+	_, _ = part.Write([]byte("amount,currency\n10,USD\n"))
+	_ = writer.Close()
+
+	testCases := []struct {
+		name string
+
+		skipParsing bool
+
+		wantParts []string
+		hasErr    bool
+	}{
+		{
+			name:        "should stream the parts of the body left unread",
+			skipParsing: true,
+			wantParts:   []string{"target_currency=INR", "file=amount,currency\n10,USD\n"},
+		},
+		{
+			name:   "should fail when the body was already parsed",
+			hasErr: true,
+		},
+	}
+
+	for _, tCase := range testCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			// Setup
+			req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body.Bytes()))
+			req.Header.Set("Content-Type", writer.FormDataContentType())
+			if tCase.skipParsing {
+				SkipMultipartParsing(req)
+			}
+			assert.NoErrorf(t, req.ParseMultipartForm(1<<20), "case: %v", tCase)
+
+			// Run test
+			reader, err := MultipartReader(req)
+
+			// Assert
+			if tCase.hasErr {
+				assert.Errorf(t, err, "case: %v", tCase)
+				return
+			}
+			assert.NoErrorf(t, err, "case: %v", tCase)
+			var parts []string
+			for {
+				part, err := reader.NextPart()
+				if err != nil {
+					assert.Equalf(t, io.EOF, err, "case: %v", tCase)
+					break
+				}
+				value, _ := io.ReadAll(part)
+				parts = append(parts, part.FormName()+"="+string(value))
+			}
+			assert.Equalf(t, tCase.wantParts, parts, "case: %v", tCase)
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// MockRedisConn is an in-memory redis connection for the tests, supporting the string, hash and transaction commands.
+// Keys don't expire, their TTL being the one they were last set with, and watched keys never abort the transactions.
+type MockRedisConn struct {
+	mu      sync.Mutex
+	strings map[string]string
+	hashes  map[string]map[string]string
+	ttls    map[string]int64
+	// queued holds the commands sent after MULTI, run on EXEC.
+	queued [][]interface{}
+	multi  bool
+}
+
+// NewMockRedisConn is used to create a new in-memory redis connection.
+// It returns the connection.
+func NewMockRedisConn() *MockRedisConn {
+	return &MockRedisConn{strings: make(map[string]string), hashes: make(map[string]map[string]string), ttls: make(map[string]int64)}
+}
+
+func (c *MockRedisConn) Close() error {
+	return nil
+}
+
+func (c *MockRedisConn) Err() error {
+	return nil
+}
+
+func (c *MockRedisConn) Do(commandName string, args ...interface{}) (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch strings.ToUpper(commandName) {
+	case "":
+		return nil, nil
+	case "MULTI":
+		c.multi = true
+		return "OK", nil
+	case "EXEC":
+		replies := make([]interface{}, 0, len(c.queued))
+		for _, command := range c.queued {
+			reply, err := c.do(command[0].(string), command[1:]...)
+			if err != nil {
+				return nil, err
+			}
+			replies = append(replies, reply)
+		}
+		c.queued, c.multi = nil, false
+		return replies, nil
+	}
+
+	if c.multi {
+		c.queued = append(c.queued, append([]interface{}{commandName}, args...))
+		return "QUEUED", nil
+	}
+
+	return c.do(commandName, args...)
+}
+
+func (c *MockRedisConn) do(commandName string, args ...interface{}) (interface{}, error) {
+	strArgs := make([]string, len(args))
+	for index, arg := range args {
+		strArgs[index] = fmt.Sprintf("%v", arg)
+	}
+
+	switch strings.ToUpper(commandName) {
+	case "PING":
+		return "PONG", nil
+	case "WATCH", "UNWATCH":
+		return "OK", nil
+	case "EXPIRE", "PEXPIRE", "EXPIREAT":
+		if strings.ToUpper(commandName) == "EXPIRE" {
+			c.ttls[strArgs[0]], _ = strconv.ParseInt(strArgs[1], 10, 64)
+		}
+		return int64(1), nil
+	case "TTL":
+		if _, ok := c.strings[strArgs[0]]; !ok {
+			return int64(-2), nil
+		} else if ttl, ok := c.ttls[strArgs[0]]; ok {
+			return ttl, nil
+		}
+		return int64(-1), nil
+	case "SET":
+		if _, ok := c.strings[strArgs[0]]; ok && strings.EqualFold(strArgs[len(strArgs)-1], "NX") {
+			return nil, nil
+		}
+		c.strings[strArgs[0]] = strArgs[1]
+		delete(c.ttls, strArgs[0])
+		for index := 2; index+1 < len(strArgs); index++ {
+			if strings.EqualFold(strArgs[index], "EX") {
+				c.ttls[strArgs[0]], _ = strconv.ParseInt(strArgs[index+1], 10, 64)
+			}
+		}
+		return "OK", nil
+	case "GET":
+		if val, ok := c.strings[strArgs[0]]; ok {
+			return []byte(val), nil
+		}
+		return nil, nil
+	case "INCR", "DECR":
+		val, _ := strconv.ParseInt(c.strings[strArgs[0]], 10, 64)
+		if strings.ToUpper(commandName) == "INCR" {
+			val++
+		} else {
+			val--
+		}
+		c.strings[strArgs[0]] = strconv.FormatInt(val, 10)
+		return val, nil
+	case "HSET":
+		if _, ok := c.hashes[strArgs[0]]; !ok {
+			c.hashes[strArgs[0]] = make(map[string]string)
+		}
+		for index := 1; index+1 < len(strArgs); index += 2 {
+			c.hashes[strArgs[0]][strArgs[index]] = strArgs[index+1]
+		}
+		return int64(1), nil
+	case "HMGET":
+		reply := make([]interface{}, 0, len(strArgs)-1)
+		for _, field := range strArgs[1:] {
+			if val, ok := c.hashes[strArgs[0]][field]; ok {
+				reply = append(reply, []byte(val))
+			} else {
+				reply = append(reply, nil)
+			}
+		}
+		return reply, nil
+	case "HGET":
+		if val, ok := c.hashes[strArgs[0]][strArgs[1]]; ok {
+			return []byte(val), nil
+		}
+		return nil, nil
+	case "HGETALL":
+		reply := make([]interface{}, 0)
+		for field, val := range c.hashes[strArgs[0]] {
+			reply = append(reply, []byte(field), []byte(val))
+		}
+		return reply, nil
+	case "HDEL":
+		if _, ok := c.hashes[strArgs[0]][strArgs[1]]; ok {
+			delete(c.hashes[strArgs[0]], strArgs[1])
+			return int64(1), nil
+		}
+		return int64(0), nil
+	default:
+		return nil, fmt.Errorf("unsupported command: %s", commandName)
+	}
+}
+
+func (c *MockRedisConn) Send(commandName string, args ...interface{}) error {
+	_, err := c.Do(commandName, args...)
+	return err
+}
+
+func (c *MockRedisConn) Flush() error {
+	return nil
+}
+
+func (c *MockRedisConn) Receive() (interface{}, error) {
+	return nil, nil
+}
@@ -3,15 +3,81 @@ package utils
 import (
 	"errors"
 	"fmt"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
 
 	"currencyify/constants"
 
 	"github.com/gomodule/redigo/redis"
 )
 
+// defaultStaleCacheExpiry is the number of seconds the stale copies of the cached data are kept by default.
+const defaultStaleCacheExpiry = 7 * 24 * 60 * 60
+
+// ratesCachedAtKey is the key holding the time the rates were last cached.
+const ratesCachedAtKey = "cache:rates-cached-at"
+
+// defaultPoolMaxIdle is the number of idle connections kept by the redis pool by default.
+const defaultPoolMaxIdle = 10
+
+// poolIdleTimeout is the duration the idle connections are kept by the redis pool.
+const poolIdleTimeout = 4 * time.Minute
+
+var (
+	pool     *redis.Pool
+	poolOnce sync.Once
+)
+
+// Pool is used to get the pool of the redis connections, created on first use. It keeps `REDIS_POOL_MAX_IDLE` idle
+// connections, 10 by default, and opens up to `REDIS_POOL_MAX_ACTIVE` connections, unlimited by default.
+// It returns the pool.
+func Pool() *redis.Pool {
+	poolOnce.Do(func() {
+		maxIdle, maxActive := defaultPoolMaxIdle, 0
+		if constants.REDIS_POOL_MAX_IDLE != "" {
+			if n, err := strconv.Atoi(constants.REDIS_POOL_MAX_IDLE); err == nil {
+				maxIdle = n
+			} else {
+				slog.Warn("Error parsing integer REDIS_POOL_MAX_IDLE", "error", err)
+			}
+		}
+		if constants.REDIS_POOL_MAX_ACTIVE != "" {
+			if n, err := strconv.Atoi(constants.REDIS_POOL_MAX_ACTIVE); err == nil {
+				maxActive = n
+			} else {
+				slog.Warn("Error parsing integer REDIS_POOL_MAX_ACTIVE", "error", err)
+			}
+		}
+
+		pool = &redis.Pool{
+			MaxIdle:     maxIdle,
+			MaxActive:   maxActive,
+			IdleTimeout: poolIdleTimeout,
+			Dial: func() (redis.Conn, error) {
+				return redis.Dial("tcp", fmt.Sprintf("%s:%s", constants.REDIS_HOST, constants.REDIS_PORT))
+			},
+			// Connections idle for a while are checked, as redis may have been restarted meanwhile.
+			TestOnBorrow: func(conn redis.Conn, idleSince time.Time) error {
+				if time.Since(idleSince) < time.Minute {
+					return nil
+				}
+				_, err := conn.Do("PING")
+				return err
+			},
+		}
+	})
+
+	return pool
+}
+
+// Conn is used to get a connection of the redis pool, which should be closed to return it to the pool.
+// It returns the connection and error connecting to redis.
 func Conn() (redis.Conn, error) {
-	conn, err := redis.Dial("tcp", fmt.Sprintf("%s:%s", constants.REDIS_HOST, constants.REDIS_PORT))
-	if err != nil {
+	conn := Pool().Get()
+	if err := conn.Err(); err != nil {
+		_ = conn.Close()
 		return nil, err
 	}
 
@@ -35,3 +101,81 @@ func SetData(conn redis.Conn, key, val string, ttl int) (bool, error) {
 
 	return true, nil
 }
+
+// SetRateData is used to cache the rate, also refreshing the time the rates were last cached, which expires along with
+// the rate so it is kept as long as the cache holds non-expired rates.
+func SetRateData(conn redis.Conn, key, val string, ttl int) (bool, error) {
+	if status, err := SetData(conn, key, val, ttl); err != nil || !status {
+		return status, err
+	}
+
+	return SetData(conn, ratesCachedAtKey, time.Now().UTC().Format(time.RFC3339), ttl)
+}
+
+// GetRatesCachedAt is used to get the time the rates were last cached.
+// It returns the time, zero when the cache doesn't hold non-expired rates, and error.
+func GetRatesCachedAt(conn redis.Conn) (time.Time, error) {
+	data, err := redis.String(conn.Do("GET", ratesCachedAtKey))
+	if errors.Is(err, redis.ErrNil) {
+		return time.Time{}, nil
+	} else if err != nil {
+		return time.Time{}, errors.New("failed to get data from Redis")
+	}
+
+	return time.Parse(time.RFC3339, data)
+}
+
+// StaleKey is used to get the key of the stale copy of the cached data, kept after the data expires to be served when
+// the vendor API call budget is exhausted.
+func StaleKey(key string) string {
+	return "stale:" + key
+}
+
+// SetStaleData is used to keep the stale copy of the cached data for `STALE_CACHE_EXPIRY` seconds, 7 days by default.
+func SetStaleData(conn redis.Conn, key, val string) (bool, error) {
+	ttl := defaultStaleCacheExpiry
+	if constants.STALE_CACHE_EXPIRY != "" {
+		var err error
+		if ttl, err = strconv.Atoi(constants.STALE_CACHE_EXPIRY); err != nil {
+			return false, err
+		}
+	}
+
+	return SetData(conn, StaleKey(key), val, ttl)
+}
+
+func GetTTL(conn redis.Conn, key string) (int, error) {
+	ttl, err := redis.Int(conn.Do("TTL", key))
+	if err != nil || ttl < 0 {
+		return 0, errors.New("failed to get TTL from Redis")
+	}
+
+	return ttl, nil
+}
+
+func HGetAllData(conn redis.Conn, key string) (map[string]string, error) {
+	data, err := redis.StringMap(conn.Do("HGETALL", key))
+	if err != nil {
+		return nil, errors.New("failed to get hash from Redis")
+	}
+
+	return data, nil
+}
+
+func HSetData(conn redis.Conn, key, field, val string) (bool, error) {
+	_, err := conn.Do("HSET", key, field, val)
+	if err != nil {
+		return false, errors.New("failed to set hash field in Redis")
+	}
+
+	return true, nil
+}
+
+func HDelData(conn redis.Conn, key, field string) (bool, error) {
+	deleted, err := redis.Int(conn.Do("HDEL", key, field))
+	if err != nil {
+		return false, errors.New("failed to delete hash field from Redis")
+	}
+
+	return deleted > 0, nil
+}
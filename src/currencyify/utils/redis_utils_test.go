@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetTTL(t *testing.T) {
+	conn := NewMockRedisConn()
+	_, _ = SetData(conn, "USD-INR", "82.771291", 60)
+	_, _ = conn.Do("SET", "USD-JPY", "150.608807")
+
+	testCases := []struct {
+		name string
+
+		key string
+
+		want   int
+		hasErr bool
+	}{
+		{
+			name: "should get the remaining TTL of the key",
+			key:  "USD-INR",
+			want: 60,
+		},
+		{
+			name:   "should fail for the key not expiring",
+			key:    "USD-JPY",
+			hasErr: true,
+		},
+		{
+			name:   "should fail for the missing key",
+			key:    "USD-EUR",
+			hasErr: true,
+		},
+	}
+
+	for _, tCase := range testCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			// Run test
+			got, err := GetTTL(conn, tCase.key)
+
+			// Assert
+			if tCase.hasErr {
+				assert.Errorf(t, err, "case: %v", tCase)
+				return
+			}
+			assert.NoErrorf(t, err, "case: %v", tCase)
+			assert.Equalf(t, tCase.want, got, "case: %v", tCase)
+		})
+	}
+}
@@ -1,9 +1,14 @@
 package utils
 
+import (
+	"errors"
+)
+
 type APIResponse struct {
-	Code  int         `json:"code"`
-	Data  interface{} `json:"data"`
-	Error string      `json:"error"`
+	Code   int               `json:"code"`
+	Data   interface{}       `json:"data"`
+	Error  string            `json:"error"`
+	Errors []ValidationError `json:"errors,omitempty"`
 }
 
 type Data map[string]interface{}
@@ -13,7 +18,7 @@ type AppError struct {
 	Status int
 }
 
-// PrepareResponse Prepares response format.
+// PrepareResponse Prepares response format. Validation errors are also listed one by one in `Errors`.
 // It returns APIResponse.
 func PrepareResponse(data interface{}, err error, code int) APIResponse {
 	r := APIResponse{
@@ -24,6 +29,11 @@ func PrepareResponse(data interface{}, err error, code int) APIResponse {
 		r.Data = data
 	} else {
 		r.Error = err.Error()
+
+		var validationErrors ValidationErrors
+		if errors.As(err, &validationErrors) {
+			r.Errors = validationErrors
+		}
 	}
 
 	return r
@@ -0,0 +1,111 @@
+package utils
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Validation error codes, stable for the clients to map the errors.
+const (
+	ErrCodeRequired        = "required"
+	ErrCodeUnknownCurrency = "unknown_currency"
+	ErrCodeConflict        = "conflicting_params"
+	ErrCodeTooManyItems    = "too_many_items"
+	ErrCodeInvalidFormat   = "invalid_format"
+	ErrCodeOutOfRange      = "out_of_range"
+	ErrCodeUnsupported     = "unsupported"
+)
+
+const MIMEProblemJSON = "application/problem+json"
+
+type ValidationError struct {
+	Code    string      `json:"code"`
+	Field   string      `json:"field"`
+	Value   interface{} `json:"value,omitempty"`
+	Message string      `json:"message"`
+}
+
+// ValidationErrors holds all the violations of a form, so they can be reported at once.
+type ValidationErrors []ValidationError
+
+// Problem is the RFC 7807 problem details document of the error responses.
+type Problem struct {
+	Type   string            `json:"type"`
+	Title  string            `json:"title"`
+	Status int               `json:"status"`
+	Detail string            `json:"detail"`
+	Errors []ValidationError `json:"errors,omitempty"`
+}
+
+// Add is used to add a violation of the given field, along with the rejected value.
+func (e *ValidationErrors) Add(code, field string, value interface{}, message string) {
+	*e = append(*e, ValidationError{
+		Code:    code,
+		Field:   field,
+		Value:   value,
+		Message: message,
+	})
+}
+
+// ValueString is used to get the rejected value as string, i.e. for the formats not having typed values.
+// It returns the rejected value, or empty string when there isn't any.
+func (e ValidationError) ValueString() string {
+	if e.Value == nil {
+		return ""
+	}
+
+	return fmt.Sprintf("%v", e.Value)
+}
+
+// Error joins the messages of the violations, one per line.
+func (e ValidationErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, v := range e {
+		messages = append(messages, v.Message)
+	}
+
+	return strings.Join(messages, "\n")
+}
+
+// Err is used to get the violations as error.
+// It returns nil if there isn't any violation.
+func (e ValidationErrors) Err() error {
+	if len(e) == 0 {
+		return nil
+	}
+
+	return e
+}
+
+// NewProblem is used to build the problem details document of the error response.
+// It returns the problem details document.
+func NewProblem(resp APIResponse) *Problem {
+	return &Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(resp.Code),
+		Status: resp.Code,
+		Detail: resp.Error,
+		Errors: resp.Errors,
+	}
+}
+
+// AcceptsProblemJSON checks whether the `Accept` header lists the RFC 7807 problem details media type.
+func AcceptsProblemJSON(accept string) bool {
+	for _, mediaRange := range strings.Split(accept, ",") {
+		params := strings.Split(mediaRange, ";")
+		if strings.ToLower(strings.TrimSpace(params[0])) != MIMEProblemJSON {
+			continue
+		}
+
+		for _, param := range params[1:] {
+			if strings.ReplaceAll(param, " ", "") == "q=0" {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,98 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrepareResponse(t *testing.T) {
+	validationErrors := ValidationErrors{}
+	validationErrors.Add(ErrCodeRequired, "source_currency", nil, "`source_currency` parameter is required")
+	validationErrors.Add(ErrCodeUnknownCurrency, "target_currency", "India", "`target_currency` not found in our database")
+
+	testCases := []struct {
+		name string
+
+		err error
+
+		wantError  string
+		wantErrors []ValidationError
+	}{
+		{
+			name:       "should success to list the validation errors one by one",
+			err:        validationErrors,
+			wantError:  "`source_currency` parameter is required\n`target_currency` not found in our database",
+			wantErrors: validationErrors,
+		},
+		{
+			name:       "should success to list the wrapped validation errors",
+			err:        fmt.Errorf("invalid form: %w", validationErrors),
+			wantError:  "invalid form: `source_currency` parameter is required\n`target_currency` not found in our database",
+			wantErrors: validationErrors,
+		},
+		{
+			name:      "should not list the errors other than validation errors",
+			err:       errors.New("some error"),
+			wantError: "some error",
+		},
+	}
+
+	for _, tCase := range testCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			// Run test
+			got := PrepareResponse(nil, tCase.err, 400)
+
+			// Assert
+			assert.Equal(t, tCase.wantError, got.Error, "case: %v", tCase)
+			assert.Equal(t, tCase.wantErrors, got.Errors, "case: %v", tCase)
+		})
+	}
+}
+
+func TestValidationErrors_Err(t *testing.T) {
+	errs := ValidationErrors{}
+	assert.NoError(t, errs.Err(), "no violation should be nil error")
+
+	errs.Add(ErrCodeOutOfRange, "threshold", -1.0, "`threshold` parameter should not be negative")
+	assert.EqualError(t, errs.Err(), "`threshold` parameter should not be negative")
+	assert.Equal(t, "-1", errs[0].ValueString())
+}
+
+func TestAcceptsProblemJSON(t *testing.T) {
+	testCases := []struct {
+		name string
+
+		accept string
+
+		want bool
+	}{
+		{
+			name:   "should accept problem details listed along with JSON",
+			accept: "application/json, application/problem+json",
+			want:   true,
+		},
+		{
+			name:   "should not accept problem details having zero quality",
+			accept: "application/problem+json; q=0",
+			want:   false,
+		},
+		{
+			name:   "should not accept problem details not listed",
+			accept: "*/*",
+			want:   false,
+		},
+	}
+
+	for _, tCase := range testCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			// Run test
+			got := AcceptsProblemJSON(tCase.accept)
+
+			// Assert
+			assert.Equal(t, tCase.want, got, "case: %v", tCase)
+		})
+	}
+}
@@ -0,0 +1,212 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"time"
+
+	"currencyify/constants"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// vendorUsageKeyPrefix is the prefix of the redis counters of the vendor API calls, per provider per window.
+const vendorUsageKeyPrefix = "vendor-usage"
+
+// vendorProvidersHashKey is the redis hash holding the time of the last call of each vendor API provider.
+const vendorProvidersHashKey = "vendor-usage:providers"
+
+// vendorSuccessesHashKey is the redis hash holding the time of the last successful call of each vendor API provider.
+const vendorSuccessesHashKey = "vendor-usage:successes"
+
+// ErrVendorBudgetExhausted is returned when the vendor API isn't called as its call budget is exhausted.
+var ErrVendorBudgetExhausted = errors.New("vendor API call budget exhausted")
+
+// vendorUsageConn opens the redis connection holding the vendor API call counters.
+var vendorUsageConn = Conn
+
+var vendorUsageNow = time.Now
+
+type VendorUsage struct {
+	// Provider is the host of the vendor API.
+	Provider   string        `json:"provider"`
+	LastCallAt time.Time     `json:"last_call_at"`
+	Windows    []UsageWindow `json:"windows"`
+}
+
+type UsageWindow struct {
+	// Period is the period of the window, one of `minute`, `day` and `month`.
+	Period string `json:"period"`
+	Calls  int    `json:"calls"`
+	// Budget is the number of calls allowed in the window, 0 when unlimited.
+	Budget  int       `json:"budget"`
+	ResetAt time.Time `json:"reset_at"`
+}
+
+type usageWindow struct {
+	period string
+	key    string
+	budget int
+	end    time.Time
+}
+
+// reserveVendorCall is used to count the call of the given vendor API URL in the current minute, day and month, before
+// it is made. Calls exceeding the budget of a window aren't counted, nor made. Calls are made uncounted when redis
+// can't be reached, so the vendor API stays available.
+// It returns error wrapping `ErrVendorBudgetExhausted` when a budget is exhausted.
+func reserveVendorCall(rawURL string) error {
+	conn, err := vendorUsageConn()
+	if err != nil {
+		slog.Warn("Error counting vendor API call, making it uncounted", "error", err)
+		return nil
+	}
+	defer func() {
+		if err := conn.Close(); err != nil {
+			slog.Error("error closing redis connection", "error", err)
+		}
+	}()
+
+	provider := VendorProvider(rawURL)
+	now := vendorUsageNow().UTC()
+	counted := make([]string, 0)
+	for _, window := range usageWindows(now) {
+		key := fmt.Sprintf("%s:%s:%s:%s", vendorUsageKeyPrefix, provider, window.period, window.key)
+		calls, err := redis.Int(conn.Do("INCR", key))
+		if err != nil {
+			slog.Warn("Error counting vendor API call, making it uncounted", "error", err)
+			return nil
+		}
+		counted = append(counted, key)
+		if calls == 1 {
+			if _, err = conn.Do("EXPIREAT", key, window.end.Unix()); err != nil {
+				slog.Error("error setting vendor API call counter expiry", "key", key, "error", err)
+			}
+		}
+
+		if window.budget > 0 && calls > window.budget {
+			for _, countedKey := range counted {
+				if _, err = conn.Do("DECR", countedKey); err != nil {
+					slog.Error("error uncounting refused vendor API call", "key", countedKey, "error", err)
+				}
+			}
+			return fmt.Errorf("%w: %d calls per %s to %s", ErrVendorBudgetExhausted, window.budget, window.period, provider)
+		}
+	}
+
+	if _, err = HSetData(conn, vendorProvidersHashKey, provider, now.Format(time.RFC3339)); err != nil {
+		slog.Error("error setting vendor API last call time", "error", err)
+	}
+
+	return nil
+}
+
+// recordVendorSuccess is used to record the time of the successful call of the given vendor API URL, shared by the
+// instances to tell whether the vendor API is reachable. Failing to record it doesn't fail the call.
+func recordVendorSuccess(rawURL string) {
+	conn, err := vendorUsageConn()
+	if err != nil {
+		slog.Warn("Error recording vendor API call success", "error", err)
+		return
+	}
+	defer func() {
+		if err := conn.Close(); err != nil {
+			slog.Error("error closing redis connection", "error", err)
+		}
+	}()
+
+	if _, err = HSetData(conn, vendorSuccessesHashKey, VendorProvider(rawURL), vendorUsageNow().UTC().Format(time.RFC3339)); err != nil {
+		slog.Error("error setting vendor API last success time", "error", err)
+	}
+}
+
+// LastVendorSuccess is used to get the vendor API provider whose call succeeded last, along with the time of the call.
+// It returns the provider, empty along with the zero time when no call succeeded, and error.
+func LastVendorSuccess(conn redis.Conn) (string, time.Time, error) {
+	successes, err := HGetAllData(conn, vendorSuccessesHashKey)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	var lastProvider string
+	var lastSuccessAt time.Time
+	for _, provider := range SortedKeys(successes) {
+		if successAt, err := time.Parse(time.RFC3339, successes[provider]); err == nil && successAt.After(lastSuccessAt) {
+			lastProvider, lastSuccessAt = provider, successAt
+		}
+	}
+
+	return lastProvider, lastSuccessAt, nil
+}
+
+// GetVendorUsage is used to get the calls made to each vendor API provider in the current minute, day and month, along
+// with their budgets.
+// It returns the usage of the providers sorted by provider, and error.
+func GetVendorUsage(conn redis.Conn) ([]VendorUsage, error) {
+	providers, err := HGetAllData(conn, vendorProvidersHashKey)
+	if err != nil {
+		return nil, err
+	}
+
+	now := vendorUsageNow().UTC()
+	usages := make([]VendorUsage, 0, len(providers))
+	for _, provider := range SortedKeys(providers) {
+		usage := VendorUsage{Provider: provider}
+		usage.LastCallAt, _ = time.Parse(time.RFC3339, providers[provider])
+		for _, window := range usageWindows(now) {
+			key := fmt.Sprintf("%s:%s:%s:%s", vendorUsageKeyPrefix, provider, window.period, window.key)
+			calls, err := redis.Int(conn.Do("GET", key))
+			if err != nil && !errors.Is(err, redis.ErrNil) {
+				return nil, errors.New("failed to get vendor API call counter from Redis")
+			}
+			usage.Windows = append(usage.Windows, UsageWindow{
+				Period:  window.period,
+				Calls:   calls,
+				Budget:  window.budget,
+				ResetAt: window.end,
+			})
+		}
+		usages = append(usages, usage)
+	}
+
+	return usages, nil
+}
+
+// usageWindows is used to get the minute, day and month windows of the given UTC time, along with their budgets.
+func usageWindows(now time.Time) []usageWindow {
+	minute := now.Truncate(time.Minute)
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	return []usageWindow{
+		{period: "minute", key: minute.Format("2006-01-02T15:04"), budget: vendorBudget(constants.VENDOR_CALL_BUDGET_PER_MINUTE), end: minute.Add(time.Minute)},
+		{period: "day", key: day.Format("2006-01-02"), budget: vendorBudget(constants.VENDOR_CALL_BUDGET_PER_DAY), end: day.AddDate(0, 0, 1)},
+		{period: "month", key: month.Format("2006-01"), budget: vendorBudget(constants.VENDOR_CALL_BUDGET_PER_MONTH), end: month.AddDate(0, 1, 0)},
+	}
+}
+
+// vendorBudget parses the given budget, 0 being unlimited.
+func vendorBudget(value string) int {
+	if value == "" {
+		return 0
+	}
+
+	budget, err := strconv.Atoi(value)
+	if err != nil || budget < 0 {
+		slog.Warn("Error parsing vendor API call budget, it should be a non-negative integer", "value", value)
+		return 0
+	}
+
+	return budget
+}
+
+// VendorProvider is used to get the provider of the given vendor API URL, being its host.
+func VendorProvider(rawURL string) string {
+	if u, err := url.Parse(rawURL); err == nil && u.Host != "" {
+		return u.Host
+	}
+
+	return rawURL
+}
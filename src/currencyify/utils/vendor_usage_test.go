@@ -0,0 +1,140 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"currencyify/constants"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReserveVendorCall(t *testing.T) {
+	now := time.Date(2026, time.March, 30, 23, 59, 30, 0, time.UTC)
+	vendorUsageNow = func() time.Time {
+		return now
+	}
+	defer func() {
+		vendorUsageNow = time.Now
+		vendorUsageConn = Conn
+	}()
+
+	type vars struct {
+		minuteBudget string
+		dayBudget    string
+		calls        int
+	}
+
+	testCases := []struct {
+		name string
+
+		vars vars
+
+		hasErr    bool
+		err       string
+		wantCalls []int
+	}{
+		{
+			name: "should count the calls without budget",
+			vars: vars{
+				calls: 3,
+			},
+			wantCalls: []int{3, 3, 3},
+		},
+		{
+			name: "should count the calls within budget",
+			vars: vars{
+				minuteBudget: "3",
+				dayBudget:    "10",
+				calls:        3,
+			},
+			wantCalls: []int{3, 3, 3},
+		},
+		{
+			name: "should refuse the call exceeding the budget without counting it",
+			vars: vars{
+				minuteBudget: "5",
+				dayBudget:    "2",
+				calls:        3,
+			},
+			hasErr:    true,
+			err:       "vendor API call budget exhausted: 2 calls per day to api.fxratesapi.com",
+			wantCalls: []int{2, 2, 2},
+		},
+	}
+
+	for _, tCase := range testCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			// Setup
+			conn := NewMockRedisConn()
+			vendorUsageConn = func() (redis.Conn, error) {
+				return conn, nil
+			}
+			constants.VENDOR_CALL_BUDGET_PER_MINUTE = tCase.vars.minuteBudget
+			constants.VENDOR_CALL_BUDGET_PER_DAY = tCase.vars.dayBudget
+
+			// Run test
+			var err error
+			for i := 0; i < tCase.vars.calls; i++ {
+				err = reserveVendorCall("https://api.fxratesapi.com/latest?base=USD")
+			}
+
+			// Assert
+			if tCase.hasErr {
+				assert.ErrorIsf(t, err, ErrVendorBudgetExhausted, "case: %v", tCase)
+				assert.EqualErrorf(t, err, tCase.err, "case: %v", tCase)
+			} else {
+				assert.NoErrorf(t, err, "case: %v", tCase)
+			}
+
+			usages, err := GetVendorUsage(conn)
+			if assert.NoErrorf(t, err, "case: %v", tCase) && assert.Len(t, usages, 1, "case: %v", tCase) {
+				assert.Equal(t, "api.fxratesapi.com", usages[0].Provider, "case: %v", tCase)
+				assert.Equal(t, now.Truncate(time.Second), usages[0].LastCallAt, "case: %v", tCase)
+				calls := make([]int, 0)
+				for _, window := range usages[0].Windows {
+					calls = append(calls, window.Calls)
+				}
+				assert.Equal(t, tCase.wantCalls, calls, "case: %v", tCase)
+				assert.Equal(t, time.Date(2026, time.March, 31, 0, 0, 0, 0, time.UTC), usages[0].Windows[0].ResetAt, "case: %v", tCase)
+				assert.Equal(t, time.Date(2026, time.April, 1, 0, 0, 0, 0, time.UTC), usages[0].Windows[2].ResetAt, "case: %v", tCase)
+			}
+		})
+	}
+	constants.VENDOR_CALL_BUDGET_PER_MINUTE = ""
+	constants.VENDOR_CALL_BUDGET_PER_DAY = ""
+}
+
+func TestLastVendorSuccess(t *testing.T) {
+	// Setup
+	now := time.Date(2026, time.March, 31, 12, 0, 0, 0, time.UTC)
+	conn := NewMockRedisConn()
+	vendorUsageConn = func() (redis.Conn, error) {
+		return conn, nil
+	}
+	defer func() {
+		vendorUsageNow = time.Now
+		vendorUsageConn = Conn
+	}()
+
+	// Run test
+	provider, successAt, err := LastVendorSuccess(conn)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Empty(t, provider)
+	assert.True(t, successAt.IsZero())
+
+	// Run test
+	vendorUsageNow = func() time.Time { return now.Add(-time.Hour) }
+	recordVendorSuccess("https://api.fxratesapi.com/latest?base=USD")
+	vendorUsageNow = func() time.Time { return now }
+	recordVendorSuccess("https://api.frankfurter.app/latest")
+	provider, successAt, err = LastVendorSuccess(conn)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, "api.frankfurter.app", provider)
+	assert.Equal(t, now, successAt)
+}